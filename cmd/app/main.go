@@ -3,15 +3,21 @@ package main
 import (
 	"bank-api/internal/config"
 	"bank-api/internal/handlers"
+	"bank-api/internal/models"
 	"bank-api/internal/repository"
 	"bank-api/internal/service"
 	"bank-api/pkg/crypto"
 	"bank-api/pkg/database"
+	"bank-api/pkg/iso8583"
 	"bank-api/pkg/logging"
 	"bank-api/pkg/mail"
+	"bank-api/pkg/sbp"
+	"bank-api/pkg/sms"
 	"log"
 	"net/http"
 	"time"
+	// Часовые пояса клиентов для лимитов не зависят от tzdata в системе
+	_ "time/tzdata"
 
 	"github.com/gorilla/mux"
 )
@@ -59,18 +65,238 @@ func main() {
 	transactionRepo := repository.NewTransactionRepository(db)
 	cardRepo := repository.NewCardRepository(db)
 	creditRepo := repository.NewCreditRepository(db)
+	paymentImportRepo := repository.NewPaymentImportRepository(db)
+	interestRepo := repository.NewInterestRepository(db)
+	depositRepo := repository.NewDepositRepository(db)
+	holdRepo := repository.NewHoldRepository(db)
+	standingOrderRepo := repository.NewStandingOrderRepository(db)
+	p2pRepo := repository.NewP2PRepository(db)
+	sbpRepo := repository.NewSBPRepository(db)
+	paymentRequestRepo := repository.NewPaymentRequestRepository(db)
+	payrollRepo := repository.NewPayrollRepository(db)
+	limitRepo := repository.NewLimitRepository(db)
+	cardAuthorizationRepo := repository.NewCardAuthorizationRepository(db)
 
 	// Инициализация сервисов
 	authService := service.NewAuthService(userRepo, cfg.JWTSecret)
 	notificationService := service.NewNotificationService(mailer)
-	// transactionService := service.NewTransactionService(
-	// 	transactionRepo,
-	// 	accountRepo,
-	// 	notificationService,
-	// 	db,
-	// )
-	accountService := service.NewAccountService(accountRepo, transactionRepo, db)
-	cardService := service.NewCardService(cardRepo, accountRepo, cfg.HMACSecret)
+	limitService := service.NewLimitService(
+		limitRepo,
+		accountRepo,
+		cardRepo,
+		userRepo,
+		service.LimitPolicy{
+			Account: models.SpendingLimits{
+				DailyAmount:   cfg.AccountDailyLimit,
+				MonthlyAmount: cfg.AccountMonthlyLimit,
+				DailyCount:    cfg.AccountDailyCountLimit,
+				MonthlyCount:  cfg.AccountMonthlyCountLimit,
+			},
+			Card: models.SpendingLimits{
+				DailyAmount:   cfg.CardDailyLimit,
+				MonthlyAmount: cfg.CardMonthlyLimit,
+				DailyCount:    cfg.CardDailyCountLimit,
+				MonthlyCount:  cfg.CardMonthlyCountLimit,
+			},
+			DefaultTimezone: cfg.DefaultTimezone,
+		},
+	)
+	transactionService := service.NewTransactionService(
+		transactionRepo,
+		accountRepo,
+		userRepo,
+		sbpRepo,
+		limitService,
+		notificationService,
+		db,
+	)
+	// Списания по переводам до появления знака в проводках хранились
+	// положительными; выписки и лимиты без исправления считают их неверно
+	if n, err := transactionService.FixLegacyTransferDebits(); err != nil {
+		logger.Fatalf("Failed to fix legacy transfer debits: %v", err)
+	} else if n > 0 {
+		logger.Infof("Fixed debit legs of %d legacy transfers", n)
+	}
+	savingsProduct := service.SavingsProduct{
+		Rate:          cfg.SavingsInterestRate,
+		KeyRateLinked: cfg.SavingsKeyRateLinked,
+		KeyRateSpread: cfg.SavingsKeyRateSpread,
+	}
+	accountService := service.NewAccountService(
+		accountRepo,
+		transactionRepo,
+		creditRepo,
+		depositRepo,
+		limitService,
+		service.AccountNumbering{
+			BIC:                   cfg.BankBIC,
+			BalanceAccount:        cfg.BalanceAccount,
+			SavingsBalanceAccount: cfg.SavingsBalanceAccount,
+			Branch:                cfg.BranchCode,
+		},
+		savingsProduct,
+		db,
+	)
+	// Счета, открытые до появления номеров, иначе не найти по номеру
+	if n, err := accountService.BackfillNumbers(); err != nil {
+		logger.Fatalf("Failed to backfill account numbers: %v", err)
+	} else if n > 0 {
+		logger.Infof("Backfilled numbers for %d accounts", n)
+	}
+	interestService := service.NewInterestService(
+		accountRepo,
+		transactionRepo,
+		interestRepo,
+		savingsProduct,
+		db,
+	)
+	depositService := service.NewDepositService(
+		depositRepo,
+		accountRepo,
+		transactionRepo,
+		userRepo,
+		notificationService,
+		service.DepositProduct{
+			Rate:                cfg.DepositInterestRate,
+			EarlyWithdrawalRate: cfg.DepositEarlyRate,
+		},
+		db,
+	)
+	statementService := service.NewStatementService(
+		accountRepo,
+		transactionRepo,
+		userRepo,
+		notificationService,
+		cfg.BankName,
+		cfg.BankBIC,
+	)
+	holdService := service.NewHoldService(
+		holdRepo,
+		accountRepo,
+		cardRepo,
+		transactionRepo,
+		accountService,
+		limitService,
+		db,
+	)
+	standingOrderService := service.NewStandingOrderService(
+		standingOrderRepo,
+		accountRepo,
+		userRepo,
+		accountService,
+		notificationService,
+	)
+	// SMS с кодами подтверждения телефона
+	var smsSender sms.Sender
+	switch {
+	case cfg.SMSGatewayURL != "":
+		smsSender = sms.NewClient(cfg.SMSGatewayURL, cfg.SMSGatewayToken)
+	case cfg.SMSFake:
+		logger.Warn("SMS_GATEWAY_URL is not set, SMS codes are written to the log")
+		smsSender = sms.NewFake()
+	default:
+		logger.Fatal("SMS_GATEWAY_URL must be set (SMS_FAKE=true writes SMS to the log for development)")
+	}
+	p2pService := service.NewP2PService(p2pRepo, userRepo, accountRepo, accountService, smsSender)
+	paymentRequestService := service.NewPaymentRequestService(
+		paymentRequestRepo,
+		userRepo,
+		accountRepo,
+		accountService,
+		notificationService,
+		cfg.PaymentRequestTTL,
+	)
+	payrollService := service.NewPayrollService(
+		payrollRepo,
+		accountRepo,
+		transactionRepo,
+		accountService,
+		limitService,
+		db,
+	)
+	// НСПК: внешний сервис или встроенная эмуляция для разработки. Эмуляция
+	// теряет ТСП и QR-коды при перезапуске, поэтому включается только явно.
+	var nspk sbp.NSPK
+	switch {
+	case cfg.NSPKURL != "":
+		nspk = sbp.NewClient(cfg.NSPKURL)
+	case cfg.NSPKFake:
+		logger.Warn("NSPK_URL is not set, using in-memory NSPK emulation")
+		nspk = sbp.NewFake()
+	default:
+		logger.Fatal("NSPK_URL must be set (NSPK_FAKE=true enables the in-memory emulation for development)")
+	}
+	sbpService := service.NewSBPService(sbpRepo, accountRepo, accountService, nspk, cfg.SBPBankID)
+	paymentImportService := service.NewPaymentImportService(paymentImportRepo, accountService, db)
+	binRanges, err := service.ParseBINRanges(cfg.CardBINRanges)
+	if err != nil {
+		logger.Fatalf("Invalid card BIN ranges: %v", err)
+	}
+	cardService := service.NewCardService(
+		cardRepo,
+		accountRepo,
+		limitRepo,
+		limitService,
+		cfg.HMACSecret,
+		binRanges,
+		db,
+	)
+	// Карты, выпущенные до появления хеша номера, иначе не найти по номеру,
+	// а новый номер мог бы совпасть с их номером. Без хешей карты не
+	// выпускаем.
+	if n, err := cardService.BackfillNumberHashes(); err != nil {
+		logger.Fatalf("Failed to backfill card number hashes: %v", err)
+	} else if n > 0 {
+		logger.Infof("Backfilled number hashes for %d cards", n)
+	}
+	// Срок действия старых карт хранился только в зашифрованном виде
+	if n, err := cardService.BackfillExpiresAt(); err != nil {
+		logger.Errorf("Failed to backfill card expiry dates: %v", err)
+	} else if n > 0 {
+		logger.Infof("Backfilled expiry dates for %d cards", n)
+	}
+	cardExpiryService := service.NewCardExpiryService(
+		cardRepo,
+		accountRepo,
+		userRepo,
+		cardService,
+		notificationService,
+		cfg.CardRenewalDays,
+		db,
+	)
+	cardTransferService := service.NewCardTransferService(
+		cardRepo,
+		accountRepo,
+		userRepo,
+		holdService,
+		cfg.HMACSecret,
+	)
+	if cfg.PINSecret == cfg.HMACSecret {
+		logger.Fatal("PIN_SECRET must differ from HMAC_SECRET")
+	}
+	if cfg.PINMaxAttempts < 1 {
+		logger.Fatal("PIN_MAX_ATTEMPTS must be positive")
+	}
+	pinService := service.NewPINService(
+		cardRepo,
+		accountRepo,
+		userRepo,
+		cardService,
+		service.PINPolicy{
+			Secret:      cfg.PINSecret,
+			MaxAttempts: cfg.PINMaxAttempts,
+		},
+		db,
+	)
+	cardAuthorizationService := service.NewCardAuthorizationService(
+		cardAuthorizationRepo,
+		cardRepo,
+		accountRepo,
+		holdService,
+		transactionService,
+		pinService,
+		cfg.HMACSecret,
+	)
 	creditService := service.NewCreditService(
 		creditRepo,
 		accountRepo,
@@ -79,13 +305,55 @@ func main() {
 		db,
 	)
 
+	// Прием сообщений ISO 8583 от терминалов
+	if cfg.ISO8583Addr != "" {
+		isoKeys, err := iso8583.ParseKeys(cfg.ISO8583AcquirerKeys)
+		if err != nil {
+			logger.Fatalf("ISO8583_ACQUIRER_KEYS: %v", err)
+		}
+		isoServer := &iso8583.Server{
+			Addr:        cfg.ISO8583Addr,
+			Handler:     handlers.NewISO8583Handler(cardAuthorizationService),
+			Keys:        isoKeys,
+			IdleTimeout: 5 * time.Minute,
+		}
+		go func() {
+			logger.Infof("ISO 8583 listener is running on %s", cfg.ISO8583Addr)
+			if err := isoServer.ListenAndServe(); err != nil {
+				logger.Errorf("ISO 8583 listener stopped: %v", err)
+			}
+		}()
+	}
+
 	// Запуск шедулера для обработки платежей
-	go StartScheduler(creditService)
+	go StartScheduler(
+		creditService,
+		interestService,
+		depositService,
+		holdService,
+		standingOrderService,
+		paymentRequestService,
+		cardExpiryService,
+	)
 
 	// Инициализация обработчиков
 	authHandler := handlers.NewAuthHandler(authService)
-	accountHandler := handlers.NewAccountHandler(accountService)
+	accountHandler := handlers.NewAccountHandler(accountService, statementService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	interestHandler := handlers.NewInterestHandler(interestService)
+	depositHandler := handlers.NewDepositHandler(depositService)
+	holdHandler := handlers.NewHoldHandler(holdService)
+	standingOrderHandler := handlers.NewStandingOrderHandler(standingOrderService)
+	p2pHandler := handlers.NewP2PHandler(p2pService)
+	sbpHandler := handlers.NewSBPHandler(sbpService)
+	paymentRequestHandler := handlers.NewPaymentRequestHandler(paymentRequestService)
+	payrollHandler := handlers.NewPayrollHandler(payrollService)
+	limitHandler := handlers.NewLimitHandler(limitService)
+	paymentImportHandler := handlers.NewPaymentImportHandler(paymentImportService)
 	cardHandler := handlers.NewCardHandler(cardService)
+	pinHandler := handlers.NewPINHandler(pinService)
+	cardTransferHandler := handlers.NewCardTransferHandler(cardTransferService)
+	cardAuthorizationHandler := handlers.NewCardAuthorizationHandler(cardAuthorizationService)
 	creditHandler := handlers.NewCreditHandler(
 		creditService,
 		accountRepo,
@@ -97,24 +365,80 @@ func main() {
 	publicRouter := router.PathPrefix("/api").Subrouter()
 	authHandler.RegisterRoutes(publicRouter)
 
+	// Маршруты эквайреров
+	processingRouter := router.PathPrefix("/api/processing").Subrouter()
+	processingRouter.Use(handlers.AcquirerMiddleware(cfg.AcquirerAPIKey))
+	cardAuthorizationHandler.RegisterRoutes(processingRouter)
+
 	// Защищенные маршруты
 	protectedRouter := router.PathPrefix("/api").Subrouter()
 	protectedRouter.Use(handlers.AuthMiddleware(cfg.JWTSecret))
 	accountHandler.RegisterRoutes(protectedRouter)
+	transactionHandler.RegisterRoutes(protectedRouter)
+	interestHandler.RegisterRoutes(protectedRouter)
+	depositHandler.RegisterRoutes(protectedRouter)
+	holdHandler.RegisterRoutes(protectedRouter)
+	standingOrderHandler.RegisterRoutes(protectedRouter)
+	p2pHandler.RegisterRoutes(protectedRouter)
+	sbpHandler.RegisterRoutes(protectedRouter)
+	paymentRequestHandler.RegisterRoutes(protectedRouter)
+	payrollHandler.RegisterRoutes(protectedRouter)
+	limitHandler.RegisterRoutes(protectedRouter)
+	paymentImportHandler.RegisterRoutes(protectedRouter)
 	cardHandler.RegisterRoutes(protectedRouter)
+	pinHandler.RegisterRoutes(protectedRouter)
+	cardTransferHandler.RegisterRoutes(protectedRouter)
 	creditHandler.RegisterRoutes(protectedRouter)
 
 	logger.Infof("Server is running on port %s", cfg.ServerPort)
 	log.Fatal(http.ListenAndServe(cfg.ServerPort, router))
 }
 
-func StartScheduler(creditSvc *service.CreditService) {
+func StartScheduler(
+	creditSvc *service.CreditService,
+	interestSvc *service.InterestService,
+	depositSvc *service.DepositService,
+	holdSvc *service.HoldService,
+	standingOrderSvc *service.StandingOrderService,
+	paymentRequestSvc *service.PaymentRequestService,
+	cardExpirySvc *service.CardExpiryService,
+) {
 	ticker := time.NewTicker(12 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if err := creditSvc.ProcessDuePayments(); err != nil {
-			log.Printf("Error processing due payments: %v", err)
+	// Переводы по расписанию и сроки запросов денег проверяются чаще,
+	// чтобы повторные попытки не ждали следующего запуска остальных задач
+	ordersTicker := time.NewTicker(time.Hour)
+	defer ordersTicker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := creditSvc.ProcessDuePayments(); err != nil {
+				log.Printf("Error processing due payments: %v", err)
+			}
+			// Начисление идемпотентно по датам и догоняет пропущенные дни
+			if err := interestSvc.ProcessDailyAccruals(time.Now()); err != nil {
+				log.Printf("Error processing interest accruals: %v", err)
+			}
+			// Выплата процентов и окончание срока вкладов
+			if err := depositSvc.ProcessDeposits(time.Now()); err != nil {
+				log.Printf("Error processing deposits: %v", err)
+			}
+			if err := holdSvc.ExpireHolds(time.Now()); err != nil {
+				log.Printf("Error expiring holds: %v", err)
+			}
+			// Замена карт с истекающим сроком и закрытие операций по истекшим
+			if err := cardExpirySvc.ProcessCards(time.Now()); err != nil {
+				log.Printf("Error processing card expiry: %v", err)
+			}
+		case <-ordersTicker.C:
+			if err := standingOrderSvc.ProcessDueOrders(time.Now()); err != nil {
+				log.Printf("Error processing standing orders: %v", err)
+			}
+			if err := paymentRequestSvc.ExpireRequests(time.Now()); err != nil {
+				log.Printf("Error expiring payment requests: %v", err)
+			}
 		}
 	}
 }
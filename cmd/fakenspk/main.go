@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"bank-api/pkg/sbp"
+)
+
+// Локальная эмуляция НСПК. Запуск:
+//
+//	go run ./cmd/fakenspk -addr :8090
+//
+// и NSPK_URL=http://localhost:8090 для основного приложения.
+func main() {
+	addr := flag.String("addr", ":8090", "listen address")
+	flag.Parse()
+
+	log.Printf("Fake NSPK is running on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, sbp.NewFake().Handler()))
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -19,26 +21,148 @@ type Config struct {
 	SMTPUser     string
 	SMTPPassword string
 	SMTPFrom     string
+	BankName     string
+	BankBIC      string
+	// Балансовый счет и код филиала для номеров открываемых счетов
+	BalanceAccount string
+	BranchCode     string
+	// Сберегательные счета: фиксированная ставка либо ключевая ставка ЦБ
+	// со спредом, а также балансовый счет для их номеров
+	SavingsBalanceAccount string
+	SavingsInterestRate   float64
+	SavingsKeyRateLinked  bool
+	SavingsKeyRateSpread  float64
+	// Срочные вклады: годовая ставка и ставка при досрочном расторжении
+	DepositInterestRate float64
+	DepositEarlyRate    float64
+	// СБП: идентификатор банка в СБП и адрес НСПК. Встроенная эмуляция НСПК
+	// хранит ТСП и QR-коды только в памяти, поэтому без адреса сервис
+	// запускается лишь с явным NSPK_FAKE=true для разработки.
+	SBPBankID string
+	NSPKURL   string
+	NSPKFake  bool
+	// SMS-шлюз для кодов подтверждения телефона. Без адреса сервис
+	// запускается только с SMS_FAKE=true: коды пишутся в журнал.
+	SMSGatewayURL   string
+	SMSGatewayToken string
+	SMSFake         bool
+	// Срок действия запроса денег
+	PaymentRequestTTL time.Duration
+	// Лимиты банка на расходные операции по счету и по карте и часовой пояс
+	// для границ периодов, если клиент не задал свой
+	AccountDailyLimit        float64
+	AccountMonthlyLimit      float64
+	AccountDailyCountLimit   int
+	AccountMonthlyCountLimit int
+	CardDailyLimit           float64
+	CardMonthlyLimit         float64
+	CardDailyCountLimit      int
+	CardMonthlyCountLimit    int
+	DefaultTimezone          string
+	// Диапазоны BIN для выпуска карт по платежным системам
+	CardBINRanges string
+	// За сколько дней до окончания срока карты выпускается новая
+	CardRenewalDays int
+	// Ключ API для запросов авторизации от эквайреров
+	AcquirerAPIKey string
+	// Адрес для приема сообщений ISO 8583 от терминалов; пустой адрес
+	// отключает прием
+	ISO8583Addr string
+	// Ключи MAC эквайреров для ISO 8583 в формате
+	// "<код эквайрера>=<ключ hex>;..."; без них прием не запускается
+	ISO8583AcquirerKeys string
+	// Ключ хеширования PIN (отдельный от HMAC_SECRET) и число неверных
+	// попыток ввода PIN до блокировки карты
+	PINSecret      string
+	PINMaxAttempts int
 }
 
 func Load() (*Config, error) {
 	port, _ := strconv.Atoi(getEnv("DB_PORT", "5432"))
 	smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	savingsRate, _ := strconv.ParseFloat(getEnv("SAVINGS_INTEREST_RATE", "8"), 64)
+	savingsLinked, _ := strconv.ParseBool(getEnv("SAVINGS_KEY_RATE_LINKED", "false"))
+	savingsSpread, _ := strconv.ParseFloat(getEnv("SAVINGS_KEY_RATE_SPREAD", "-2"), 64)
+	depositRate, _ := strconv.ParseFloat(getEnv("DEPOSIT_INTEREST_RATE", "12"), 64)
+	depositEarlyRate, _ := strconv.ParseFloat(getEnv("DEPOSIT_EARLY_WITHDRAWAL_RATE", "0.01"), 64)
+	requestTTLHours, _ := strconv.Atoi(getEnv("PAYMENT_REQUEST_TTL_HOURS", "72"))
+	accountDailyLimit, _ := strconv.ParseFloat(getEnv("ACCOUNT_DAILY_LIMIT", "1000000"), 64)
+	accountMonthlyLimit, _ := strconv.ParseFloat(getEnv("ACCOUNT_MONTHLY_LIMIT", "10000000"), 64)
+	accountDailyCount, _ := strconv.Atoi(getEnv("ACCOUNT_DAILY_COUNT_LIMIT", "100"))
+	accountMonthlyCount, _ := strconv.Atoi(getEnv("ACCOUNT_MONTHLY_COUNT_LIMIT", "1000"))
+	cardDailyLimit, _ := strconv.ParseFloat(getEnv("CARD_DAILY_LIMIT", "300000"), 64)
+	cardMonthlyLimit, _ := strconv.ParseFloat(getEnv("CARD_MONTHLY_LIMIT", "3000000"), 64)
+	cardDailyCount, _ := strconv.Atoi(getEnv("CARD_DAILY_COUNT_LIMIT", "50"))
+	cardMonthlyCount, _ := strconv.Atoi(getEnv("CARD_MONTHLY_COUNT_LIMIT", "500"))
+	pinMaxAttempts, _ := strconv.Atoi(getEnv("PIN_MAX_ATTEMPTS", "3"))
+	cardRenewalDays, _ := strconv.Atoi(getEnv("CARD_RENEWAL_DAYS", "30"))
+	nspkFake, _ := strconv.ParseBool(getEnv("NSPK_FAKE", "false"))
+	smsFake, _ := strconv.ParseBool(getEnv("SMS_FAKE", "false"))
+
+	// Ключи эквайреров и PIN не имеют значений по умолчанию: известное
+	// значение позволило бы проводить операции по картам и подбирать PIN
+	for _, key := range []string{"ACQUIRER_API_KEY", "PIN_SECRET"} {
+		if getEnv(key, "") == "" {
+			return nil, fmt.Errorf("%s must be set", key)
+		}
+	}
 
 	return &Config{
-		DBHost:       getEnv("DB_HOST", "localhost"),
-		DBPort:       port,
-		DBUser:       getEnv("DB_USER", "postgres"),
-		DBPassword:   getEnv("DB_PASSWORD", "1234"),
-		DBName:       getEnv("DB_NAME", "banking"),
-		ServerPort:   getEnv("SERVER_PORT", ":8080"),
-		JWTSecret:    getEnv("JWT_SECRET", "secret"),
-		HMACSecret:   getEnv("HMAC_SECRET", "secret"),
-		SMTPHost:     getEnv("SMTP_HOST", "smtp.example.com"),
-		SMTPPort:     smtpPort,
-		SMTPUser:     getEnv("SMTP_USER", "user@example.com"),
-		SMTPPassword: getEnv("SMTP_PASSWORD", "password"),
-		SMTPFrom:     getEnv("SMTP_FROM", "noreply@example.com"),
+		DBHost:         getEnv("DB_HOST", "localhost"),
+		DBPort:         port,
+		DBUser:         getEnv("DB_USER", "postgres"),
+		DBPassword:     getEnv("DB_PASSWORD", "1234"),
+		DBName:         getEnv("DB_NAME", "banking"),
+		ServerPort:     getEnv("SERVER_PORT", ":8080"),
+		JWTSecret:      getEnv("JWT_SECRET", "secret"),
+		HMACSecret:     getEnv("HMAC_SECRET", "secret"),
+		SMTPHost:       getEnv("SMTP_HOST", "smtp.example.com"),
+		SMTPPort:       smtpPort,
+		SMTPUser:       getEnv("SMTP_USER", "user@example.com"),
+		SMTPPassword:   getEnv("SMTP_PASSWORD", "password"),
+		SMTPFrom:       getEnv("SMTP_FROM", "noreply@example.com"),
+		BankName:       getEnv("BANK_NAME", "Bank API"),
+		BankBIC:        getEnv("BANK_BIC", "044525999"),
+		BalanceAccount: getEnv("BALANCE_ACCOUNT", "40817"),
+		BranchCode:     getEnv("BRANCH_CODE", "0000"),
+
+		SavingsBalanceAccount: getEnv("SAVINGS_BALANCE_ACCOUNT", "42301"),
+		SavingsInterestRate:   savingsRate,
+		SavingsKeyRateLinked:  savingsLinked,
+		SavingsKeyRateSpread:  savingsSpread,
+
+		DepositInterestRate: depositRate,
+		DepositEarlyRate:    depositEarlyRate,
+
+		SBPBankID: getEnv("SBP_BANK_ID", "100000000999"),
+		NSPKURL:   getEnv("NSPK_URL", ""),
+		NSPKFake:  nspkFake,
+
+		SMSGatewayURL:   getEnv("SMS_GATEWAY_URL", ""),
+		SMSGatewayToken: getEnv("SMS_GATEWAY_TOKEN", ""),
+		SMSFake:         smsFake,
+
+		PaymentRequestTTL: time.Duration(requestTTLHours) * time.Hour,
+
+		AccountDailyLimit:        accountDailyLimit,
+		AccountMonthlyLimit:      accountMonthlyLimit,
+		AccountDailyCountLimit:   accountDailyCount,
+		AccountMonthlyCountLimit: accountMonthlyCount,
+		CardDailyLimit:           cardDailyLimit,
+		CardMonthlyLimit:         cardMonthlyLimit,
+		CardDailyCountLimit:      cardDailyCount,
+		CardMonthlyCountLimit:    cardMonthlyCount,
+		DefaultTimezone:          getEnv("DEFAULT_TIMEZONE", "Europe/Moscow"),
+
+		CardBINRanges:   getEnv("CARD_BIN_RANGES", "mir=220000-220099;visa=400000-400099;mastercard=510000-510099"),
+		CardRenewalDays: cardRenewalDays,
+		AcquirerAPIKey:  getEnv("ACQUIRER_API_KEY", ""),
+		ISO8583Addr:     getEnv("ISO8583_ADDR", ""),
+
+		ISO8583AcquirerKeys: getEnv("ISO8583_ACQUIRER_KEYS", ""),
+
+		PINSecret:      getEnv("PIN_SECRET", ""),
+		PINMaxAttempts: pinMaxAttempts,
 	}, nil
 }
 
@@ -2,27 +2,43 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"bank-api/internal/models"
 	"bank-api/internal/service"
+	"bank-api/pkg/cbr"
 
 	"github.com/gorilla/mux"
 )
 
 type AccountHandler struct {
-	accountService *service.AccountService
+	accountService   *service.AccountService
+	statementService *service.StatementService
 }
 
-func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
-	return &AccountHandler{accountService: accountService}
+func NewAccountHandler(
+	accountService *service.AccountService,
+	statementService *service.StatementService,
+) *AccountHandler {
+	return &AccountHandler{
+		accountService:   accountService,
+		statementService: statementService,
+	}
 }
 
 func (h *AccountHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/accounts", h.CreateAccount).Methods("POST")
+	router.HandleFunc("/accounts", h.ListAccounts).Methods("GET")
 	router.HandleFunc("/accounts/{id}/balance", h.UpdateBalance).Methods("PATCH")
 	router.HandleFunc("/accounts/{id}/transfer", h.Transfer).Methods("POST")
+	router.HandleFunc("/accounts/{id}/statement", h.GetStatement).Methods("GET")
+	router.HandleFunc("/accounts/{id}/statement/email", h.EmailStatement).Methods("POST")
+	router.HandleFunc("/accounts/{id}/freeze", h.FreezeAccount).Methods("POST")
+	router.HandleFunc("/accounts/{id}/unfreeze", h.UnfreezeAccount).Methods("POST")
+	router.HandleFunc("/accounts/{id}/close", h.CloseAccount).Methods("POST")
 	router.HandleFunc("/accounts/{id}", h.GetAccount).Methods("GET")
 }
 
@@ -37,16 +53,19 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 
 	account, err := h.accountService.CreateAccount(userID, &req)
 	if err != nil {
+		if err == service.ErrInvalidAccountType {
+			http.Error(w, "Invalid account type", http.StatusBadRequest)
+			return
+		}
+		if err == service.ErrUnsupportedCurrency {
+			http.Error(w, "Unsupported account currency", http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to create account", http.StatusInternalServerError)
 		return
 	}
 
-	response := models.AccountResponse{
-		ID:        account.ID,
-		Balance:   account.Balance,
-		Currency:  account.Currency,
-		CreatedAt: account.CreatedAt,
-	}
+	response := newAccountResponse(account)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -76,12 +95,7 @@ func (h *AccountHandler) UpdateBalance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.accountService.UpdateBalance(accountID, req.Amount); err != nil {
-		switch err {
-		case service.ErrInsufficientFunds:
-			http.Error(w, "Insufficient funds", http.StatusBadRequest)
-		default:
-			http.Error(w, "Update failed", http.StatusInternalServerError)
-		}
+		writeLedgerError(w, err, "Update failed")
 		return
 	}
 
@@ -117,14 +131,7 @@ func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.accountService.Transfer(&req); err != nil {
-		switch err {
-		case service.ErrAccountNotFound:
-			http.Error(w, "Account not found", http.StatusNotFound)
-		case service.ErrInsufficientFunds:
-			http.Error(w, "Insufficient funds", http.StatusBadRequest)
-		default:
-			http.Error(w, "Transfer failed", http.StatusInternalServerError)
-		}
+		writeLedgerError(w, err, "Transfer failed")
 		return
 	}
 
@@ -135,9 +142,20 @@ func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(int)
 	vars := mux.Vars(r)
-	accountID, _ := strconv.Atoi(vars["id"])
 
-	account, err := h.accountService.GetAccountByID(accountID)
+	// Счет можно запросить как по ID, так и по 20-значному номеру
+	var account *models.Account
+	var err error
+	if len(vars["id"]) == cbr.AccountNumberLength {
+		account, err = h.accountService.GetAccountByNumber(vars["id"])
+	} else {
+		accountID, _ := strconv.Atoi(vars["id"])
+		account, err = h.accountService.GetAccountByID(accountID)
+	}
+	if err == service.ErrInvalidAccountNumber {
+		http.Error(w, "Invalid account number", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Account error", http.StatusInternalServerError)
 		return
@@ -147,13 +165,217 @@ func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := models.AccountResponse{
-		ID:        account.ID,
-		Balance:   account.Balance,
-		Currency:  account.Currency,
-		CreatedAt: account.CreatedAt,
+	response := newAccountResponse(account)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *AccountHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	accounts, err := h.accountService.GetUserAccounts(userID)
+	if err != nil {
+		http.Error(w, "Failed to get accounts", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]models.AccountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		response = append(response, newAccountResponse(account))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+func (h *AccountHandler) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	if err := h.accountService.FreezeAccount(userID, accountID); err != nil {
+		writeLedgerError(w, err, "Failed to freeze account")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account frozen"})
+}
+
+func (h *AccountHandler) UnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	if err := h.accountService.UnfreezeAccount(userID, accountID); err != nil {
+		writeLedgerError(w, err, "Failed to unfreeze account")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account unfrozen"})
+}
+
+func (h *AccountHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	// Тело запроса необязательно: для счета с нулевым остатком оно не нужно
+	var req models.CloseAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountService.CloseAccount(userID, accountID, &req); err != nil {
+		writeLedgerError(w, err, "Failed to close account")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account closed"})
+}
+
+func (h *AccountHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	query := r.URL.Query()
+	from, to, err := parsePeriod(query.Get("from"), query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid period", http.StatusBadRequest)
+		return
+	}
+
+	format := models.StatementFormat(query.Get("format"))
+	if format == "" {
+		format = models.StatementFormatCSV
+	}
+
+	statement, err := h.statementService.GenerateStatement(userID, accountID, from, to)
+	if err != nil {
+		writeStatementError(w, err)
+		return
+	}
+
+	file, err := h.statementService.Render(statement, format)
+	if err != nil {
+		writeStatementError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file.Filename+`"`)
+	w.Write(file.Data)
+}
+
+func (h *AccountHandler) EmailStatement(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	var req models.EmailStatementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parsePeriod(req.From, req.To)
+	if err != nil {
+		http.Error(w, "Invalid period", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.statementService.EmailStatement(userID, accountID, from, to, req.Format); err != nil {
+		writeStatementError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Statement sent"})
+}
+
+func writeStatementError(w http.ResponseWriter, err error) {
+	switch err {
+	case service.ErrAccountNotFound:
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case service.ErrInvalidPeriod:
+		http.Error(w, "Invalid period", http.StatusBadRequest)
+	case service.ErrUnsupportedFormat:
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to build statement", http.StatusInternalServerError)
+	}
+}
+
+// parsePeriod разбирает даты периода в формате YYYY-MM-DD
+func parsePeriod(fromStr, toStr string) (time.Time, time.Time, error) {
+	from, err := time.Parse("2006-01-02", fromStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to, err := time.Parse("2006-01-02", toStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return from, to, nil
+}
+
+func newAccountResponse(account *models.Account) models.AccountResponse {
+	return models.AccountResponse{
+		ID:           account.ID,
+		Number:       account.Number,
+		Balance:      account.Balance,
+		Available:    account.AvailableBalance,
+		Currency:     account.Currency,
+		Type:         account.Type,
+		Status:       account.Status,
+		InterestRate: account.InterestRate,
+		CreatedAt:    account.CreatedAt,
+	}
+}
+
+// writeLedgerError отвечает клиенту на ошибку операции по счету
+func writeLedgerError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrAccountNotFound:
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case service.ErrInsufficientFunds:
+		http.Error(w, "Insufficient funds", http.StatusBadRequest)
+	case service.ErrInvalidAmount:
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+	case service.ErrInvalidAccountNumber:
+		http.Error(w, "Invalid account number", http.StatusBadRequest)
+	case service.ErrSameAccount:
+		http.Error(w, "Source and destination accounts are the same", http.StatusBadRequest)
+	case service.ErrAccountFrozen:
+		http.Error(w, "Account is frozen", http.StatusConflict)
+	case service.ErrAccountClosed:
+		http.Error(w, "Account is closed", http.StatusConflict)
+	case service.ErrAccountNotFrozen:
+		http.Error(w, "Account is not frozen", http.StatusConflict)
+	case service.ErrAccountHasCredits:
+		http.Error(w, "Account has active credits", http.StatusConflict)
+	case service.ErrAccountHasDeposits:
+		http.Error(w, "Account has active deposits", http.StatusConflict)
+	case service.ErrAccountHasHolds:
+		http.Error(w, "Account has active holds", http.StatusConflict)
+	case service.ErrDailyLimitExceeded:
+		http.Error(w, "Daily spending limit exceeded", http.StatusUnprocessableEntity)
+	case service.ErrMonthlyLimitExceeded:
+		http.Error(w, "Monthly spending limit exceeded", http.StatusUnprocessableEntity)
+	case service.ErrDailyCountExceeded:
+		http.Error(w, "Daily operations count limit exceeded", http.StatusUnprocessableEntity)
+	case service.ErrMonthlyCountExceeded:
+		http.Error(w, "Monthly operations count limit exceeded", http.StatusUnprocessableEntity)
+	case service.ErrNonZeroBalance:
+		http.Error(w, "Account balance is not zero, specify transfer_to_account_id", http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -21,8 +22,18 @@ func NewCardHandler(cardService *service.CardService) *CardHandler {
 
 func (h *CardHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/cards", h.CreateCard).Methods("POST")
+	router.HandleFunc("/cards", h.GetUserCards).Methods("GET")
 	router.HandleFunc("/cards/{id}", h.GetCard).Methods("GET")
 	router.HandleFunc("/accounts/{id}/cards", h.GetAccountCards).Methods("GET")
+	router.HandleFunc("/cards/{id}/block", h.BlockCard).Methods("POST")
+	router.HandleFunc("/cards/{id}/unblock", h.UnblockCard).Methods("POST")
+	router.HandleFunc("/cards/{id}/reissue", h.ReissueCard).Methods("POST")
+	router.HandleFunc("/cards/{id}/close", h.CloseCard).Methods("POST")
+	router.HandleFunc("/cards/{id}/history", h.GetCardHistory).Methods("GET")
+	router.HandleFunc("/cards/{id}/amount-cap", h.UpdateAmountCap).Methods("PUT")
+	router.HandleFunc("/cards/{id}/controls", h.GetCardControls).Methods("GET")
+	router.HandleFunc("/cards/{id}/controls", h.UpdateCardControls).Methods("PUT")
+	router.HandleFunc("/card-controls/mcc-groups", h.GetMCCGroups).Methods("GET")
 }
 
 func (h *CardHandler) CreateCard(w http.ResponseWriter, r *http.Request) {
@@ -36,21 +47,18 @@ func (h *CardHandler) CreateCard(w http.ResponseWriter, r *http.Request) {
 
 	card, err := h.cardService.CreateCard(userID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		switch err {
+		case service.ErrUnsupportedProduct, service.ErrInvalidCardType, service.ErrInvalidCardValidity, service.ErrInvalidAmount:
+			writeCardError(w, err, "Failed to create card")
+		default:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		}
 		return
 	}
 
-	response := models.CardResponse{
-		ID:         card.ID,
-		AccountID:  card.AccountID,
-		LastFour:   card.Number[len(card.Number)-4:],
-		ExpiryDate: card.ExpiryDate,
-		CreatedAt:  card.CreatedAt,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(issuedCardResponse(card))
 }
 
 func (h *CardHandler) GetCard(w http.ResponseWriter, r *http.Request) {
@@ -68,17 +76,245 @@ func (h *CardHandler) GetCard(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(card)
 }
 
+// GetAccountCards возвращает карты счета с маскированными номерами
 func (h *CardHandler) GetAccountCards(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	cards, err := h.cardService.GetAccountCards(userID, accountID)
+	if err != nil {
+		writeCardError(w, err, "Failed to get cards")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cards)
+}
+
+// GetUserCards возвращает карты всех счетов клиента. Параметры status и
+// type отбирают карты по статусу и виду.
+func (h *CardHandler) GetUserCards(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	query := r.URL.Query()
+
+	cards, err := h.cardService.GetUserCards(
+		userID,
+		models.CardStatus(query.Get("status")),
+		models.CardType(query.Get("type")),
+	)
+	if err != nil {
+		writeCardError(w, err, "Failed to get cards")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cards)
+}
+
+// BlockCard блокирует карту временно или, при утере и краже, навсегда
+func (h *CardHandler) BlockCard(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(int)
 	vars := mux.Vars(r)
 	cardID, _ := strconv.Atoi(vars["id"])
 
-	card, err := h.cardService.GetCard(userID, cardID)
+	// Тело запроса необязательно: без причины блокировка временная
+	var req models.BlockCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	card, err := h.cardService.BlockCard(userID, cardID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		writeCardError(w, err, "Failed to block card")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+func (h *CardHandler) UnblockCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	card, err := h.cardService.UnblockCard(userID, cardID)
+	if err != nil {
+		writeCardError(w, err, "Failed to unblock card")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(card)
 }
+
+// ReissueCard выпускает новую карту взамен указанной
+func (h *CardHandler) ReissueCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	card, err := h.cardService.ReissueCard(userID, cardID)
+	if err != nil {
+		writeCardError(w, err, "Failed to reissue card")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(issuedCardResponse(card))
+}
+
+func (h *CardHandler) CloseCard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	card, err := h.cardService.CloseCard(userID, cardID)
+	if err != nil {
+		writeCardError(w, err, "Failed to close card")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// GetCardHistory возвращает журнал изменений статуса карты
+func (h *CardHandler) GetCardHistory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	history, err := h.cardService.GetCardHistory(userID, cardID)
+	if err != nil {
+		writeCardError(w, err, "Failed to get card history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// UpdateAmountCap задает предельную сумму операций по карте
+func (h *CardHandler) UpdateAmountCap(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	var req models.UpdateAmountCapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	card, err := h.cardService.UpdateAmountCap(userID, cardID, &req)
+	if err != nil {
+		writeCardError(w, err, "Failed to update amount cap")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// GetCardControls возвращает ограничения операций по карте
+func (h *CardHandler) GetCardControls(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	controls, err := h.cardService.GetCardControls(userID, cardID)
+	if err != nil {
+		writeCardError(w, err, "Failed to get card controls")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(controls)
+}
+
+// UpdateCardControls изменяет указанные в запросе ограничения, остальные
+// остаются прежними
+func (h *CardHandler) UpdateCardControls(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	var req models.UpdateCardControlsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	controls, err := h.cardService.UpdateCardControls(userID, cardID, &req)
+	if err != nil {
+		writeCardError(w, err, "Failed to update card controls")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(controls)
+}
+
+// GetMCCGroups возвращает группы MCC, которые можно разрешить или запретить
+func (h *CardHandler) GetMCCGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.cardService.GetMCCGroups())
+}
+
+// issuedCardResponse формирует ответ о выпущенной карте. Реквизиты
+// виртуальной карты возвращаются полностью: другой возможности узнать
+// CVV у владельца нет.
+func issuedCardResponse(card *models.Card) models.CardResponse {
+	response := models.CardResponse{
+		ID:             card.ID,
+		AccountID:      card.AccountID,
+		LastFour:       card.Number[len(card.Number)-4:],
+		ExpiryDate:     card.ExpiryDate,
+		CreatedAt:      card.CreatedAt,
+		Product:        card.Product,
+		Status:         card.Status,
+		ReissuedFromID: card.ReissuedFromID,
+		Type:           card.Type,
+		AmountCap:      card.AmountCap,
+	}
+	if card.Type != models.CardTypePhysical {
+		response.Number = card.Number
+		response.CVV = card.CVV
+	}
+	return response
+}
+
+func writeCardError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrCardNotFound:
+		http.Error(w, "Card not found", http.StatusNotFound)
+	case service.ErrCardBlocked:
+		http.Error(w, "Card is blocked", http.StatusConflict)
+	case service.ErrCardExpired:
+		http.Error(w, "Card has expired", http.StatusConflict)
+	case service.ErrCardClosed:
+		http.Error(w, "Card is closed", http.StatusConflict)
+	case service.ErrCardNotBlocked:
+		http.Error(w, "Card is not blocked", http.StatusConflict)
+	case service.ErrCardAlreadyReissued:
+		http.Error(w, "Card is already reissued", http.StatusConflict)
+	case service.ErrInvalidBlockReason:
+		http.Error(w, "Invalid block reason", http.StatusBadRequest)
+	case service.ErrUnsupportedProduct:
+		http.Error(w, "Card product is not available", http.StatusBadRequest)
+	case service.ErrInvalidCardType:
+		http.Error(w, "Invalid card type", http.StatusBadRequest)
+	case service.ErrInvalidCardValidity:
+		http.Error(w, "Card validity must be between 1 and 60 months", http.StatusBadRequest)
+	case service.ErrAmountCapExceeded:
+		http.Error(w, "Card amount cap exceeded", http.StatusUnprocessableEntity)
+	case service.ErrUnknownMCCGroup:
+		http.Error(w, "Unknown MCC group", http.StatusBadRequest)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// CardAuthorizationHandler - API для эквайреров: авторизация операций по
+// картам банка, расчеты и отмена
+type CardAuthorizationHandler struct {
+	authService *service.CardAuthorizationService
+}
+
+func NewCardAuthorizationHandler(authService *service.CardAuthorizationService) *CardAuthorizationHandler {
+	return &CardAuthorizationHandler{authService: authService}
+}
+
+func (h *CardAuthorizationHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/authorizations", h.Authorize).Methods("POST")
+	router.HandleFunc("/authorizations/{id}", h.GetAuthorization).Methods("GET")
+	router.HandleFunc("/authorizations/{id}/settlement", h.Settle).Methods("POST")
+	router.HandleFunc("/authorizations/{id}/reversal", h.Reverse).Methods("POST")
+	router.HandleFunc("/pin-verification", h.VerifyPIN).Methods("POST")
+}
+
+// Authorize возвращает решение по операции. Отказ передается кодом ответа
+// в теле, а не HTTP-статусом.
+func (h *CardAuthorizationHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	var req models.AuthorizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Acquirer = models.AcquirerAPI
+
+	auth, err := h.authService.Authorize(&req)
+	if err != nil {
+		http.Error(w, "Authorization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth)
+}
+
+// VerifyPIN проверяет PIN карты; результат передается кодом ответа
+func (h *CardAuthorizationHandler) VerifyPIN(w http.ResponseWriter, r *http.Request) {
+	var req models.PINVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.authService.VerifyPIN(&req)
+	if err != nil {
+		http.Error(w, "PIN verification failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *CardAuthorizationHandler) GetAuthorization(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	authID, _ := strconv.Atoi(vars["id"])
+
+	auth, err := h.authService.GetAuthorization(models.AcquirerAPI, authID)
+	if err != nil {
+		writeAuthorizationError(w, err, "Failed to get authorization")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth)
+}
+
+func (h *CardAuthorizationHandler) Settle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	authID, _ := strconv.Atoi(vars["id"])
+
+	// Тело запроса необязательно: без суммы списывается вся сумма авторизации
+	var req models.SettlementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	auth, err := h.authService.Settle(models.AcquirerAPI, authID, &req)
+	if err != nil {
+		writeAuthorizationError(w, err, "Settlement failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth)
+}
+
+func (h *CardAuthorizationHandler) Reverse(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	authID, _ := strconv.Atoi(vars["id"])
+
+	auth, err := h.authService.Reverse(models.AcquirerAPI, authID)
+	if err != nil {
+		writeAuthorizationError(w, err, "Reversal failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth)
+}
+
+func writeAuthorizationError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrAuthNotFound:
+		http.Error(w, "Authorization not found", http.StatusNotFound)
+	case service.ErrAuthNotActive:
+		http.Error(w, "Authorization is already settled or reversed", http.StatusConflict)
+	case service.ErrAlreadyReversed:
+		http.Error(w, "Transaction is already reversed or refunded", http.StatusConflict)
+	default:
+		writeHoldError(w, err, fallback)
+	}
+}
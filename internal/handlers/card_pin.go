@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type PINHandler struct {
+	pinService *service.PINService
+}
+
+func NewPINHandler(pinService *service.PINService) *PINHandler {
+	return &PINHandler{pinService: pinService}
+}
+
+func (h *PINHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/cards/{id}/pin", h.SetPIN).Methods("POST")
+	router.HandleFunc("/cards/{id}/pin", h.ChangePIN).Methods("PUT")
+}
+
+// SetPIN устанавливает PIN новой карты
+func (h *PINHandler) SetPIN(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	var req models.SetPINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.pinService.SetPIN(userID, cardID, &req); err != nil {
+		writePINError(w, err, "Failed to set PIN")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePIN меняет PIN карты; в запросе передается пароль владельца
+func (h *PINHandler) ChangePIN(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	var req models.ChangePINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.pinService.ChangePIN(userID, cardID, &req); err != nil {
+		writePINError(w, err, "Failed to change PIN")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePINError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrInvalidCredentials:
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	case service.ErrInvalidPIN:
+		http.Error(w, "PIN must be 4 to 6 digits", http.StatusBadRequest)
+	case service.ErrWeakPIN:
+		http.Error(w, "PIN is too easy to guess", http.StatusBadRequest)
+	case service.ErrPINAlreadySet:
+		http.Error(w, "Card PIN is already set", http.StatusConflict)
+	default:
+		writeCardError(w, err, fallback)
+	}
+}
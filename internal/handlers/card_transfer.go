@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type CardTransferHandler struct {
+	transferService *service.CardTransferService
+}
+
+func NewCardTransferHandler(transferService *service.CardTransferService) *CardTransferHandler {
+	return &CardTransferHandler{transferService: transferService}
+}
+
+func (h *CardTransferHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/card-transfers", h.Transfer).Methods("POST")
+}
+
+// Transfer переводит деньги с карты клиента на карту банка по ее номеру
+func (h *CardTransferHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.CardTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.transferService.Transfer(userID, &req)
+	if err != nil {
+		writeCardTransferError(w, err, "Failed to transfer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+func writeCardTransferError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrInvalidCardNumber:
+		http.Error(w, "Invalid card number", http.StatusBadRequest)
+	case service.ErrRecipientNotFound:
+		http.Error(w, "Recipient card not found", http.StatusNotFound)
+	case service.ErrTransferNotPermitted:
+		http.Error(w, "Card controls do not allow money transfers", http.StatusForbidden)
+	case service.ErrAboveTransactionMax:
+		http.Error(w, "Amount exceeds the card transaction maximum", http.StatusUnprocessableEntity)
+	default:
+		writeHoldError(w, err, fallback)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type DepositHandler struct {
+	depositService *service.DepositService
+}
+
+func NewDepositHandler(depositService *service.DepositService) *DepositHandler {
+	return &DepositHandler{depositService: depositService}
+}
+
+func (h *DepositHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/deposits", h.OpenDeposit).Methods("POST")
+	router.HandleFunc("/deposits", h.ListDeposits).Methods("GET")
+	router.HandleFunc("/deposits/{id}", h.GetDeposit).Methods("GET")
+	router.HandleFunc("/deposits/{id}/withdraw", h.WithdrawEarly).Methods("POST")
+}
+
+func (h *DepositHandler) OpenDeposit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.CreateDepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	deposit, err := h.depositService.OpenDeposit(userID, &req)
+	if err != nil {
+		writeDepositError(w, err, "Failed to open deposit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(deposit)
+}
+
+func (h *DepositHandler) ListDeposits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	deposits, err := h.depositService.GetUserDeposits(userID)
+	if err != nil {
+		http.Error(w, "Failed to get deposits", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deposits)
+}
+
+func (h *DepositHandler) GetDeposit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	depositID, _ := strconv.Atoi(vars["id"])
+
+	deposit, err := h.depositService.GetDeposit(userID, depositID)
+	if err != nil {
+		writeDepositError(w, err, "Failed to get deposit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deposit)
+}
+
+func (h *DepositHandler) WithdrawEarly(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	depositID, _ := strconv.Atoi(vars["id"])
+
+	result, err := h.depositService.WithdrawEarly(userID, depositID)
+	if err != nil {
+		writeDepositError(w, err, "Failed to withdraw deposit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeDepositError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrDepositNotFound:
+		http.Error(w, "Deposit not found", http.StatusNotFound)
+	case service.ErrDepositNotActive:
+		http.Error(w, "Deposit is not active", http.StatusConflict)
+	case service.ErrInvalidDepositTerm:
+		http.Error(w, "Deposit term must be between 1 and 36 months", http.StatusBadRequest)
+	case service.ErrInvalidDepositOption:
+		http.Error(w, "Invalid interest mode or maturity action", http.StatusBadRequest)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
+}
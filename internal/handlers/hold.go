@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type HoldHandler struct {
+	holdService *service.HoldService
+}
+
+func NewHoldHandler(holdService *service.HoldService) *HoldHandler {
+	return &HoldHandler{holdService: holdService}
+}
+
+func (h *HoldHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/holds", h.PlaceHold).Methods("POST")
+	router.HandleFunc("/holds/{id}", h.GetHold).Methods("GET")
+	router.HandleFunc("/holds/{id}/capture", h.CaptureHold).Methods("POST")
+	router.HandleFunc("/holds/{id}/release", h.ReleaseHold).Methods("POST")
+	router.HandleFunc("/accounts/{id}/holds", h.GetAccountHolds).Methods("GET")
+}
+
+func (h *HoldHandler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	hold, err := h.holdService.PlaceHold(userID, &req)
+	if err != nil {
+		writeHoldError(w, err, "Failed to place hold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+func (h *HoldHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	holdID, _ := strconv.Atoi(vars["id"])
+
+	hold, err := h.holdService.GetHold(userID, holdID)
+	if err != nil {
+		writeHoldError(w, err, "Failed to get hold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+func (h *HoldHandler) GetAccountHolds(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	holds, err := h.holdService.GetAccountHolds(userID, accountID)
+	if err != nil {
+		writeHoldError(w, err, "Failed to get holds")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+func (h *HoldHandler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	holdID, _ := strconv.Atoi(vars["id"])
+
+	// Тело запроса необязательно: без суммы списывается вся блокировка
+	var req models.CaptureHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	hold, err := h.holdService.CaptureHold(userID, holdID, req.Amount)
+	if err != nil {
+		writeHoldError(w, err, "Failed to capture hold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+func (h *HoldHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	holdID, _ := strconv.Atoi(vars["id"])
+
+	hold, err := h.holdService.ReleaseHold(userID, holdID)
+	if err != nil {
+		writeHoldError(w, err, "Failed to release hold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+func writeHoldError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrHoldNotFound:
+		http.Error(w, "Hold not found", http.StatusNotFound)
+	case service.ErrHoldNotActive:
+		http.Error(w, "Hold is not active", http.StatusConflict)
+	case service.ErrHoldExpired:
+		http.Error(w, "Hold has expired", http.StatusConflict)
+	case service.ErrCardHoldLocked:
+		http.Error(w, "Card holds are settled or reversed by the acquirer", http.StatusConflict)
+	case service.ErrHoldNoRecipient:
+		http.Error(w, "Hold recipient is required: set to_account_id or to_account_number", http.StatusBadRequest)
+	case service.ErrInvalidHoldExpiry:
+		http.Error(w, "Hold expiry must be in the future and within 30 days", http.StatusBadRequest)
+	default:
+		writeCardError(w, err, fallback)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type InterestHandler struct {
+	interestService *service.InterestService
+}
+
+func NewInterestHandler(interestService *service.InterestService) *InterestHandler {
+	return &InterestHandler{interestService: interestService}
+}
+
+func (h *InterestHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/accounts/{id}/interest", h.GetAccruals).Methods("GET")
+}
+
+func (h *InterestHandler) GetAccruals(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	accruals, err := h.interestService.GetAccruals(userID, accountID)
+	if err != nil {
+		switch err {
+		case service.ErrAccountNotFound:
+			http.Error(w, "Account not found", http.StatusNotFound)
+		case service.ErrNotSavingsAccount:
+			http.Error(w, "Not a savings account", http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to get interest accruals", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accruals)
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+	"bank-api/pkg/iso8583"
+	"bank-api/pkg/pan"
+)
+
+// Код валюты рубля по ISO 4217 (поле 49)
+const isoCurrencyRUB = "643"
+
+// Поля запроса, которые возвращаются в ответе без изменений
+var isoEchoFields = []int{2, 3, 4, 7, 11, 12, 13, 32, 37, 41, 42, 49}
+
+// ISO8583Handler переводит сообщения терминалов в операции авторизации
+// по картам: 0100 - авторизация, 0200 - авторизация с немедленными
+// расчетами, 0400 - отмена, 0800 - сетевое управление. CVV2 передается
+// в поле 48. Код эквайрера в поле 32 к этому моменту проверен сервером
+// по MAC, и операции ищутся только среди операций этого эквайрера.
+type ISO8583Handler struct {
+	authService *service.CardAuthorizationService
+}
+
+func NewISO8583Handler(authService *service.CardAuthorizationService) *ISO8583Handler {
+	return &ISO8583Handler{authService: authService}
+}
+
+func (h *ISO8583Handler) Handle(request *iso8583.Message) *iso8583.Message {
+	switch request.MTI {
+	case iso8583.MTINetworkRequest:
+		// Вход в сеть, выход и эхо-тест всегда подтверждаются
+		response := request.Response(7, 11, 70)
+		response.Set(39, models.ResponseApproved)
+		return response
+	case iso8583.MTIAuthorizationRequest:
+		return h.authorize(request, false)
+	case iso8583.MTIFinancialRequest:
+		return h.authorize(request, true)
+	case iso8583.MTIReversalRequest:
+		return h.reverse(request)
+	}
+
+	// На неизвестный запрос отвечаем отказом, на ответы не отвечаем
+	if len(request.MTI) == 4 && (request.MTI[2]-'0')%2 == 0 {
+		response := request.Response(isoEchoFields...)
+		response.Set(39, models.ResponseInvalidTxn)
+		return response
+	}
+	return nil
+}
+
+// authorize обрабатывает 0100 и 0200. Для 0200 одобренная операция сразу
+// рассчитывается.
+func (h *ISO8583Handler) authorize(request *iso8583.Message, settle bool) *iso8583.Message {
+	response := request.Response(isoEchoFields...)
+
+	req, ok := isoAuthorizationRequest(request)
+	if !ok {
+		response.Set(39, models.ResponseInvalidTxn)
+		return response
+	}
+
+	auth, err := h.authService.Authorize(req)
+	if err != nil {
+		log.Printf("ISO 8583 authorization %s failed: %v", req.Reference, err)
+		response.Set(39, models.ResponseSystemError)
+		return response
+	}
+
+	if settle && auth.Status == models.AuthorizationApproved {
+		if _, err := h.authService.Settle(req.Acquirer, auth.ID, &models.SettlementRequest{}); err != nil {
+			log.Printf("ISO 8583 settlement of authorization %d failed: %v", auth.ID, err)
+			if _, err := h.authService.Reverse(req.Acquirer, auth.ID); err != nil {
+				log.Printf("ISO 8583 reversal of authorization %d failed: %v", auth.ID, err)
+			}
+			response.Set(39, models.ResponseSystemError)
+			return response
+		}
+	}
+
+	if auth.ApprovalCode != "" {
+		response.Set(38, auth.ApprovalCode)
+	}
+	response.Set(39, auth.ResponseCode)
+	return response
+}
+
+// reverse обрабатывает 0400. Исходная операция ищется по RRN среди
+// операций эквайрера; повторная отмена и отмена отклоненной операции
+// подтверждаются.
+func (h *ISO8583Handler) reverse(request *iso8583.Message) *iso8583.Message {
+	response := request.Response(isoEchoFields...)
+
+	acquirer := request.Get(iso8583.FieldAcquirer)
+	auth, err := h.authService.GetAuthorizationByReference(acquirer, isoReference(request))
+	if err == nil && auth.Status != models.AuthorizationReversed && auth.Status != models.AuthorizationDeclined {
+		_, err = h.authService.Reverse(acquirer, auth.ID)
+	}
+
+	switch err {
+	case nil:
+		response.Set(39, models.ResponseApproved)
+	case service.ErrAuthNotFound:
+		response.Set(39, models.ResponseNoOriginal)
+	default:
+		log.Printf("ISO 8583 reversal %s failed: %v", request.Get(37), err)
+		response.Set(39, models.ResponseSystemError)
+	}
+	return response
+}
+
+// isoAuthorizationRequest собирает запрос авторизации из полей сообщения.
+// Поддерживаются покупка и выдача наличных в рублях.
+func isoAuthorizationRequest(request *iso8583.Message) (*models.AuthorizationRequest, bool) {
+	processing := request.Get(3)
+	if len(processing) < 2 || (processing[:2] != "00" && processing[:2] != "01") {
+		return nil, false
+	}
+	if currency := request.Get(49); currency != "" && currency != isoCurrencyRUB {
+		return nil, false
+	}
+	if request.Get(37) == "" || request.Get(iso8583.FieldAcquirer) == "" {
+		return nil, false
+	}
+
+	// Сумма передается в копейках
+	kopecks, err := strconv.ParseInt(request.Get(4), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	// Срок действия в поле 14 передается как YYMM
+	expiry := request.Get(14)
+	if len(expiry) == 4 {
+		expiry = expiry[2:] + "/" + expiry[:2]
+	}
+
+	auth := &models.AuthorizationRequest{
+		PAN:          request.Get(2),
+		Expiry:       expiry,
+		CVV:          strings.TrimSpace(request.Get(48)),
+		Amount:       float64(kopecks) / 100,
+		MerchantID:   strings.TrimSpace(request.Get(42)),
+		MerchantName: strings.TrimSpace(request.Get(43)),
+		MCC:          request.Get(18),
+		Reference:    isoReference(request),
+		Channel:      models.ChannelPOS,
+		Acquirer:     request.Get(iso8583.FieldAcquirer),
+	}
+
+	// Способ ввода карты (поле 22) и условия операции (поле 25)
+	entryMode := request.Get(22)
+	if len(entryMode) >= 2 {
+		entryMode = entryMode[:2]
+	}
+	switch {
+	case processing[:2] == "01":
+		auth.Channel = models.ChannelATM
+	case entryMode == "01" || entryMode == "81" || request.Get(25) == "59":
+		auth.Channel = models.ChannelEcommerce
+	}
+	auth.Contactless = entryMode == "07" || entryMode == "91"
+
+	// Последние две позиции поля 43 - код страны ТСП
+	if location := request.Get(43); len(location) == 40 {
+		auth.MerchantCountry = strings.TrimSpace(location[38:])
+	}
+
+	if request.Has(52) {
+		pin, ok := isoPIN(request.Get(52), pan.Normalize(auth.PAN))
+		if !ok {
+			return nil, false
+		}
+		auth.PIN = pin
+	}
+
+	return auth, true
+}
+
+// isoPIN извлекает PIN из PIN-блока ISO 9564 формата 0: блок PIN
+// складывается по модулю 2 с 12 цифрами номера карты без контрольной.
+// Шифрование PIN-блока на участке от терминала снимается до хоста.
+func isoPIN(block, number string) (string, bool) {
+	if len(block) != 16 || len(number) < 13 {
+		return "", false
+	}
+	account := "0000" + number[len(number)-13:len(number)-1]
+
+	decoded := make([]byte, 16)
+	for i := range decoded {
+		a, err1 := strconv.ParseUint(block[i:i+1], 16, 4)
+		b, err2 := strconv.ParseUint(account[i:i+1], 16, 4)
+		if err1 != nil || err2 != nil {
+			return "", false
+		}
+		decoded[i] = "0123456789ABCDEF"[a^b]
+	}
+
+	length := int(decoded[1] - '0')
+	if decoded[0] != '0' || length < 4 || length > 6 {
+		return "", false
+	}
+	pin := string(decoded[2 : 2+length])
+	if strings.Trim(pin, "0123456789") != "" || strings.Trim(string(decoded[2+length:]), "F") != "" {
+		return "", false
+	}
+	return pin, true
+}
+
+// isoReference возвращает идентификатор операции - RRN. Он уникален только
+// в пределах эквайрера, поэтому авторизации ищутся вместе с его кодом.
+func isoReference(request *iso8583.Message) string {
+	return strings.TrimSpace(request.Get(37))
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type LimitHandler struct {
+	limitService *service.LimitService
+}
+
+func NewLimitHandler(limitService *service.LimitService) *LimitHandler {
+	return &LimitHandler{limitService: limitService}
+}
+
+func (h *LimitHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/accounts/{id}/limits", h.GetAccountLimits).Methods("GET")
+	router.HandleFunc("/accounts/{id}/limits", h.UpdateAccountLimits).Methods("PUT")
+	router.HandleFunc("/cards/{id}/limits", h.GetCardLimits).Methods("GET")
+	router.HandleFunc("/cards/{id}/limits", h.UpdateCardLimits).Methods("PUT")
+	router.HandleFunc("/settings/timezone", h.GetTimezone).Methods("GET")
+	router.HandleFunc("/settings/timezone", h.SetTimezone).Methods("PUT")
+}
+
+// GetAccountLimits возвращает лимиты счета и их использование в текущих
+// сутках и месяце
+func (h *LimitHandler) GetAccountLimits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	limits, err := h.limitService.GetAccountLimits(userID, accountID)
+	if err != nil {
+		writeLimitError(w, err, "Failed to get limits")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+func (h *LimitHandler) UpdateAccountLimits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	var req models.UpdateLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	limits, err := h.limitService.UpdateAccountLimits(userID, accountID, &req)
+	if err != nil {
+		writeLimitError(w, err, "Failed to update limits")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+func (h *LimitHandler) GetCardLimits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	limits, err := h.limitService.GetCardLimits(userID, cardID)
+	if err != nil {
+		writeLimitError(w, err, "Failed to get limits")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+func (h *LimitHandler) UpdateCardLimits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	cardID, _ := strconv.Atoi(vars["id"])
+
+	var req models.UpdateLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	limits, err := h.limitService.UpdateCardLimits(userID, cardID, &req)
+	if err != nil {
+		writeLimitError(w, err, "Failed to update limits")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+func (h *LimitHandler) GetTimezone(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	settings, err := h.limitService.GetTimezone(userID)
+	if err != nil {
+		http.Error(w, "Failed to get timezone", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *LimitHandler) SetTimezone(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.TimezoneSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.limitService.SetTimezone(userID, &req)
+	if err != nil {
+		writeLimitError(w, err, "Failed to update timezone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func writeLimitError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrInvalidLimit:
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+	case service.ErrLimitAboveMaximum:
+		http.Error(w, "Limit exceeds the bank maximum", http.StatusBadRequest)
+	case service.ErrInvalidTimezone:
+		http.Error(w, "Invalid timezone", http.StatusBadRequest)
+	default:
+		writeCardError(w, err, fallback)
+	}
+}
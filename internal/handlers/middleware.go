@@ -3,6 +3,7 @@ package handlers
 import (
 	"bank-api/pkg/auth"
 	"context"
+	"crypto/subtle"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -29,3 +30,19 @@ func AuthMiddleware(jwtSecret string) mux.MiddlewareFunc {
 		})
 	}
 }
+
+// AcquirerMiddleware пропускает запросы эквайреров с общим ключом API
+// в заголовке X-API-Key
+func AcquirerMiddleware(apiKey string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type P2PHandler struct {
+	p2pService *service.P2PService
+}
+
+func NewP2PHandler(p2pService *service.P2PService) *P2PHandler {
+	return &P2PHandler{p2pService: p2pService}
+}
+
+func (h *P2PHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/p2p/settings", h.GetSettings).Methods("GET")
+	router.HandleFunc("/p2p/settings", h.UpdateSettings).Methods("PUT")
+	router.HandleFunc("/p2p/settings/phone/confirm", h.ConfirmPhone).Methods("POST")
+	router.HandleFunc("/p2p/transfers", h.CreateTransfer).Methods("POST")
+	router.HandleFunc("/p2p/transfers/{id}/confirm", h.ConfirmTransfer).Methods("POST")
+}
+
+func (h *P2PHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	settings, err := h.p2pService.GetSettings(userID)
+	if err != nil {
+		writeP2PError(w, err, "Failed to get settings")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *P2PHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.P2PSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.p2pService.UpdateSettings(userID, &req)
+	if err != nil {
+		writeP2PError(w, err, "Failed to update settings")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// ConfirmPhone подтверждает телефон кодом, отправленным в SMS при
+// изменении настроек
+func (h *P2PHandler) ConfirmPhone(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.ConfirmPhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.p2pService.ConfirmPhone(userID, &req)
+	if err != nil {
+		writeP2PError(w, err, "Failed to confirm phone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// CreateTransfer создает перевод и возвращает маскированное имя получателя
+// для подтверждения
+func (h *P2PHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.CreateP2PTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.p2pService.CreateTransfer(userID, &req)
+	if err != nil {
+		writeP2PError(w, err, "Failed to create transfer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+func (h *P2PHandler) ConfirmTransfer(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	transferID, _ := strconv.Atoi(vars["id"])
+
+	transfer, err := h.p2pService.ConfirmTransfer(userID, transferID)
+	if err != nil {
+		writeP2PError(w, err, "Transfer failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+func writeP2PError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrRecipientNotFound:
+		http.Error(w, "Recipient not found", http.StatusNotFound)
+	case service.ErrNoReceivingAccount:
+		http.Error(w, "Recipient has no account to receive transfers", http.StatusConflict)
+	case service.ErrInvalidPhone:
+		http.Error(w, "Invalid phone number", http.StatusBadRequest)
+	case service.ErrPhoneTaken:
+		http.Error(w, "Phone number is already in use", http.StatusConflict)
+	case service.ErrPhoneCodeNotFound:
+		http.Error(w, "No phone verification is pending", http.StatusNotFound)
+	case service.ErrPhoneCodeExpired:
+		http.Error(w, "Verification code has expired, update the phone to get a new one", http.StatusGone)
+	case service.ErrInvalidPhoneCode:
+		http.Error(w, "Invalid verification code", http.StatusBadRequest)
+	case service.ErrTransferNotFound:
+		http.Error(w, "Transfer not found", http.StatusNotFound)
+	case service.ErrTransferNotPending:
+		http.Error(w, "Transfer is already confirmed", http.StatusConflict)
+	case service.ErrTransferExpired:
+		http.Error(w, "Transfer confirmation has expired", http.StatusGone)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"bank-api/internal/service"
+	"bank-api/pkg/iso20022"
+
+	"github.com/gorilla/mux"
+)
+
+// Ограничение размера загружаемого файла платежных поручений
+const maxPaymentFileSize = 10 << 20
+
+type PaymentImportHandler struct {
+	importService *service.PaymentImportService
+}
+
+func NewPaymentImportHandler(importService *service.PaymentImportService) *PaymentImportHandler {
+	return &PaymentImportHandler{importService: importService}
+}
+
+func (h *PaymentImportHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/payments/pain001", h.ImportPain001).Methods("POST")
+}
+
+func (h *PaymentImportHandler) ImportPain001(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxPaymentFileSize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.importService.ImportCreditTransfers(userID, data)
+	if err != nil {
+		if errors.Is(err, iso20022.ErrInvalidDocument) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err == service.ErrImportInProgress {
+			http.Error(w, "Payment file with this MsgId is already being processed", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Import failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(report)
+}
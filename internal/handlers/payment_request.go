@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type PaymentRequestHandler struct {
+	requestService *service.PaymentRequestService
+}
+
+func NewPaymentRequestHandler(requestService *service.PaymentRequestService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{requestService: requestService}
+}
+
+func (h *PaymentRequestHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/payment-requests", h.CreateRequest).Methods("POST")
+	router.HandleFunc("/payment-requests/incoming", h.ListIncoming).Methods("GET")
+	router.HandleFunc("/payment-requests/outgoing", h.ListOutgoing).Methods("GET")
+	router.HandleFunc("/payment-requests/{id}", h.GetRequest).Methods("GET")
+	router.HandleFunc("/payment-requests/{id}/accept", h.AcceptRequest).Methods("POST")
+	router.HandleFunc("/payment-requests/{id}/decline", h.DeclineRequest).Methods("POST")
+}
+
+func (h *PaymentRequestHandler) CreateRequest(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.CreatePaymentRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.requestService.CreateRequest(userID, &req)
+	if err != nil {
+		writePaymentRequestError(w, err, "Failed to create payment request")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(request)
+}
+
+func (h *PaymentRequestHandler) ListIncoming(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	requests, err := h.requestService.GetIncomingRequests(userID)
+	if err != nil {
+		http.Error(w, "Failed to get payment requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+func (h *PaymentRequestHandler) ListOutgoing(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	requests, err := h.requestService.GetOutgoingRequests(userID)
+	if err != nil {
+		http.Error(w, "Failed to get payment requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+func (h *PaymentRequestHandler) GetRequest(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	requestID, _ := strconv.Atoi(vars["id"])
+
+	request, err := h.requestService.GetRequest(userID, requestID)
+	if err != nil {
+		writePaymentRequestError(w, err, "Failed to get payment request")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// AcceptRequest оплачивает запрос. Тело запроса необязательно, если запрос
+// выставлен на счет плательщика.
+func (h *PaymentRequestHandler) AcceptRequest(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	requestID, _ := strconv.Atoi(vars["id"])
+
+	var req models.AcceptPaymentRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.requestService.AcceptRequest(userID, requestID, &req)
+	if err != nil {
+		writePaymentRequestError(w, err, "Payment failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+func (h *PaymentRequestHandler) DeclineRequest(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	requestID, _ := strconv.Atoi(vars["id"])
+
+	request, err := h.requestService.DeclineRequest(userID, requestID)
+	if err != nil {
+		writePaymentRequestError(w, err, "Failed to decline payment request")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+func writePaymentRequestError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrRecipientNotFound:
+		http.Error(w, "Payer not found", http.StatusNotFound)
+	case service.ErrSelfRequest:
+		http.Error(w, "Cannot request money from yourself", http.StatusBadRequest)
+	case service.ErrRequestNotFound:
+		http.Error(w, "Payment request not found", http.StatusNotFound)
+	case service.ErrRequestNotPending:
+		http.Error(w, "Payment request is already answered", http.StatusConflict)
+	case service.ErrRequestExpired:
+		http.Error(w, "Payment request has expired", http.StatusGone)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
+}
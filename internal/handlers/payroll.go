@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type PayrollHandler struct {
+	payrollService *service.PayrollService
+}
+
+func NewPayrollHandler(payrollService *service.PayrollService) *PayrollHandler {
+	return &PayrollHandler{payrollService: payrollService}
+}
+
+func (h *PayrollHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/payroll", h.SubmitPayroll).Methods("POST")
+	router.HandleFunc("/payroll", h.ListBatches).Methods("GET")
+	router.HandleFunc("/payroll/{id}", h.GetBatch).Methods("GET")
+	router.HandleFunc("/payroll/{id}/report", h.GetReport).Methods("GET")
+}
+
+// SubmitPayroll принимает ведомость в JSON или CSV (Content-Type: text/csv).
+// Для CSV счет списания, режим и идентификатор ведомости передаются
+// в параметрах from_account_id, mode и reference.
+func (h *PayrollHandler) SubmitPayroll(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.PayrollRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxPaymentFileSize))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Items, err = service.ParsePayrollCSV(data); err != nil {
+			writePayrollError(w, err, "Invalid payroll file")
+			return
+		}
+
+		query := r.URL.Query()
+		req.FromAccountID, _ = strconv.Atoi(query.Get("from_account_id"))
+		req.Mode = models.PayrollMode(query.Get("mode"))
+		req.Reference = query.Get("reference")
+	} else if err := json.NewDecoder(io.LimitReader(r.Body, maxPaymentFileSize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.payrollService.SubmitPayroll(userID, &req)
+	if err != nil {
+		writePayrollError(w, err, "Payroll failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(batch)
+}
+
+func (h *PayrollHandler) ListBatches(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	batches, err := h.payrollService.GetUserBatches(userID)
+	if err != nil {
+		http.Error(w, "Failed to get payroll batches", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+func (h *PayrollHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	batchID, _ := strconv.Atoi(vars["id"])
+
+	batch, err := h.payrollService.GetBatch(userID, batchID)
+	if err != nil {
+		writePayrollError(w, err, "Failed to get payroll batch")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// GetReport отдает отчет об исполнении ведомости файлом CSV
+func (h *PayrollHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	batchID, _ := strconv.Atoi(vars["id"])
+
+	batch, err := h.payrollService.GetBatch(userID, batchID)
+	if err != nil {
+		writePayrollError(w, err, "Failed to get payroll batch")
+		return
+	}
+
+	file, err := h.payrollService.RenderReport(batch)
+	if err != nil {
+		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file.Filename+`"`)
+	w.Write(file.Data)
+}
+
+func writePayrollError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPayrollFile):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == service.ErrInvalidPayrollMode:
+		http.Error(w, "Invalid payroll mode", http.StatusBadRequest)
+	case err == service.ErrPayrollNotFound:
+		http.Error(w, "Payroll batch not found", http.StatusNotFound)
+	case err == service.ErrPayrollInProgress:
+		http.Error(w, "Payroll batch with this reference is already being processed", http.StatusConflict)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
+}
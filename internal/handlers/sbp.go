@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type SBPHandler struct {
+	sbpService *service.SBPService
+}
+
+func NewSBPHandler(sbpService *service.SBPService) *SBPHandler {
+	return &SBPHandler{sbpService: sbpService}
+}
+
+func (h *SBPHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/sbp/merchants", h.RegisterMerchant).Methods("POST")
+	router.HandleFunc("/sbp/merchants", h.ListMerchants).Methods("GET")
+	router.HandleFunc("/sbp/merchants/{id}/qr", h.CreateQR).Methods("POST")
+	router.HandleFunc("/sbp/qr/{qrcId}", h.GetQR).Methods("GET")
+	router.HandleFunc("/sbp/links/resolve", h.ResolveLink).Methods("POST")
+	router.HandleFunc("/sbp/payments", h.Pay).Methods("POST")
+	router.HandleFunc("/sbp/payments/{id}", h.GetPayment).Methods("GET")
+}
+
+// RegisterMerchant регистрирует ТСП. Ключ подписи уведомлений возвращается
+// только в ответе на этот запрос.
+func (h *SBPHandler) RegisterMerchant(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.RegisterMerchantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	merchant, err := h.sbpService.RegisterMerchant(userID, &req)
+	if err != nil {
+		writeSBPError(w, err, "Failed to register merchant")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(merchant)
+}
+
+func (h *SBPHandler) ListMerchants(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	merchants, err := h.sbpService.GetUserMerchants(userID)
+	if err != nil {
+		http.Error(w, "Failed to get merchants", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merchants)
+}
+
+func (h *SBPHandler) CreateQR(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	merchantID, _ := strconv.Atoi(vars["id"])
+
+	var req models.CreateQRRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	qr, err := h.sbpService.CreateQR(userID, merchantID, &req)
+	if err != nil {
+		writeSBPError(w, err, "Failed to create QR code")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(qr)
+}
+
+func (h *SBPHandler) GetQR(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+
+	qr, err := h.sbpService.GetQR(userID, vars["qrcId"])
+	if err != nil {
+		writeSBPError(w, err, "Failed to get QR code")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(qr)
+}
+
+// ResolveLink возвращает получателя и сумму по ссылке для подтверждения оплаты
+func (h *SBPHandler) ResolveLink(w http.ResponseWriter, r *http.Request) {
+	var req models.ResolveLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.sbpService.ResolveLink(req.Link)
+	if err != nil {
+		writeSBPError(w, err, "Failed to resolve link")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+func (h *SBPHandler) Pay(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.SBPPayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.sbpService.Pay(userID, &req)
+	if err != nil {
+		writeSBPError(w, err, "Payment failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment)
+}
+
+func (h *SBPHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	paymentID, _ := strconv.Atoi(vars["id"])
+
+	payment, err := h.sbpService.GetPayment(userID, paymentID)
+	if err != nil {
+		writeSBPError(w, err, "Failed to get payment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+func writeSBPError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrMerchantNotFound:
+		http.Error(w, "Merchant not found", http.StatusNotFound)
+	case service.ErrInvalidCallbackURL:
+		http.Error(w, "Callback URL must be a public https address", http.StatusBadRequest)
+	case service.ErrInvalidQRParams:
+		http.Error(w, "Invalid QR code parameters", http.StatusBadRequest)
+	case service.ErrInvalidPaymentLink:
+		http.Error(w, "Invalid payment link", http.StatusBadRequest)
+	case service.ErrForeignBank:
+		http.Error(w, "Payment link belongs to another bank", http.StatusUnprocessableEntity)
+	case service.ErrQRNotFound:
+		http.Error(w, "QR code not found", http.StatusNotFound)
+	case service.ErrQRNotActive:
+		http.Error(w, "QR code is already paid", http.StatusConflict)
+	case service.ErrQRExpired:
+		http.Error(w, "QR code has expired", http.StatusGone)
+	case service.ErrPaymentNotFound:
+		http.Error(w, "Payment not found", http.StatusNotFound)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
+}
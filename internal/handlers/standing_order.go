@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bank-api/internal/models"
+	"bank-api/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type StandingOrderHandler struct {
+	orderService *service.StandingOrderService
+}
+
+func NewStandingOrderHandler(orderService *service.StandingOrderService) *StandingOrderHandler {
+	return &StandingOrderHandler{orderService: orderService}
+}
+
+func (h *StandingOrderHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/standing-orders", h.CreateOrder).Methods("POST")
+	router.HandleFunc("/standing-orders", h.ListOrders).Methods("GET")
+	router.HandleFunc("/standing-orders/{id}", h.GetOrder).Methods("GET")
+	router.HandleFunc("/standing-orders/{id}", h.UpdateOrder).Methods("PATCH")
+	router.HandleFunc("/standing-orders/{id}", h.CancelOrder).Methods("DELETE")
+	router.HandleFunc("/standing-orders/{id}/runs", h.GetOrderRuns).Methods("GET")
+}
+
+func (h *StandingOrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req models.CreateStandingOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.CreateOrder(userID, &req)
+	if err != nil {
+		writeStandingOrderError(w, err, "Failed to create standing order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *StandingOrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	orders, err := h.orderService.GetUserOrders(userID)
+	if err != nil {
+		http.Error(w, "Failed to get standing orders", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *StandingOrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	orderID, _ := strconv.Atoi(vars["id"])
+
+	order, err := h.orderService.GetOrder(userID, orderID)
+	if err != nil {
+		writeStandingOrderError(w, err, "Failed to get standing order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *StandingOrderHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	orderID, _ := strconv.Atoi(vars["id"])
+
+	var req models.UpdateStandingOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.UpdateOrder(userID, orderID, &req)
+	if err != nil {
+		writeStandingOrderError(w, err, "Failed to update standing order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *StandingOrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	orderID, _ := strconv.Atoi(vars["id"])
+
+	order, err := h.orderService.CancelOrder(userID, orderID)
+	if err != nil {
+		writeStandingOrderError(w, err, "Failed to cancel standing order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *StandingOrderHandler) GetOrderRuns(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	orderID, _ := strconv.Atoi(vars["id"])
+
+	runs, err := h.orderService.GetOrderRuns(userID, orderID)
+	if err != nil {
+		writeStandingOrderError(w, err, "Failed to get standing order runs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+func writeStandingOrderError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrOrderNotFound:
+		http.Error(w, "Standing order not found", http.StatusNotFound)
+	case service.ErrOrderNotActive:
+		http.Error(w, "Standing order is not active", http.StatusConflict)
+	case service.ErrInvalidSchedule:
+		http.Error(w, "Invalid schedule", http.StatusBadRequest)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
+}
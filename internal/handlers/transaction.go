@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 
+	"bank-api/internal/models"
 	"bank-api/internal/service"
 
 	"github.com/gorilla/mux"
@@ -22,39 +24,33 @@ func (h *TransactionHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/accounts/{id}/deposit", h.Deposit).Methods("POST")
 	router.HandleFunc("/accounts/{id}/withdraw", h.Withdraw).Methods("POST")
 	router.HandleFunc("/accounts/{id}/transactions", h.GetTransactions).Methods("GET")
+	router.HandleFunc("/transactions/{id}/reverse", h.Reverse).Methods("POST")
+	router.HandleFunc("/transactions/{id}/refund", h.Refund).Methods("POST")
+}
+
+type amountRequest struct {
+	Amount      float64 `json:"amount" validate:"required,gt=0"`
+	Description string  `json:"description"`
 }
 
 func (h *TransactionHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(int)
-	_ = userID
 	vars := mux.Vars(r)
 	accountID, _ := strconv.Atoi(vars["id"])
 
-	var req struct {
-		Amount      float64 `json:"amount" validate:"required,gt=0"`
-		Description string  `json:"description"`
-	}
-
+	var req amountRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// В реальном приложении email должен браться из БД по userID
-	userEmail := "user@example.com"
-
 	if err := h.transactionSvc.ProcessDeposit(
+		userID,
 		accountID,
 		req.Amount,
 		req.Description,
-		userEmail,
 	); err != nil {
-		switch err {
-		case service.ErrAccountNotFound:
-			http.Error(w, "Account not found", http.StatusNotFound)
-		default:
-			http.Error(w, "Deposit failed", http.StatusInternalServerError)
-		}
+		writeLedgerError(w, err, "Deposit failed")
 		return
 	}
 
@@ -63,9 +59,111 @@ func (h *TransactionHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TransactionHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
-	// Аналогично Deposit, но вызывает ProcessWithdrawal
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	var req amountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.transactionSvc.ProcessWithdrawal(
+		userID,
+		accountID,
+		req.Amount,
+		req.Description,
+	); err != nil {
+		writeLedgerError(w, err, "Withdrawal failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Withdrawal successful"})
 }
 
 func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
-	// Реализация получения истории транзакций
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	accountID, _ := strconv.Atoi(vars["id"])
+
+	transactions, err := h.transactionSvc.GetTransactions(userID, accountID)
+	if err != nil {
+		writeLedgerError(w, err, "Failed to get transactions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transactions)
+}
+
+// Reverse сторнирует операцию. Доступно только операторам.
+func (h *TransactionHandler) Reverse(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	transactionID, _ := strconv.Atoi(vars["id"])
+
+	var req models.ReverseTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	reversals, err := h.transactionSvc.ReverseTransaction(userID, transactionID, req.Reason)
+	if err != nil {
+		writeCompensationError(w, err, "Reversal failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reversals)
+}
+
+// Refund возвращает плательщику оплату, поступившую на счет ТСП
+func (h *TransactionHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	vars := mux.Vars(r)
+	transactionID, _ := strconv.Atoi(vars["id"])
+
+	// Тело запроса необязательно: без суммы возвращается весь остаток
+	var req models.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refunds, err := h.transactionSvc.RefundTransaction(userID, transactionID, &req)
+	if err != nil {
+		writeCompensationError(w, err, "Refund failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refunds)
+}
+
+func writeCompensationError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrForbidden:
+		http.Error(w, "Operation is not permitted", http.StatusForbidden)
+	case service.ErrTransactionNotFound:
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	case service.ErrNotReversible:
+		http.Error(w, "Transaction cannot be reversed", http.StatusUnprocessableEntity)
+	case service.ErrNotRefundable:
+		http.Error(w, "Transaction is not a merchant payment", http.StatusUnprocessableEntity)
+	case service.ErrAlreadyReversed:
+		http.Error(w, "Transaction is already reversed or refunded", http.StatusConflict)
+	case service.ErrRefundTooLarge:
+		http.Error(w, "Refund exceeds the unrefunded amount", http.StatusBadRequest)
+	default:
+		writeLedgerError(w, err, fallback)
+	}
 }
@@ -2,26 +2,59 @@ package models
 
 import "time"
 
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "active"
+	AccountStatusFrozen AccountStatus = "frozen"
+	AccountStatusClosed AccountStatus = "closed"
+)
+
+type AccountType string
+
+const (
+	AccountTypeCurrent AccountType = "current"
+	AccountTypeSavings AccountType = "savings"
+)
+
 type Account struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Balance   float64   `json:"balance"`
-	Currency  string    `json:"currency"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID      int     `json:"id"`
+	UserID  int     `json:"user_id"`
+	Number  string  `json:"number"`
+	Balance float64 `json:"balance"`
+	// Остаток за вычетом действующих блокировок
+	AvailableBalance float64       `json:"available_balance"`
+	Currency         string        `json:"currency"`
+	Type             AccountType   `json:"type"`
+	Status           AccountStatus `json:"status"`
+	// Годовая процентная ставка для сберегательных счетов
+	InterestRate float64   `json:"interest_rate"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 type CreateAccountRequest struct {
-	Currency string `json:"currency" validate:"required,oneof=RUB"`
+	Currency string      `json:"currency" validate:"required,oneof=RUB"`
+	Type     AccountType `json:"type" validate:"omitempty,oneof=current savings"`
 }
 
 type AccountResponse struct {
-	ID        int       `json:"id"`
-	Balance   float64   `json:"balance"`
-	Currency  string    `json:"currency"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           int           `json:"id"`
+	Number       string        `json:"number"`
+	Balance      float64       `json:"balance"`
+	Available    float64       `json:"available_balance"`
+	Currency     string        `json:"currency"`
+	Type         AccountType   `json:"type"`
+	Status       AccountStatus `json:"status"`
+	InterestRate float64       `json:"interest_rate,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
 }
 
 type UpdateBalanceRequest struct {
 	Amount float64 `json:"amount" validate:"required"`
 }
+
+type CloseAccountRequest struct {
+	// Счет для перевода остатка; обязателен, если баланс не нулевой
+	TransferToAccountID int `json:"transfer_to_account_id"`
+}
@@ -2,6 +2,52 @@ package models
 
 import "time"
 
+// CardProduct - платежная система карты
+type CardProduct string
+
+const (
+	CardMir        CardProduct = "mir"
+	CardVisa       CardProduct = "visa"
+	CardMastercard CardProduct = "mastercard"
+)
+
+// CardType - вид карты
+type CardType string
+
+const (
+	CardTypePhysical CardType = "physical"
+	// Виртуальная карта для оплаты в интернете выпускается сразу, ее
+	// реквизиты возвращаются владельцу при выпуске
+	CardTypeVirtual CardType = "virtual"
+	// Одноразовая карта блокируется после первой одобренной операции
+	CardTypeSingleUse CardType = "single_use"
+)
+
+type CardStatus string
+
+const (
+	CardStatusActive CardStatus = "active"
+	// Временная блокировка владельцем, он же может ее снять
+	CardStatusBlocked CardStatus = "blocked"
+	// Блокировка при утере или краже: карту можно только перевыпустить
+	CardStatusPermanentlyBlocked CardStatus = "permanently_blocked"
+	CardStatusExpired            CardStatus = "expired"
+	CardStatusClosed             CardStatus = "closed"
+)
+
+// CardBlockReason - причина блокировки карты
+type CardBlockReason string
+
+const (
+	CardBlockTemporary CardBlockReason = "temporary"
+	CardBlockLost      CardBlockReason = "lost"
+	CardBlockStolen    CardBlockReason = "stolen"
+	// Одноразовая карта использована
+	CardBlockUsed CardBlockReason = "used"
+	// Исчерпаны попытки ввода PIN; владелец может разблокировать карту
+	CardBlockPINAttempts CardBlockReason = "pin_attempts"
+)
+
 type Card struct {
 	ID         int       `json:"id"`
 	AccountID  int       `json:"account_id"`
@@ -9,10 +55,37 @@ type Card struct {
 	ExpiryDate string    `json:"expiry_date"`
 	CVV        string    `json:"-"`
 	CreatedAt  time.Time `json:"created_at"`
+	// Платежная система определяется по BIN, из которого выпущена карта
+	Product CardProduct `json:"product"`
+	// Ключевой хеш номера: номер хранится зашифрованным, а уникальность
+	// и поиск по номеру проверяются по хешу
+	NumberHash  string          `json:"-"`
+	Status      CardStatus      `json:"status"`
+	BlockReason CardBlockReason `json:"block_reason,omitempty"`
+	// Карта, вместо которой выпущена эта
+	ReissuedFromID *int     `json:"reissued_from_id,omitempty"`
+	Type           CardType `json:"type"`
+	// Предельная сумма операций за весь срок действия карты
+	AmountCap *float64 `json:"amount_cap,omitempty"`
+	// Начало месяца, следующего за сроком действия. Хранится открыто, в
+	// отличие от срока на карте, чтобы отбирать карты с истекающим сроком.
+	ExpiresAt *time.Time `json:"-"`
 }
 
 type CreateCardRequest struct {
-	AccountID int `json:"account_id" validate:"required"`
+	AccountID int         `json:"account_id" validate:"required"`
+	Product   CardProduct `json:"product" validate:"omitempty,oneof=mir visa mastercard"`
+	// По умолчанию выпускается пластиковая карта
+	Type      CardType `json:"type" validate:"omitempty,oneof=physical virtual single_use"`
+	AmountCap *float64 `json:"amount_cap" validate:"omitempty,gt=0"`
+	// Срок действия в месяцах; по умолчанию - 3 года
+	ValidityMonths int `json:"validity_months" validate:"omitempty,min=1,max=60"`
+}
+
+// UpdateAmountCapRequest задает предельную сумму операций по карте;
+// null снимает ограничение
+type UpdateAmountCapRequest struct {
+	AmountCap *float64 `json:"amount_cap" validate:"omitempty,gt=0"`
 }
 
 type CardResponse struct {
@@ -21,4 +94,32 @@ type CardResponse struct {
 	LastFour   string    `json:"last_four"`
 	ExpiryDate string    `json:"expiry_date"`
 	CreatedAt  time.Time `json:"created_at"`
+	// Платежная система карты
+	Product        CardProduct `json:"product"`
+	Status         CardStatus  `json:"status"`
+	ReissuedFromID *int        `json:"reissued_from_id,omitempty"`
+	Type           CardType    `json:"type"`
+	AmountCap      *float64    `json:"amount_cap,omitempty"`
+	// Полные реквизиты виртуальной карты возвращаются только при выпуске
+	Number string `json:"number,omitempty"`
+	CVV    string `json:"cvv,omitempty"`
+	// Действующие лимиты карты; возвращаются в списке карт
+	Limits *SpendingLimits `json:"limits,omitempty"`
+}
+
+// BlockCardRequest - блокировка карты. Без причины карта блокируется
+// временно; при утере или краже блокировка необратима.
+type BlockCardRequest struct {
+	Reason CardBlockReason `json:"reason" validate:"omitempty,oneof=temporary lost stolen"`
+}
+
+// CardStatusChange - запись журнала изменений статуса карты
+type CardStatusChange struct {
+	ID         int        `json:"id"`
+	CardID     int        `json:"card_id"`
+	UserID     int        `json:"user_id"`
+	FromStatus CardStatus `json:"from_status,omitempty"`
+	ToStatus   CardStatus `json:"to_status"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+type AuthorizationStatus string
+
+const (
+	AuthorizationApproved AuthorizationStatus = "approved"
+	AuthorizationDeclined AuthorizationStatus = "declined"
+	// Эквайрер подтвердил операцию, блокировка превращена в списание
+	AuthorizationSettled AuthorizationStatus = "settled"
+	// Эквайрер отменил операцию, блокировка снята
+	AuthorizationReversed AuthorizationStatus = "reversed"
+)
+
+// Коды ответа на авторизацию по ISO 8583 (поле 39)
+const (
+	ResponseApproved          = "00"
+	ResponseDoNotHonor        = "05"
+	ResponseInvalidTxn        = "12"
+	ResponseInvalidAmount     = "13"
+	ResponseInvalidCard       = "14"
+	ResponseNoOriginal        = "25"
+	ResponseLostCard          = "41"
+	ResponseStolenCard        = "43"
+	ResponseInsufficientFunds = "51"
+	ResponseExpiredCard       = "54"
+	ResponseIncorrectPIN      = "55"
+	ResponseNotPermitted      = "57"
+	ResponseAmountLimit       = "61"
+	ResponseRestrictedCard    = "62"
+	ResponseCountLimit        = "65"
+	ResponsePINTriesExceeded  = "75"
+	ResponseSystemError       = "96"
+	ResponseInvalidCVV        = "N7"
+)
+
+// Эквайрер операций, пришедших через HTTP API процессинга. Операции
+// ISO 8583 относятся к эквайреру из поля 32.
+const AcquirerAPI = "api"
+
+// CardAuthorization - запрос эквайрера на операцию по карте и решение банка.
+// Отклоненные запросы тоже сохраняются.
+type CardAuthorization struct {
+	ID           int                 `json:"id"`
+	CardID       *int                `json:"-"`
+	HoldID       *int                `json:"hold_id,omitempty"`
+	Amount       float64             `json:"amount"`
+	MerchantID   string              `json:"merchant_id"`
+	MerchantName string              `json:"merchant_name"`
+	MCC          string              `json:"mcc"`
+	Reference    string              `json:"reference,omitempty"`
+	Status       AuthorizationStatus `json:"status"`
+	ResponseCode string              `json:"response_code"`
+	ApprovalCode string              `json:"approval_code,omitempty"`
+	// Списание по операции после расчетов
+	TransactionID *int      `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// Ограничение карты, из-за которого операция отклонена
+	DeclineReason string      `json:"decline_reason,omitempty"`
+	Channel       CardChannel `json:"channel"`
+	// Эквайрер, приславший операцию; идентификатор операции уникален
+	// только в его пределах
+	Acquirer string `json:"acquirer"`
+}
+
+type AuthorizationRequest struct {
+	PAN string `json:"pan" validate:"required"`
+	// Срок действия карты в формате MM/YY или MM/YYYY
+	Expiry       string  `json:"expiry" validate:"required"`
+	CVV          string  `json:"cvv" validate:"required_without=PIN,omitempty,len=3,numeric"`
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	MerchantID   string  `json:"merchant_id" validate:"required"`
+	MerchantName string  `json:"merchant_name"`
+	MCC          string  `json:"mcc" validate:"required,len=4,numeric"`
+	// Идентификатор операции у эквайрера (RRN). Повторный запрос с тем же
+	// идентификатором возвращает прежнее решение.
+	Reference string `json:"reference" validate:"max=64"`
+	// По умолчанию - оплата на терминале ТСП
+	Channel     CardChannel `json:"channel" validate:"omitempty,oneof=pos ecommerce atm"`
+	Contactless bool        `json:"contactless"`
+	// Страна ТСП (ISO 3166, две буквы); пустая - Россия
+	MerchantCountry string `json:"merchant_country" validate:"omitempty,len=2"`
+	// PIN, введенный держателем. Если он передан, вместо CVV проверяется
+	// PIN; снятие наличных в банкомате без PIN не проводится.
+	PIN string `json:"pin" validate:"omitempty,min=4,max=6,numeric"`
+	// Эквайрер определяется каналом, по которому пришел запрос
+	Acquirer string `json:"-"`
+}
+
+type SettlementRequest struct {
+	// Сумма расчетов; если не указана, списывается вся авторизованная сумма
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+}
@@ -0,0 +1,53 @@
+package models
+
+// CardChannel - способ проведения операции по карте
+type CardChannel string
+
+const (
+	ChannelPOS       CardChannel = "pos"
+	ChannelEcommerce CardChannel = "ecommerce"
+	ChannelATM       CardChannel = "atm"
+)
+
+// Причины отказа по ограничениям, которые владелец задал для карты
+const (
+	DeclineMCCNotAllowed       = "mcc_not_allowed"
+	DeclineMCCBlocked          = "mcc_blocked"
+	DeclineEcommerceDisabled   = "ecommerce_disabled"
+	DeclineATMDisabled         = "atm_cash_disabled"
+	DeclineContactlessDisabled = "contactless_disabled"
+	DeclineForeignDisabled     = "foreign_disabled"
+	DeclineAboveTransactionMax = "above_transaction_max"
+)
+
+// CardControls - ограничения операций по карте, которые задает владелец.
+// По умолчанию разрешено все.
+type CardControls struct {
+	// Если список не пуст, разрешены только операции в этих группах MCC
+	AllowedMCCGroups []string `json:"allowed_mcc_groups"`
+	BlockedMCCGroups []string `json:"blocked_mcc_groups"`
+	Ecommerce        bool     `json:"ecommerce"`
+	ATMCash          bool     `json:"atm_cash"`
+	Contactless      bool     `json:"contactless"`
+	Foreign          bool     `json:"foreign"`
+	// Наибольшая сумма одной операции
+	MaxTransactionAmount *float64 `json:"max_transaction_amount,omitempty"`
+}
+
+// UpdateCardControlsRequest изменяет только переданные ограничения.
+// Нулевая максимальная сумма операции снимает ограничение.
+type UpdateCardControlsRequest struct {
+	AllowedMCCGroups     *[]string `json:"allowed_mcc_groups"`
+	BlockedMCCGroups     *[]string `json:"blocked_mcc_groups"`
+	Ecommerce            *bool     `json:"ecommerce"`
+	ATMCash              *bool     `json:"atm_cash"`
+	Contactless          *bool     `json:"contactless"`
+	Foreign              *bool     `json:"foreign"`
+	MaxTransactionAmount *float64  `json:"max_transaction_amount" validate:"omitempty,gte=0"`
+}
+
+// MCCGroup - группа кодов категорий ТСП, по которой задаются ограничения
+type MCCGroup struct {
+	Name   string   `json:"name"`
+	Ranges []string `json:"mcc"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// CardPIN - ключевой хеш PIN карты и число неверных попыток ввода подряд
+type CardPIN struct {
+	CardID         int       `json:"card_id"`
+	Hash           string    `json:"-"`
+	FailedAttempts int       `json:"failed_attempts"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// SetPINRequest - установка PIN для карты, у которой его еще нет
+type SetPINRequest struct {
+	PIN string `json:"pin" validate:"required,min=4,max=6,numeric"`
+}
+
+// ChangePINRequest - смена PIN. Владелец повторно подтверждает вход паролем.
+type ChangePINRequest struct {
+	Password string `json:"password" validate:"required"`
+	PIN      string `json:"pin" validate:"required,min=4,max=6,numeric"`
+}
+
+// PINVerificationRequest - проверка PIN эквайрером без проведения операции
+type PINVerificationRequest struct {
+	PAN    string `json:"pan" validate:"required"`
+	Expiry string `json:"expiry" validate:"required"`
+	PIN    string `json:"pin" validate:"required"`
+}
+
+type PINVerificationResponse struct {
+	ResponseCode string `json:"response_code"`
+}
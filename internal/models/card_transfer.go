@@ -0,0 +1,20 @@
+package models
+
+// CardTransferRequest - перевод с карты клиента на любую карту банка по ее
+// номеру
+type CardTransferRequest struct {
+	FromCardID   int     `json:"from_card_id" validate:"required"`
+	ToCardNumber string  `json:"to_card_number" validate:"required"`
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	Description  string  `json:"description"`
+}
+
+// CardTransfer - выполненный перевод. Карта и имя получателя маскируются.
+type CardTransfer struct {
+	FromCardID    int     `json:"from_card_id"`
+	ToCard        string  `json:"to_card"`
+	RecipientName string  `json:"recipient_name"`
+	Amount        float64 `json:"amount"`
+	HoldID        int     `json:"hold_id"`
+	TransactionID *int    `json:"transaction_id"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+type DepositStatus string
+
+const (
+	DepositStatusActive    DepositStatus = "active"
+	DepositStatusClosed    DepositStatus = "closed"
+	DepositStatusWithdrawn DepositStatus = "withdrawn_early"
+)
+
+// Способ выплаты процентов: прибавление к сумме вклада или перечисление
+// на счет, с которого вклад был открыт
+type DepositInterestMode string
+
+const (
+	DepositInterestCapitalize DepositInterestMode = "capitalize"
+	DepositInterestPayout     DepositInterestMode = "payout"
+)
+
+// Действие по окончании срока вклада
+type DepositMaturityAction string
+
+const (
+	DepositMaturityReturn  DepositMaturityAction = "return"
+	DepositMaturityProlong DepositMaturityAction = "prolong"
+)
+
+// Deposit - срочный вклад. InitialAmount и InterestPaid относятся к текущему
+// сроку: при пролонгации сумма вклада становится новой начальной суммой.
+type Deposit struct {
+	ID                  int                   `json:"id"`
+	AccountID           int                   `json:"account_id"`
+	InitialAmount       float64               `json:"initial_amount"`
+	Amount              float64               `json:"amount"`
+	InterestRate        float64               `json:"interest_rate"`
+	EarlyWithdrawalRate float64               `json:"early_withdrawal_rate"`
+	TermMonths          int                   `json:"term_months"`
+	InterestMode        DepositInterestMode   `json:"interest_mode"`
+	MaturityAction      DepositMaturityAction `json:"maturity_action"`
+	InterestPaid        float64               `json:"interest_paid"`
+	StartDate           time.Time             `json:"start_date"`
+	MaturityDate        time.Time             `json:"maturity_date"`
+	LastInterestDate    time.Time             `json:"last_interest_date"`
+	NextInterestDate    time.Time             `json:"next_interest_date"`
+	Status              DepositStatus         `json:"status"`
+	ClosedAt            *time.Time            `json:"closed_at"`
+	CreatedAt           time.Time             `json:"created_at"`
+}
+
+type CreateDepositRequest struct {
+	AccountID      int                   `json:"account_id" validate:"required"`
+	Amount         float64               `json:"amount" validate:"required,gt=0"`
+	TermMonths     int                   `json:"term_months" validate:"required,gte=1,lte=36"`
+	InterestMode   DepositInterestMode   `json:"interest_mode" validate:"omitempty,oneof=capitalize payout"`
+	MaturityAction DepositMaturityAction `json:"maturity_action" validate:"omitempty,oneof=return prolong"`
+}
+
+// DepositWithdrawalResponse - результат досрочного расторжения вклада
+type DepositWithdrawalResponse struct {
+	Deposit *Deposit `json:"deposit"`
+	// Сумма, перечисленная на счет с учетом пересчета процентов по
+	// сниженной ставке
+	PaidOut float64 `json:"paid_out"`
+}
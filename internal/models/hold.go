@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusReleased HoldStatus = "released"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+// Hold - блокировка средств на счете. Заблокированная сумма не списывается,
+// но уменьшает доступный остаток до списания, отмены или истечения срока.
+type Hold struct {
+	ID        int     `json:"id"`
+	AccountID int     `json:"account_id"`
+	Amount    float64 `json:"amount"`
+	// Карта, по операции которой установлена блокировка
+	CardID *int `json:"card_id,omitempty"`
+	// Счет получателя для отложенного перевода; без него списание
+	// проводится как платеж за пределы банка
+	ToAccountID    *int       `json:"to_account_id,omitempty"`
+	CapturedAmount float64    `json:"captured_amount"`
+	TransactionID  *int       `json:"transaction_id,omitempty"`
+	Status         HoldStatus `json:"status"`
+	Description    string     `json:"description"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type CreateHoldRequest struct {
+	AccountID   int     `json:"account_id" validate:"required"`
+	Amount      float64 `json:"amount" validate:"required,gt=0"`
+	Description string  `json:"description"`
+	// Получатель отложенного перевода (обязателен): ID или 20-значный номер счета
+	ToAccountID     int    `json:"to_account_id"`
+	ToAccountNumber string `json:"to_account_number" validate:"omitempty,len=20,numeric"`
+	// Срок действия блокировки; по умолчанию - 7 дней
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CardHoldRequest struct {
+	Amount      float64    `json:"amount" validate:"required,gt=0"`
+	Description string     `json:"description"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type CaptureHoldRequest struct {
+	// Сумма списания; если не указана, списывается вся заблокированная сумма
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+}
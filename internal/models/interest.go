@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// InterestAccrual - проценты, начисленные на остаток сберегательного счета
+// за один день. TransactionID заполняется при капитализации.
+type InterestAccrual struct {
+	ID            int       `json:"id"`
+	AccountID     int       `json:"account_id"`
+	AccrualDate   time.Time `json:"accrual_date"`
+	Balance       float64   `json:"balance"`
+	Rate          float64   `json:"rate"`
+	Amount        float64   `json:"amount"`
+	TransactionID *int      `json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// SpendingLimits - лимиты расходных операций за календарные сутки и месяц
+type SpendingLimits struct {
+	DailyAmount   float64 `json:"daily_amount"`
+	MonthlyAmount float64 `json:"monthly_amount"`
+	DailyCount    int     `json:"daily_count"`
+	MonthlyCount  int     `json:"monthly_count"`
+}
+
+// SpendingUsage - израсходованная часть лимитов в текущих периодах
+type SpendingUsage struct {
+	DailyAmount   float64 `json:"daily_amount"`
+	MonthlyAmount float64 `json:"monthly_amount"`
+	DailyCount    int     `json:"daily_count"`
+	MonthlyCount  int     `json:"monthly_count"`
+}
+
+type LimitsResponse struct {
+	Limits SpendingLimits `json:"limits"`
+	// Лимиты банка, выше которых клиент не может поднять свои
+	Max   SpendingLimits `json:"max"`
+	Usage SpendingUsage  `json:"usage"`
+	// Лимиты обнуляются в полночь и первого числа по часовому поясу клиента
+	Timezone       string    `json:"timezone"`
+	DailyResetAt   time.Time `json:"daily_reset_at"`
+	MonthlyResetAt time.Time `json:"monthly_reset_at"`
+}
+
+type UpdateLimitsRequest struct {
+	DailyAmount   *float64 `json:"daily_amount" validate:"omitempty,gte=0"`
+	MonthlyAmount *float64 `json:"monthly_amount" validate:"omitempty,gte=0"`
+	DailyCount    *int     `json:"daily_count" validate:"omitempty,gte=0"`
+	MonthlyCount  *int     `json:"monthly_count" validate:"omitempty,gte=0"`
+}
+
+type TimezoneSettings struct {
+	// Часовой пояс IANA, например Europe/Moscow
+	Timezone string `json:"timezone" validate:"required"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+type P2PTransferStatus string
+
+const (
+	P2PTransferPending    P2PTransferStatus = "pending"
+	P2PTransferProcessing P2PTransferStatus = "processing"
+	P2PTransferCompleted  P2PTransferStatus = "completed"
+	P2PTransferFailed     P2PTransferStatus = "failed"
+)
+
+// P2PTransfer - перевод клиенту банка по номеру телефона или email.
+// Перевод создается неподтвержденным: отправитель видит маскированное
+// имя получателя и подтверждает перевод отдельным запросом.
+type P2PTransfer struct {
+	ID              int               `json:"id"`
+	UserID          int               `json:"-"`
+	FromAccountID   int               `json:"from_account_id"`
+	RecipientUserID int               `json:"-"`
+	ToAccountID     int               `json:"-"`
+	Recipient       string            `json:"recipient"`
+	RecipientName   string            `json:"recipient_name"`
+	Amount          float64           `json:"amount"`
+	Description     string            `json:"description"`
+	Status          P2PTransferStatus `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	ExpiresAt       time.Time         `json:"expires_at"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+type CreateP2PTransferRequest struct {
+	FromAccountID int `json:"from_account_id" validate:"required"`
+	// Номер телефона или email получателя
+	Recipient   string  `json:"recipient" validate:"required"`
+	Amount      float64 `json:"amount" validate:"required,gt=0"`
+	Description string  `json:"description"`
+}
+
+// P2PSettings - настройки получения переводов по телефону и email.
+// Новый телефон начинает действовать после подтверждения кодом из SMS.
+type P2PSettings struct {
+	Phone            string `json:"phone"`
+	PhoneVerified    bool   `json:"phone_verified"`
+	PendingPhone     string `json:"pending_phone,omitempty"`
+	DefaultAccountID *int   `json:"default_account_id"`
+}
+
+// PhoneVerification - код подтверждения телефона, отправленный в SMS.
+// Хранится только хеш кода.
+type PhoneVerification struct {
+	ID          int
+	UserID      int
+	Phone       string
+	CodeHash    string
+	Attempts    int
+	ExpiresAt   time.Time
+	ConfirmedAt *time.Time
+	CreatedAt   time.Time
+}
+
+type ConfirmPhoneRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Статус загрузки, переводы которой еще исполняются; после исполнения
+// статус заменяется групповым статусом отчета
+const PaymentImportProcessing = "processing"
+
+// PaymentImport - загруженный клиентом файл платежных поручений (pain.001)
+// вместе с отчетом об исполнении (pain.002)
+type PaymentImport struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	MessageID string    `json:"message_id"`
+	Status    string    `json:"status"`
+	Report    []byte    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PaymentImportTransaction - статус строки загрузки. Сохраняется вместе
+// с переводом, поэтому после сбоя строка не исполняется повторно.
+type PaymentImportTransaction struct {
+	ImportID int    `json:"import_id"`
+	StatusID string `json:"status_id"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+	Info     string `json:"info,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type PaymentRequestStatus string
+
+const (
+	PaymentRequestPending    PaymentRequestStatus = "pending"
+	PaymentRequestProcessing PaymentRequestStatus = "processing"
+	PaymentRequestAccepted   PaymentRequestStatus = "accepted"
+	PaymentRequestDeclined   PaymentRequestStatus = "declined"
+	PaymentRequestExpired    PaymentRequestStatus = "expired"
+)
+
+// PaymentRequest - запрос денег у другого клиента банка. Плательщик
+// принимает запрос, и тогда выполняется перевод, или отклоняет его.
+type PaymentRequest struct {
+	ID          int `json:"id"`
+	RequesterID int `json:"-"`
+	// Счет, на который будут зачислены деньги
+	ToAccountID   int    `json:"to_account_id"`
+	RequesterName string `json:"requester_name"`
+	PayerUserID   int    `json:"-"`
+	// Email или номер счета плательщика, указанный при создании запроса
+	Payer string `json:"payer"`
+	// Счет списания; известен заранее, если запрос выставлен на счет
+	FromAccountID *int                 `json:"from_account_id,omitempty"`
+	Amount        float64              `json:"amount"`
+	Note          string               `json:"note"`
+	Status        PaymentRequestStatus `json:"status"`
+	ExpiresAt     time.Time            `json:"expires_at"`
+	RespondedAt   *time.Time           `json:"responded_at,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+type CreatePaymentRequestRequest struct {
+	ToAccountID int `json:"to_account_id" validate:"required"`
+	// Email или номер счета плательщика
+	Payer  string  `json:"payer" validate:"required"`
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+	Note   string  `json:"note" validate:"max=140"`
+}
+
+type AcceptPaymentRequestRequest struct {
+	// Обязателен, если запрос выставлен по email
+	FromAccountID int `json:"from_account_id"`
+}
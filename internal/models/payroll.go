@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// PayrollMode определяет, что делать со строками ведомости при ошибках
+type PayrollMode string
+
+const (
+	// Ведомость исполняется целиком в одной транзакции или не исполняется вовсе
+	PayrollAllOrNothing PayrollMode = "all_or_nothing"
+	// Исполняются все корректные строки, ошибочные пропускаются
+	PayrollBestEffort PayrollMode = "best_effort"
+)
+
+type PayrollStatus string
+
+const (
+	PayrollCompleted PayrollStatus = "completed"
+	PayrollPartial   PayrollStatus = "partially_completed"
+	PayrollRejected  PayrollStatus = "rejected"
+	// Ведомость принята, переводы еще исполняются
+	PayrollProcessing PayrollStatus = "processing"
+)
+
+type PayrollItemStatus string
+
+const (
+	PayrollItemCompleted PayrollItemStatus = "completed"
+	PayrollItemFailed    PayrollItemStatus = "failed"
+	// Строка корректна, но не исполнена, так как ведомость отклонена
+	PayrollItemSkipped PayrollItemStatus = "skipped"
+	// Строка еще не исполнялась
+	PayrollItemPending PayrollItemStatus = "pending"
+)
+
+// PayrollBatch - зарплатная ведомость: пакет переводов с одного счета
+type PayrollBatch struct {
+	ID            int `json:"id"`
+	UserID        int `json:"-"`
+	FromAccountID int `json:"from_account_id"`
+	// Идентификатор ведомости у клиента; повторная загрузка с тем же
+	// идентификатором не исполняет переводы заново
+	Reference      string         `json:"reference,omitempty"`
+	Mode           PayrollMode    `json:"mode"`
+	Status         PayrollStatus  `json:"status"`
+	TotalAmount    float64        `json:"total_amount"`
+	ItemCount      int            `json:"item_count"`
+	CompletedCount int            `json:"completed_count"`
+	FailedCount    int            `json:"failed_count"`
+	Error          string         `json:"error,omitempty"`
+	Items          []*PayrollItem `json:"items,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type PayrollItem struct {
+	ID      int `json:"-"`
+	BatchID int `json:"-"`
+	// Номер строки в файле, начиная с 1
+	Line int `json:"line"`
+	// Номер счета (20 цифр) или ID счета получателя
+	ToAccount     string            `json:"to_account"`
+	ToAccountID   *int              `json:"-"`
+	Amount        float64           `json:"amount"`
+	Description   string            `json:"description"`
+	Status        PayrollItemStatus `json:"status"`
+	Error         string            `json:"error,omitempty"`
+	TransactionID *int              `json:"transaction_id,omitempty"`
+}
+
+// PayrollRequest - ведомость в формате JSON. Для CSV те же параметры
+// передаются в строке запроса.
+type PayrollRequest struct {
+	FromAccountID int                  `json:"from_account_id" validate:"required"`
+	Mode          PayrollMode          `json:"mode" validate:"omitempty,oneof=all_or_nothing best_effort"`
+	Reference     string               `json:"reference" validate:"max=64"`
+	Items         []PayrollItemRequest `json:"items" validate:"required,min=1"`
+}
+
+type PayrollItemRequest struct {
+	ToAccount   string  `json:"to_account" validate:"required"`
+	Amount      float64 `json:"amount" validate:"required,gt=0"`
+	Description string  `json:"description"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+// SBPMerchant - торгово-сервисное предприятие, принимающее оплату через СБП
+type SBPMerchant struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"-"`
+	AccountID int    `json:"account_id"`
+	Name      string `json:"name"`
+	// Идентификатор ТСП в НСПК
+	MerchantID  string `json:"merchant_id"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Ключ подписи уведомлений; возвращается только при регистрации
+	CallbackSecret string    `json:"callback_secret,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type RegisterMerchantRequest struct {
+	AccountID   int    `json:"account_id" validate:"required"`
+	Name        string `json:"name" validate:"required,max=140"`
+	CallbackURL string `json:"callback_url" validate:"omitempty,url"`
+}
+
+type SBPQRStatus string
+
+const (
+	SBPQRActive SBPQRStatus = "active"
+	SBPQRPaid   SBPQRStatus = "paid"
+)
+
+type SBPQRCode struct {
+	ID         int    `json:"-"`
+	QRCID      string `json:"qrc_id"`
+	MerchantID int    `json:"merchant_id"`
+	// static - многоразовый код, dynamic - код для одной оплаты
+	Type string `json:"type"`
+	// Сумма; 0 для статического кода, где сумму вводит плательщик
+	Amount    float64     `json:"amount"`
+	Purpose   string      `json:"purpose"`
+	Payload   string      `json:"payload"`
+	Status    SBPQRStatus `json:"status"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type CreateQRRequest struct {
+	Type    string  `json:"type" validate:"required,oneof=static dynamic"`
+	Amount  float64 `json:"amount" validate:"omitempty,gt=0"`
+	Purpose string  `json:"purpose" validate:"max=140"`
+	// Срок действия динамического кода; по умолчанию - 72 часа
+	ExpiresInMinutes int `json:"expires_in_minutes" validate:"omitempty,gt=0"`
+}
+
+// SBPPaymentPreview - данные QR-кода, которые плательщик видит перед оплатой
+type SBPPaymentPreview struct {
+	QRCID        string  `json:"qrc_id"`
+	MerchantName string  `json:"merchant_name"`
+	Type         string  `json:"type"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	Purpose      string  `json:"purpose"`
+}
+
+type ResolveLinkRequest struct {
+	Link string `json:"link" validate:"required"`
+}
+
+type SBPPaymentStatus string
+
+// Статусы платежа в терминах ISO 20022, как в уведомлениях СБП
+const (
+	SBPPaymentAccepted SBPPaymentStatus = "ACWP"
+	SBPPaymentRejected SBPPaymentStatus = "RJCT"
+)
+
+type SBPPayment struct {
+	ID             int              `json:"id"`
+	QRCID          string           `json:"qrc_id"`
+	MerchantID     int              `json:"merchant_id"`
+	PayerUserID    int              `json:"-"`
+	PayerAccountID int              `json:"payer_account_id"`
+	Amount         float64          `json:"amount"`
+	Status         SBPPaymentStatus `json:"status"`
+	Error          string           `json:"error,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+type SBPPayRequest struct {
+	Link          string `json:"link" validate:"required"`
+	FromAccountID int    `json:"from_account_id" validate:"required"`
+	// Сумма для статического кода без суммы
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+}
+
+// SBPCallback - уведомление ТСП о статусе платежа
+type SBPCallback struct {
+	QRCID     string           `json:"qrc_id"`
+	PaymentID int              `json:"payment_id"`
+	Amount    float64          `json:"amount"`
+	Currency  string           `json:"currency"`
+	Purpose   string           `json:"purpose"`
+	Status    SBPPaymentStatus `json:"status"`
+	Timestamp time.Time        `json:"timestamp"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+type StandingOrderSchedule string
+
+const (
+	StandingOrderOnce    StandingOrderSchedule = "once"
+	StandingOrderWeekly  StandingOrderSchedule = "weekly"
+	StandingOrderMonthly StandingOrderSchedule = "monthly"
+)
+
+type StandingOrderStatus string
+
+const (
+	StandingOrderStatusActive    StandingOrderStatus = "active"
+	StandingOrderStatusCompleted StandingOrderStatus = "completed"
+	StandingOrderStatusCancelled StandingOrderStatus = "cancelled"
+)
+
+// StandingOrder - перевод на будущую дату или регулярный перевод между счетами
+type StandingOrder struct {
+	ID            int                   `json:"id"`
+	UserID        int                   `json:"user_id"`
+	FromAccountID int                   `json:"from_account_id"`
+	ToAccountID   int                   `json:"to_account_id"`
+	Amount        float64               `json:"amount"`
+	Description   string                `json:"description"`
+	Schedule      StandingOrderSchedule `json:"schedule"`
+	// День недели (0 - воскресенье) для еженедельных переводов
+	Weekday int `json:"weekday,omitempty"`
+	// День месяца для ежемесячных переводов; в коротких месяцах перевод
+	// выполняется в последний день
+	DayOfMonth int        `json:"day_of_month,omitempty"`
+	StartDate  time.Time  `json:"start_date"`
+	EndDate    *time.Time `json:"end_date,omitempty"`
+	// Дата очередного перевода и время следующей попытки его выполнить
+	ScheduledFor   time.Time           `json:"scheduled_for"`
+	NextRunAt      time.Time           `json:"next_run_at"`
+	FailedAttempts int                 `json:"failed_attempts"`
+	Status         StandingOrderStatus `json:"status"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+type StandingOrderRunStatus string
+
+const (
+	StandingOrderRunSuccess StandingOrderRunStatus = "success"
+	StandingOrderRunFailed  StandingOrderRunStatus = "failed"
+	// Попытка занята, перевод выполняется
+	StandingOrderRunPending StandingOrderRunStatus = "pending"
+)
+
+// StandingOrderRun - результат одной попытки выполнить перевод
+type StandingOrderRun struct {
+	ID           int                    `json:"id"`
+	OrderID      int                    `json:"order_id"`
+	ScheduledFor time.Time              `json:"scheduled_for"`
+	Attempt      int                    `json:"attempt"`
+	Status       StandingOrderRunStatus `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+	ExecutedAt   time.Time              `json:"executed_at"`
+}
+
+type CreateStandingOrderRequest struct {
+	FromAccountID int `json:"from_account_id" validate:"required"`
+	ToAccountID   int `json:"to_account_id" validate:"required_without=ToAccountNumber"`
+	// Номер счета получателя (20 цифр) - альтернатива ToAccountID
+	ToAccountNumber string                `json:"to_account_number" validate:"omitempty,len=20,numeric"`
+	Amount          float64               `json:"amount" validate:"required,gt=0"`
+	Description     string                `json:"description"`
+	Schedule        StandingOrderSchedule `json:"schedule" validate:"required,oneof=once weekly monthly"`
+	Weekday         int                   `json:"weekday" validate:"gte=0,lte=6"`
+	DayOfMonth      int                   `json:"day_of_month" validate:"gte=0,lte=31"`
+	// Дата разового перевода или начала регулярных; по умолчанию - сегодня
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+type UpdateStandingOrderRequest struct {
+	Amount      *float64   `json:"amount" validate:"omitempty,gt=0"`
+	Description *string    `json:"description"`
+	EndDate     *time.Time `json:"end_date"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type StatementFormat string
+
+const (
+	StatementFormatCSV StatementFormat = "csv"
+	StatementFormatPDF StatementFormat = "pdf"
+	StatementFormat1C  StatementFormat = "1c"
+	// ISO 20022 BankToCustomerStatement
+	StatementFormatCamt053 StatementFormat = "camt053"
+)
+
+type Statement struct {
+	AccountID      int            `json:"account_id"`
+	AccountNumber  string         `json:"account_number"`
+	OwnerName      string         `json:"owner_name"`
+	Currency       string         `json:"currency"`
+	From           time.Time      `json:"from"`
+	To             time.Time      `json:"to"`
+	OpeningBalance float64        `json:"opening_balance"`
+	TotalCredit    float64        `json:"total_credit"`
+	TotalDebit     float64        `json:"total_debit"`
+	ClosingBalance float64        `json:"closing_balance"`
+	Transactions   []*Transaction `json:"transactions"`
+	GeneratedAt    time.Time      `json:"generated_at"`
+}
+
+type EmailStatementRequest struct {
+	From   string          `json:"from" validate:"required"`
+	To     string          `json:"to" validate:"required"`
+	Format StatementFormat `json:"format" validate:"required,oneof=csv pdf 1c camt053"`
+}
@@ -8,6 +8,14 @@ const (
 	TransactionDeposit    TransactionType = "deposit"
 	TransactionWithdrawal TransactionType = "withdrawal"
 	TransactionTransfer   TransactionType = "transfer"
+	TransactionInterest   TransactionType = "interest"
+	// Размещение средств во вклад и их возврат
+	TransactionTermDeposit TransactionType = "term_deposit"
+	// Списание по блокировке без счета получателя в банке (оплата картой)
+	TransactionPayment TransactionType = "payment"
+	// Компенсирующие проводки: сторно ошибочной операции и возврат платежа
+	TransactionReversal TransactionType = "reversal"
+	TransactionRefund   TransactionType = "refund"
 )
 
 type Transaction struct {
@@ -17,11 +25,27 @@ type Transaction struct {
 	Type        TransactionType `json:"type"`
 	Description string          `json:"description"`
 	CreatedAt   time.Time       `json:"created_at"`
+	// Вторая проводка перевода (списание или зачисление)
+	CounterpartID *int `json:"counterpart_transaction_id,omitempty"`
+	// Проводка, которую компенсирует сторно или возврат
+	OriginalTransactionID *int `json:"original_transaction_id,omitempty"`
 }
 
 type TransferRequest struct {
-	FromAccountID int     `json:"from_account_id" validate:"required"`
-	ToAccountID   int     `json:"to_account_id" validate:"required"`
-	Amount        float64 `json:"amount" validate:"required,gt=0"`
-	Description   string  `json:"description"`
+	FromAccountID int `json:"from_account_id" validate:"required"`
+	ToAccountID   int `json:"to_account_id" validate:"required_without=ToAccountNumber"`
+	// Номер счета получателя (20 цифр) - альтернатива ToAccountID
+	ToAccountNumber string  `json:"to_account_number" validate:"omitempty,len=20,numeric"`
+	Amount          float64 `json:"amount" validate:"required,gt=0"`
+	Description     string  `json:"description"`
+}
+
+type ReverseTransactionRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type RefundRequest struct {
+	// Сумма возврата; по умолчанию - весь невозвращенный остаток платежа
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+	Reason string  `json:"reason"`
 }
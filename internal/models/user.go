@@ -2,6 +2,14 @@ package models
 
 import "time"
 
+type UserRole string
+
+const (
+	UserRoleClient UserRole = "client"
+	// Сотрудник банка: может сторнировать операции клиентов
+	UserRoleOperator UserRole = "operator"
+)
+
 type User struct {
 	ID           int       `json:"id"`
 	Email        string    `json:"email" validate:"required,email"`
@@ -9,6 +17,16 @@ type User struct {
 	PasswordHash string    `json:"-"` // Исключаем из JSON
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	// Телефон в формате +7XXXXXXXXXX для переводов по номеру телефона
+	Phone string `json:"phone,omitempty"`
+	// Телефон подтвержден кодом из SMS; переводы по телефону находят
+	// только подтвержденные номера
+	PhoneVerified bool `json:"phone_verified"`
+	// Счет для зачисления переводов по телефону или email
+	DefaultAccountID *int     `json:"default_account_id,omitempty"`
+	Role             UserRole `json:"role"`
+	// Часовой пояс для границ суточных и месячных лимитов
+	Timezone string `json:"timezone,omitempty"`
 }
 
 type RegisterRequest struct {
@@ -14,40 +14,80 @@ func NewAccountRepository(db *sql.DB) *AccountRepository {
 	return &AccountRepository{db: db}
 }
 
+// Счета, открытые до появления номеров, хранят NULL в колонке number.
+// Доступный остаток считается по действующим блокировкам; истекшие
+// блокировки перестают учитываться сразу, не дожидаясь шедулера.
+const accountColumns = `id, user_id, COALESCE(number, ''), balance,
+	balance - COALESCE((
+		SELECT SUM(amount) FROM holds
+		WHERE holds.account_id = accounts.id AND holds.status = 'active' AND holds.expires_at > NOW()
+	), 0),
+	currency, type, status, COALESCE(interest_rate, 0), created_at, updated_at`
+
+func scanAccount(row interface{ Scan(...interface{}) error }) (*models.Account, error) {
+	account := &models.Account{}
+	err := row.Scan(
+		&account.ID,
+		&account.UserID,
+		&account.Number,
+		&account.Balance,
+		&account.AvailableBalance,
+		&account.Currency,
+		&account.Type,
+		&account.Status,
+		&account.InterestRate,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+	return account, err
+}
+
 func (r *AccountRepository) CreateAccount(account *models.Account) error {
 	query := `
-		INSERT INTO accounts (user_id, balance, currency)
-		VALUES ($1, $2, $3)
+		INSERT INTO accounts (user_id, number, balance, currency, type, status, interest_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at
 	`
 
 	err := r.db.QueryRow(
 		query,
 		account.UserID,
+		account.Number,
 		account.Balance,
 		account.Currency,
+		account.Type,
+		account.Status,
+		account.InterestRate,
 	).Scan(&account.ID, &account.CreatedAt, &account.UpdatedAt)
 
+	// У нового счета блокировок нет
+	account.AvailableBalance = account.Balance
 	return err
 }
 
 func (r *AccountRepository) GetAccountByID(id int) (*models.Account, error) {
 	query := `
-		SELECT id, user_id, balance, currency, created_at, updated_at
+		SELECT ` + accountColumns + `
 		FROM accounts
 		WHERE id = $1
 	`
 
-	account := &models.Account{}
-	err := r.db.QueryRow(query, id).Scan(
-		&account.ID,
-		&account.UserID,
-		&account.Balance,
-		&account.Currency,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-	)
+	account, err := scanAccount(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return account, err
+}
 
+func (r *AccountRepository) GetAccountByNumber(number string) (*models.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE number = $1
+	`
+
+	account, err := scanAccount(r.db.QueryRow(query, number))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -55,7 +95,133 @@ func (r *AccountRepository) GetAccountByID(id int) (*models.Account, error) {
 	return account, err
 }
 
-func (r *AccountRepository) UpdateBalance(id int, amount float64) error {
+func (r *AccountRepository) IsNumberExists(number string) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM accounts WHERE number = $1)
+	`
+
+	var exists bool
+	err := r.db.QueryRow(query, number).Scan(&exists)
+	return exists, err
+}
+
+// GetAccountByIDForUpdate читает счет внутри транзакции и блокирует строку
+// до ее завершения, чтобы параллельные списания не прошли проверку остатка.
+// Сначала берется блокировка, и только потом отдельным запросом читаются
+// остаток и сумма блокировок: в READ COMMITTED подзапрос внутри
+// SELECT ... FOR UPDATE видит снимок до ожидания блокировки и не учел бы
+// блокировки, созданные параллельной транзакцией.
+func (r *AccountRepository) GetAccountByIDForUpdate(tx *sql.Tx, id int) (*models.Account, error) {
+	var locked int
+	err := tx.QueryRow(`SELECT id FROM accounts WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE id = $1
+	`
+
+	return scanAccount(tx.QueryRow(query, id))
+}
+
+func (r *AccountRepository) GetAccountsByUser(userID int) ([]*models.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE user_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*models.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (r *AccountRepository) GetAccountsByType(accountType models.AccountType) ([]*models.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE type = $1 AND status <> $2
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, accountType, models.AccountStatusClosed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*models.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// GetAccountsWithoutNumber возвращает счета, открытые до появления номеров
+func (r *AccountRepository) GetAccountsWithoutNumber() ([]*models.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE number IS NULL OR number = ''
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*models.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// SetNumber присваивает номер счету, у которого его еще нет
+func (r *AccountRepository) SetNumber(id int, number string) error {
+	query := `
+		UPDATE accounts
+		SET number = $1
+		WHERE id = $2 AND (number IS NULL OR number = '')
+	`
+
+	_, err := r.db.Exec(query, number, id)
+	return err
+}
+
+func (r *AccountRepository) UpdateBalance(tx *sql.Tx, id int, amount float64) error {
 	query := `
 		UPDATE accounts
 		SET balance = balance + $1,
@@ -63,6 +229,30 @@ func (r *AccountRepository) UpdateBalance(id int, amount float64) error {
 		WHERE id = $2
 	`
 
-	_, err := r.db.Exec(query, amount, id)
+	_, err := conn(r.db, tx).Exec(query, amount, id)
+	return err
+}
+
+func (r *AccountRepository) UpdateStatus(tx *sql.Tx, id int, status models.AccountStatus) error {
+	query := `
+		UPDATE accounts
+		SET status = $1,
+			updated_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err := conn(r.db, tx).Exec(query, status, id)
+	return err
+}
+
+func (r *AccountRepository) UpdateInterestRate(id int, rate float64) error {
+	query := `
+		UPDATE accounts
+		SET interest_rate = $1,
+			updated_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err := r.db.Exec(query, rate, id)
 	return err
 }
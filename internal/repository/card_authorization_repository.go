@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+)
+
+const cardAuthorizationColumns = `
+	id, card_id, hold_id, amount, merchant_id, COALESCE(merchant_name, ''), mcc,
+	COALESCE(reference, ''), status, response_code, COALESCE(approval_code, ''),
+	transaction_id, created_at, updated_at, COALESCE(decline_reason, ''),
+	COALESCE(channel, 'pos'), COALESCE(acquirer, 'api')
+`
+
+type CardAuthorizationRepository struct {
+	db *sql.DB
+}
+
+func NewCardAuthorizationRepository(db *sql.DB) *CardAuthorizationRepository {
+	return &CardAuthorizationRepository{db: db}
+}
+
+func scanCardAuthorization(row interface{ Scan(...interface{}) error }) (*models.CardAuthorization, error) {
+	auth := &models.CardAuthorization{}
+	err := row.Scan(
+		&auth.ID,
+		&auth.CardID,
+		&auth.HoldID,
+		&auth.Amount,
+		&auth.MerchantID,
+		&auth.MerchantName,
+		&auth.MCC,
+		&auth.Reference,
+		&auth.Status,
+		&auth.ResponseCode,
+		&auth.ApprovalCode,
+		&auth.TransactionID,
+		&auth.CreatedAt,
+		&auth.UpdatedAt,
+		&auth.DeclineReason,
+		&auth.Channel,
+		&auth.Acquirer,
+	)
+	return auth, err
+}
+
+func (r *CardAuthorizationRepository) CreateAuthorization(auth *models.CardAuthorization) error {
+	query := `
+		INSERT INTO card_authorizations (
+			card_id, hold_id, amount, merchant_id, merchant_name, mcc, reference,
+			status, response_code, approval_code, decline_reason, channel, acquirer
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12, $13)
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		auth.CardID,
+		auth.HoldID,
+		auth.Amount,
+		auth.MerchantID,
+		auth.MerchantName,
+		auth.MCC,
+		auth.Reference,
+		auth.Status,
+		auth.ResponseCode,
+		auth.ApprovalCode,
+		auth.DeclineReason,
+		auth.Channel,
+		auth.Acquirer,
+	).Scan(&auth.ID, &auth.CreatedAt, &auth.UpdatedAt)
+}
+
+func (r *CardAuthorizationRepository) GetAuthorizationByID(id int) (*models.CardAuthorization, error) {
+	query := `SELECT ` + cardAuthorizationColumns + ` FROM card_authorizations WHERE id = $1`
+
+	auth, err := scanCardAuthorization(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return auth, err
+}
+
+// GetAuthorizationByReference ищет авторизацию по идентификатору операции
+// у эквайрера для повторных запросов
+func (r *CardAuthorizationRepository) GetAuthorizationByReference(acquirer, reference string) (*models.CardAuthorization, error) {
+	query := `
+		SELECT ` + cardAuthorizationColumns + ` FROM card_authorizations
+		WHERE COALESCE(acquirer, 'api') = $1 AND reference = $2
+	`
+
+	auth, err := scanCardAuthorization(r.db.QueryRow(query, acquirer, reference))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return auth, err
+}
+
+// CompleteAuthorization фиксирует расчеты или отмену авторизации, если она
+// все еще в статусе from. Возвращает false, если статус уже изменился.
+func (r *CardAuthorizationRepository) CompleteAuthorization(auth *models.CardAuthorization, from models.AuthorizationStatus) (bool, error) {
+	query := `
+		UPDATE card_authorizations
+		SET status = $1, transaction_id = $2, updated_at = NOW()
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := r.db.Exec(query, auth.Status, auth.TransactionID, auth.ID, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
@@ -4,6 +4,9 @@ import (
 	"bank-api/internal/models"
 	"database/sql"
 	"errors"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type CardRepository struct {
@@ -14,40 +17,93 @@ func NewCardRepository(db *sql.DB) *CardRepository {
 	return &CardRepository{db: db}
 }
 
-func (r *CardRepository) CreateCard(card *models.Card) error {
+// Карты, выпущенные до введения продуктов, - Visa-подобные с префиксом 4
+const cardColumns = `id, account_id, card_number, expiry_date, created_at,
+	COALESCE(product, 'visa'), COALESCE(number_hash, ''), COALESCE(status, 'active'),
+	COALESCE(block_reason, ''), reissued_from_id, COALESCE(cvv_hash, ''),
+	COALESCE(type, 'physical'), amount_cap, expires_at`
+
+func scanCard(row interface{ Scan(...interface{}) error }) (*models.Card, error) {
+	card := &models.Card{}
+	err := row.Scan(
+		&card.ID,
+		&card.AccountID,
+		&card.Number,
+		&card.ExpiryDate,
+		&card.CreatedAt,
+		&card.Product,
+		&card.NumberHash,
+		&card.Status,
+		&card.BlockReason,
+		&card.ReissuedFromID,
+		&card.CVV,
+		&card.Type,
+		&card.AmountCap,
+		&card.ExpiresAt,
+	)
+	return card, err
+}
+
+// CreateCard сохраняет карту. Возвращает false, если карта с тем же хешем
+// номера уже есть: номер занят параллельным выпуском.
+func (r *CardRepository) CreateCard(tx *sql.Tx, card *models.Card) (bool, error) {
 	query := `
-		INSERT INTO cards (account_id, card_number, expiry_date, cvv_hash)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO cards (
+			account_id, card_number, expiry_date, cvv_hash, product, number_hash,
+			status, reissued_from_id, type, amount_cap, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (number_hash) DO NOTHING
 		RETURNING id, created_at
 	`
 
-	err := r.db.QueryRow(
+	err := conn(r.db, tx).QueryRow(
 		query,
 		card.AccountID,
 		card.Number,
 		card.ExpiryDate,
 		card.CVV,
+		card.Product,
+		card.NumberHash,
+		card.Status,
+		card.ReissuedFromID,
+		card.Type,
+		card.AmountCap,
+		card.ExpiresAt,
 	).Scan(&card.ID, &card.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
 
-	return err
+	return err == nil, err
 }
 
 func (r *CardRepository) GetCardByID(id int) (*models.Card, error) {
 	query := `
-		SELECT id, account_id, card_number, expiry_date, created_at
+		SELECT ` + cardColumns + `
 		FROM cards
 		WHERE id = $1
 	`
 
-	card := &models.Card{}
-	err := r.db.QueryRow(query, id).Scan(
-		&card.ID,
-		&card.AccountID,
-		&card.Number,
-		&card.ExpiryDate,
-		&card.CreatedAt,
-	)
+	card, err := scanCard(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return card, err
+}
+
+// GetCardByIDForUpdate читает карту внутри транзакции и блокирует строку,
+// чтобы смена статуса и операции по карте выполнялись по очереди
+func (r *CardRepository) GetCardByIDForUpdate(tx *sql.Tx, id int) (*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE id = $1
+		FOR UPDATE
+	`
 
+	card, err := scanCard(tx.QueryRow(query, id))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -57,9 +113,10 @@ func (r *CardRepository) GetCardByID(id int) (*models.Card, error) {
 
 func (r *CardRepository) GetCardsByAccount(accountID int) ([]*models.Card, error) {
 	query := `
-		SELECT id, account_id, card_number, expiry_date, created_at
+		SELECT ` + cardColumns + `
 		FROM cards
 		WHERE account_id = $1
+		ORDER BY id
 	`
 
 	rows, err := r.db.Query(query, accountID)
@@ -70,14 +127,8 @@ func (r *CardRepository) GetCardsByAccount(accountID int) ([]*models.Card, error
 
 	var cards []*models.Card
 	for rows.Next() {
-		card := &models.Card{}
-		if err := rows.Scan(
-			&card.ID,
-			&card.AccountID,
-			&card.Number,
-			&card.ExpiryDate,
-			&card.CreatedAt,
-		); err != nil {
+		card, err := scanCard(rows)
+		if err != nil {
 			return nil, err
 		}
 		cards = append(cards, card)
@@ -85,3 +136,361 @@ func (r *CardRepository) GetCardsByAccount(accountID int) ([]*models.Card, error
 
 	return cards, nil
 }
+
+// GetCardsByUser возвращает карты всех счетов клиента
+func (r *CardRepository) GetCardsByUser(userID int) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE account_id IN (SELECT id FROM accounts WHERE user_id = $1)
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []*models.Card
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, nil
+}
+
+// GetCardByNumberHash ищет карту по ключевому хешу номера
+func (r *CardRepository) GetCardByNumberHash(hash string) (*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE number_hash = $1
+	`
+
+	card, err := scanCard(r.db.QueryRow(query, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return card, err
+}
+
+// IsNumberHashExists проверяет, выпущена ли уже карта с таким номером
+func (r *CardRepository) IsNumberHashExists(hash string) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM cards WHERE number_hash = $1)
+	`
+
+	var exists bool
+	err := r.db.QueryRow(query, hash).Scan(&exists)
+	return exists, err
+}
+
+// GetCardsWithoutNumberHash возвращает карты, выпущенные до появления
+// хеша номера
+func (r *CardRepository) GetCardsWithoutNumberHash() ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE number_hash IS NULL
+	`
+
+	return r.queryCards(query)
+}
+
+func (r *CardRepository) SetNumberHash(id int, hash string) error {
+	query := `
+		UPDATE cards
+		SET number_hash = $1
+		WHERE id = $2
+	`
+
+	_, err := r.db.Exec(query, hash, id)
+	return err
+}
+
+// GetCardsWithoutExpiresAt возвращает карты, у которых срок действия
+// хранится только в зашифрованном виде
+func (r *CardRepository) GetCardsWithoutExpiresAt() ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE expires_at IS NULL
+	`
+
+	return r.queryCards(query)
+}
+
+func (r *CardRepository) SetExpiresAt(id int, expiresAt time.Time) error {
+	query := `
+		UPDATE cards
+		SET expires_at = $1
+		WHERE id = $2
+	`
+
+	_, err := r.db.Exec(query, expiresAt, id)
+	return err
+}
+
+// GetCardsForRenewal возвращает пластиковые карты, срок которых истекает
+// до указанного момента и взамен которых еще не выпущены новые
+func (r *CardRepository) GetCardsForRenewal(before time.Time) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE COALESCE(c.status, 'active') IN ('active', 'blocked')
+			AND COALESCE(c.type, 'physical') = 'physical'
+			AND c.expires_at <= $1
+			AND NOT EXISTS (SELECT 1 FROM cards r WHERE r.reissued_from_id = c.id)
+		ORDER BY c.expires_at, c.id
+	`
+
+	return r.queryCards(query, before)
+}
+
+// GetExpiredCards возвращает карты с истекшим сроком, которые еще не
+// переведены в статус expired
+func (r *CardRepository) GetExpiredCards(now time.Time) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE COALESCE(status, 'active') IN ('active', 'blocked')
+			AND expires_at <= $1
+		ORDER BY id
+	`
+
+	return r.queryCards(query, now)
+}
+
+func (r *CardRepository) queryCards(query string, args ...interface{}) ([]*models.Card, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []*models.Card
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rows.Err()
+}
+
+func (r *CardRepository) UpdateStatus(tx *sql.Tx, id int, status models.CardStatus, reason models.CardBlockReason) error {
+	query := `
+		UPDATE cards
+		SET status = $1,
+			block_reason = NULLIF($2, '')
+		WHERE id = $3
+	`
+
+	_, err := conn(r.db, tx).Exec(query, status, reason, id)
+	return err
+}
+
+func (r *CardRepository) SetAmountCap(id int, amountCap *float64) error {
+	query := `
+		UPDATE cards
+		SET amount_cap = $1
+		WHERE id = $2
+	`
+
+	_, err := r.db.Exec(query, amountCap, id)
+	return err
+}
+
+// GetControls возвращает ограничения, заданные владельцем карты, или nil,
+// если владелец их не задавал
+func (r *CardRepository) GetControls(cardID int) (*models.CardControls, error) {
+	query := `
+		SELECT allowed_mcc_groups, blocked_mcc_groups, ecommerce, atm_cash,
+			contactless, foreign_transactions, max_transaction_amount
+		FROM card_controls
+		WHERE card_id = $1
+	`
+
+	controls := &models.CardControls{}
+	err := r.db.QueryRow(query, cardID).Scan(
+		pq.Array(&controls.AllowedMCCGroups),
+		pq.Array(&controls.BlockedMCCGroups),
+		&controls.Ecommerce,
+		&controls.ATMCash,
+		&controls.Contactless,
+		&controls.Foreign,
+		&controls.MaxTransactionAmount,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return controls, err
+}
+
+func (r *CardRepository) SetControls(cardID int, controls *models.CardControls) error {
+	query := `
+		INSERT INTO card_controls (
+			card_id, allowed_mcc_groups, blocked_mcc_groups, ecommerce, atm_cash,
+			contactless, foreign_transactions, max_transaction_amount
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (card_id) DO UPDATE
+		SET allowed_mcc_groups = EXCLUDED.allowed_mcc_groups,
+			blocked_mcc_groups = EXCLUDED.blocked_mcc_groups,
+			ecommerce = EXCLUDED.ecommerce,
+			atm_cash = EXCLUDED.atm_cash,
+			contactless = EXCLUDED.contactless,
+			foreign_transactions = EXCLUDED.foreign_transactions,
+			max_transaction_amount = EXCLUDED.max_transaction_amount,
+			updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(
+		query,
+		cardID,
+		pq.Array(controls.AllowedMCCGroups),
+		pq.Array(controls.BlockedMCCGroups),
+		controls.Ecommerce,
+		controls.ATMCash,
+		controls.Contactless,
+		controls.Foreign,
+		controls.MaxTransactionAmount,
+	)
+	return err
+}
+
+// CopyControls переносит ограничения на перевыпущенную карту
+func (r *CardRepository) CopyControls(tx *sql.Tx, fromCardID, toCardID int) error {
+	query := `
+		INSERT INTO card_controls (
+			card_id, allowed_mcc_groups, blocked_mcc_groups, ecommerce, atm_cash,
+			contactless, foreign_transactions, max_transaction_amount
+		)
+		SELECT $2, allowed_mcc_groups, blocked_mcc_groups, ecommerce, atm_cash,
+			contactless, foreign_transactions, max_transaction_amount
+		FROM card_controls
+		WHERE card_id = $1
+	`
+
+	_, err := conn(r.db, tx).Exec(query, fromCardID, toCardID)
+	return err
+}
+
+// GetPIN возвращает хеш PIN карты или nil, если PIN не установлен
+func (r *CardRepository) GetPIN(tx *sql.Tx, cardID int) (*models.CardPIN, error) {
+	query := `
+		SELECT card_id, pin_hash, failed_attempts, updated_at
+		FROM card_pins
+		WHERE card_id = $1
+	`
+
+	pin := &models.CardPIN{}
+	err := conn(r.db, tx).QueryRow(query, cardID).Scan(
+		&pin.CardID,
+		&pin.Hash,
+		&pin.FailedAttempts,
+		&pin.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return pin, err
+}
+
+// SetPIN сохраняет хеш нового PIN и сбрасывает счетчик неверных попыток
+func (r *CardRepository) SetPIN(tx *sql.Tx, cardID int, hash string) error {
+	query := `
+		INSERT INTO card_pins (card_id, pin_hash, failed_attempts)
+		VALUES ($1, $2, 0)
+		ON CONFLICT (card_id) DO UPDATE
+		SET pin_hash = EXCLUDED.pin_hash,
+			failed_attempts = 0,
+			updated_at = NOW()
+	`
+
+	_, err := conn(r.db, tx).Exec(query, cardID, hash)
+	return err
+}
+
+func (r *CardRepository) SetPINAttempts(tx *sql.Tx, cardID, attempts int) error {
+	query := `
+		UPDATE card_pins
+		SET failed_attempts = $1
+		WHERE card_id = $2
+	`
+
+	_, err := conn(r.db, tx).Exec(query, attempts, cardID)
+	return err
+}
+
+// IsReissued проверяет, выпущена ли уже карта взамен указанной
+func (r *CardRepository) IsReissued(tx *sql.Tx, id int) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM cards WHERE reissued_from_id = $1)
+	`
+
+	var exists bool
+	err := conn(r.db, tx).QueryRow(query, id).Scan(&exists)
+	return exists, err
+}
+
+func (r *CardRepository) AddStatusChange(tx *sql.Tx, change *models.CardStatusChange) error {
+	query := `
+		INSERT INTO card_status_history (card_id, user_id, from_status, to_status, reason)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		RETURNING id, created_at
+	`
+
+	return conn(r.db, tx).QueryRow(
+		query,
+		change.CardID,
+		change.UserID,
+		change.FromStatus,
+		change.ToStatus,
+		change.Reason,
+	).Scan(&change.ID, &change.CreatedAt)
+}
+
+func (r *CardRepository) GetStatusHistory(cardID int) ([]*models.CardStatusChange, error) {
+	query := `
+		SELECT id, card_id, user_id, COALESCE(from_status, ''), to_status, reason, created_at
+		FROM card_status_history
+		WHERE card_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(query, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.CardStatusChange
+	for rows.Next() {
+		change := &models.CardStatusChange{}
+		if err := rows.Scan(
+			&change.ID,
+			&change.CardID,
+			&change.UserID,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.Reason,
+			&change.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
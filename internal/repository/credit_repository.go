@@ -110,3 +110,20 @@ func (r *CreditRepository) GetPaymentSchedule(creditID int) ([]*models.PaymentSc
 
 	return payments, nil
 }
+
+func (r *CreditRepository) CountActiveCreditsByAccount(tx *sql.Tx, accountID int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM credits
+		WHERE account_id = $1 AND status IN ($2, $3)
+	`
+
+	var count int
+	err := conn(r.db, tx).QueryRow(
+		query,
+		accountID,
+		models.CreditStatusActive,
+		models.CreditStatusOverdue,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,18 @@
+package repository
+
+import "database/sql"
+
+// queryer - общий интерфейс *sql.DB и *sql.Tx, чтобы методы репозиториев
+// могли работать как внутри транзакции, так и без нее
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func conn(db *sql.DB, tx *sql.Tx) queryer {
+	if tx != nil {
+		return tx
+	}
+	return db
+}
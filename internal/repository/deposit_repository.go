@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type DepositRepository struct {
+	db *sql.DB
+}
+
+func NewDepositRepository(db *sql.DB) *DepositRepository {
+	return &DepositRepository{db: db}
+}
+
+const depositColumns = `id, account_id, initial_amount, amount, interest_rate, early_withdrawal_rate,
+	term_months, interest_mode, maturity_action, interest_paid, start_date, maturity_date,
+	last_interest_date, next_interest_date, status, closed_at, created_at`
+
+func scanDeposit(row interface{ Scan(...interface{}) error }) (*models.Deposit, error) {
+	deposit := &models.Deposit{}
+	err := row.Scan(
+		&deposit.ID,
+		&deposit.AccountID,
+		&deposit.InitialAmount,
+		&deposit.Amount,
+		&deposit.InterestRate,
+		&deposit.EarlyWithdrawalRate,
+		&deposit.TermMonths,
+		&deposit.InterestMode,
+		&deposit.MaturityAction,
+		&deposit.InterestPaid,
+		&deposit.StartDate,
+		&deposit.MaturityDate,
+		&deposit.LastInterestDate,
+		&deposit.NextInterestDate,
+		&deposit.Status,
+		&deposit.ClosedAt,
+		&deposit.CreatedAt,
+	)
+	return deposit, err
+}
+
+func (r *DepositRepository) CreateDeposit(tx *sql.Tx, deposit *models.Deposit) error {
+	query := `
+		INSERT INTO deposits (
+			account_id, initial_amount, amount, interest_rate, early_withdrawal_rate,
+			term_months, interest_mode, maturity_action, interest_paid, start_date,
+			maturity_date, last_interest_date, next_interest_date, status
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at
+	`
+
+	return conn(r.db, tx).QueryRow(
+		query,
+		deposit.AccountID,
+		deposit.InitialAmount,
+		deposit.Amount,
+		deposit.InterestRate,
+		deposit.EarlyWithdrawalRate,
+		deposit.TermMonths,
+		deposit.InterestMode,
+		deposit.MaturityAction,
+		deposit.InterestPaid,
+		deposit.StartDate,
+		deposit.MaturityDate,
+		deposit.LastInterestDate,
+		deposit.NextInterestDate,
+		deposit.Status,
+	).Scan(&deposit.ID, &deposit.CreatedAt)
+}
+
+func (r *DepositRepository) GetDepositByID(id int) (*models.Deposit, error) {
+	query := `
+		SELECT ` + depositColumns + `
+		FROM deposits
+		WHERE id = $1
+	`
+
+	deposit, err := scanDeposit(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return deposit, err
+}
+
+func (r *DepositRepository) GetDepositByIDForUpdate(tx *sql.Tx, id int) (*models.Deposit, error) {
+	query := `
+		SELECT ` + depositColumns + `
+		FROM deposits
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	deposit, err := scanDeposit(tx.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return deposit, err
+}
+
+func (r *DepositRepository) GetDepositsByUser(userID int) ([]*models.Deposit, error) {
+	query := `
+		SELECT ` + depositColumns + `
+		FROM deposits
+		WHERE account_id IN (SELECT id FROM accounts WHERE user_id = $1)
+		ORDER BY created_at DESC
+	`
+
+	return r.queryDeposits(query, userID)
+}
+
+// GetDueDeposits возвращает действующие вклады, по которым наступила дата
+// выплаты процентов
+func (r *DepositRepository) GetDueDeposits(now time.Time) ([]*models.Deposit, error) {
+	query := `
+		SELECT ` + depositColumns + `
+		FROM deposits
+		WHERE status = $1 AND next_interest_date <= $2
+		ORDER BY id
+	`
+
+	return r.queryDeposits(query, models.DepositStatusActive, now)
+}
+
+func (r *DepositRepository) CountActiveDepositsByAccount(tx *sql.Tx, accountID int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM deposits
+		WHERE account_id = $1 AND status = $2
+	`
+
+	var count int
+	err := conn(r.db, tx).QueryRow(query, accountID, models.DepositStatusActive).Scan(&count)
+	return count, err
+}
+
+func (r *DepositRepository) UpdateDeposit(tx *sql.Tx, deposit *models.Deposit) error {
+	query := `
+		UPDATE deposits
+		SET initial_amount = $1,
+			amount = $2,
+			interest_rate = $3,
+			early_withdrawal_rate = $4,
+			interest_paid = $5,
+			start_date = $6,
+			maturity_date = $7,
+			last_interest_date = $8,
+			next_interest_date = $9,
+			status = $10,
+			closed_at = $11
+		WHERE id = $12
+	`
+
+	_, err := conn(r.db, tx).Exec(
+		query,
+		deposit.InitialAmount,
+		deposit.Amount,
+		deposit.InterestRate,
+		deposit.EarlyWithdrawalRate,
+		deposit.InterestPaid,
+		deposit.StartDate,
+		deposit.MaturityDate,
+		deposit.LastInterestDate,
+		deposit.NextInterestDate,
+		deposit.Status,
+		deposit.ClosedAt,
+		deposit.ID,
+	)
+	return err
+}
+
+func (r *DepositRepository) queryDeposits(query string, args ...interface{}) ([]*models.Deposit, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deposits []*models.Deposit
+	for rows.Next() {
+		deposit, err := scanDeposit(rows)
+		if err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, deposit)
+	}
+
+	return deposits, rows.Err()
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type HoldRepository struct {
+	db *sql.DB
+}
+
+func NewHoldRepository(db *sql.DB) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+const holdColumns = `id, account_id, amount, card_id, to_account_id, captured_amount,
+	transaction_id, status, COALESCE(description, ''), expires_at, created_at, updated_at`
+
+func scanHold(row interface{ Scan(...interface{}) error }) (*models.Hold, error) {
+	hold := &models.Hold{}
+	err := row.Scan(
+		&hold.ID,
+		&hold.AccountID,
+		&hold.Amount,
+		&hold.CardID,
+		&hold.ToAccountID,
+		&hold.CapturedAmount,
+		&hold.TransactionID,
+		&hold.Status,
+		&hold.Description,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+	)
+	return hold, err
+}
+
+func (r *HoldRepository) CreateHold(tx *sql.Tx, hold *models.Hold) error {
+	query := `
+		INSERT INTO holds (account_id, amount, card_id, to_account_id, status, description, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+
+	return conn(r.db, tx).QueryRow(
+		query,
+		hold.AccountID,
+		hold.Amount,
+		hold.CardID,
+		hold.ToAccountID,
+		hold.Status,
+		hold.Description,
+		hold.ExpiresAt,
+	).Scan(&hold.ID, &hold.CreatedAt, &hold.UpdatedAt)
+}
+
+func (r *HoldRepository) GetHoldByID(id int) (*models.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds
+		WHERE id = $1
+	`
+
+	hold, err := scanHold(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return hold, err
+}
+
+func (r *HoldRepository) GetHoldByIDForUpdate(tx *sql.Tx, id int) (*models.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	hold, err := scanHold(tx.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return hold, err
+}
+
+func (r *HoldRepository) GetHoldsByAccount(accountID int) ([]*models.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds
+		WHERE account_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []*models.Hold
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+
+	return holds, rows.Err()
+}
+
+// CompleteHold фиксирует списание или отмену блокировки
+func (r *HoldRepository) CompleteHold(tx *sql.Tx, hold *models.Hold) error {
+	query := `
+		UPDATE holds
+		SET status = $1, captured_amount = $2, transaction_id = $3, updated_at = NOW()
+		WHERE id = $4
+	`
+
+	_, err := tx.Exec(query, hold.Status, hold.CapturedAmount, hold.TransactionID, hold.ID)
+	return err
+}
+
+// ExpireHolds переводит истекшие блокировки в статус expired и возвращает
+// их
+func (r *HoldRepository) ExpireHolds(tx *sql.Tx, now time.Time) ([]*models.Hold, error) {
+	query := `
+		UPDATE holds
+		SET status = $1, updated_at = NOW()
+		WHERE status = $2 AND expires_at <= $3
+		RETURNING ` + holdColumns
+
+	rows, err := tx.Query(query, models.HoldStatusExpired, models.HoldStatusActive, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []*models.Hold
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+
+	return holds, rows.Err()
+}
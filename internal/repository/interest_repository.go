@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"time"
+)
+
+type InterestRepository struct {
+	db *sql.DB
+}
+
+func NewInterestRepository(db *sql.DB) *InterestRepository {
+	return &InterestRepository{db: db}
+}
+
+// CreateAccrual сохраняет начисление за день. Повторное начисление за ту же
+// дату игнорируется, поэтому задачу шедулера можно безопасно перезапускать.
+func (r *InterestRepository) CreateAccrual(accrual *models.InterestAccrual) error {
+	query := `
+		INSERT INTO interest_accruals (account_id, accrual_date, balance, rate, amount)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id, accrual_date) DO NOTHING
+	`
+
+	_, err := r.db.Exec(
+		query,
+		accrual.AccountID,
+		accrual.AccrualDate,
+		accrual.Balance,
+		accrual.Rate,
+		accrual.Amount,
+	)
+	return err
+}
+
+// GetLastAccrualDate возвращает дату последнего начисления или nil,
+// если начислений по счету еще не было
+func (r *InterestRepository) GetLastAccrualDate(accountID int) (*time.Time, error) {
+	query := `
+		SELECT MAX(accrual_date)
+		FROM interest_accruals
+		WHERE account_id = $1
+	`
+
+	var date sql.NullTime
+	if err := r.db.QueryRow(query, accountID).Scan(&date); err != nil {
+		return nil, err
+	}
+	if !date.Valid {
+		return nil, nil
+	}
+
+	return &date.Time, nil
+}
+
+func (r *InterestRepository) GetAccrualsByAccount(accountID int) ([]*models.InterestAccrual, error) {
+	query := `
+		SELECT id, account_id, accrual_date, balance, rate, amount, transaction_id, created_at
+		FROM interest_accruals
+		WHERE account_id = $1
+		ORDER BY accrual_date DESC
+	`
+
+	rows, err := r.db.Query(query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accruals []*models.InterestAccrual
+	for rows.Next() {
+		accrual := &models.InterestAccrual{}
+		if err := rows.Scan(
+			&accrual.ID,
+			&accrual.AccountID,
+			&accrual.AccrualDate,
+			&accrual.Balance,
+			&accrual.Rate,
+			&accrual.Amount,
+			&accrual.TransactionID,
+			&accrual.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		accruals = append(accruals, accrual)
+	}
+
+	return accruals, rows.Err()
+}
+
+// SumUncapitalized возвращает сумму еще не капитализированных начислений
+// за дни до указанной даты и блокирует эти строки до конца транзакции
+func (r *InterestRepository) SumUncapitalized(tx *sql.Tx, accountID int, before time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM (
+			SELECT amount
+			FROM interest_accruals
+			WHERE account_id = $1 AND transaction_id IS NULL AND accrual_date < $2
+			FOR UPDATE
+		) pending
+	`
+
+	var sum float64
+	err := tx.QueryRow(query, accountID, before).Scan(&sum)
+	return sum, err
+}
+
+// SumCarriedOver возвращает остаток прошлых капитализаций: разницу между
+// капитализированными начислениями и зачисленными по ним суммами. Зачисляются
+// целые копейки, а доли копейки переходят в следующую капитализацию.
+func (r *InterestRepository) SumCarriedOver(tx *sql.Tx, accountID int) (float64, error) {
+	query := `
+		SELECT
+			COALESCE((
+				SELECT SUM(amount)
+				FROM interest_accruals
+				WHERE account_id = $1 AND transaction_id IS NOT NULL
+			), 0) - COALESCE((
+				SELECT SUM(amount)
+				FROM transactions
+				WHERE id IN (
+					SELECT transaction_id
+					FROM interest_accruals
+					WHERE account_id = $1 AND transaction_id IS NOT NULL
+				)
+			), 0)
+	`
+
+	var sum float64
+	err := tx.QueryRow(query, accountID).Scan(&sum)
+	return sum, err
+}
+
+func (r *InterestRepository) MarkCapitalized(tx *sql.Tx, accountID int, before time.Time, transactionID int) error {
+	query := `
+		UPDATE interest_accruals
+		SET transaction_id = $1
+		WHERE account_id = $2 AND transaction_id IS NULL AND accrual_date < $3
+	`
+
+	_, err := tx.Exec(query, transactionID, accountID, before)
+	return err
+}
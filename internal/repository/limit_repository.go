@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type LimitRepository struct {
+	db *sql.DB
+}
+
+func NewLimitRepository(db *sql.DB) *LimitRepository {
+	return &LimitRepository{db: db}
+}
+
+// GetAccountLimits возвращает лимиты, заданные клиентом для счета, или nil,
+// если действуют лимиты банка
+func (r *LimitRepository) GetAccountLimits(tx *sql.Tx, accountID int) (*models.SpendingLimits, error) {
+	query := `
+		SELECT daily_amount, monthly_amount, daily_count, monthly_count
+		FROM account_limits
+		WHERE account_id = $1
+	`
+
+	return r.getLimits(tx, query, accountID)
+}
+
+func (r *LimitRepository) GetCardLimits(tx *sql.Tx, cardID int) (*models.SpendingLimits, error) {
+	query := `
+		SELECT daily_amount, monthly_amount, daily_count, monthly_count
+		FROM card_limits
+		WHERE card_id = $1
+	`
+
+	return r.getLimits(tx, query, cardID)
+}
+
+func (r *LimitRepository) SetAccountLimits(accountID int, limits *models.SpendingLimits) error {
+	query := `
+		INSERT INTO account_limits (account_id, daily_amount, monthly_amount, daily_count, monthly_count)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id) DO UPDATE
+		SET daily_amount = EXCLUDED.daily_amount,
+			monthly_amount = EXCLUDED.monthly_amount,
+			daily_count = EXCLUDED.daily_count,
+			monthly_count = EXCLUDED.monthly_count,
+			updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(query, accountID, limits.DailyAmount, limits.MonthlyAmount, limits.DailyCount, limits.MonthlyCount)
+	return err
+}
+
+func (r *LimitRepository) SetCardLimits(cardID int, limits *models.SpendingLimits) error {
+	query := `
+		INSERT INTO card_limits (card_id, daily_amount, monthly_amount, daily_count, monthly_count)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (card_id) DO UPDATE
+		SET daily_amount = EXCLUDED.daily_amount,
+			monthly_amount = EXCLUDED.monthly_amount,
+			daily_count = EXCLUDED.daily_count,
+			monthly_count = EXCLUDED.monthly_count,
+			updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(query, cardID, limits.DailyAmount, limits.MonthlyAmount, limits.DailyCount, limits.MonthlyCount)
+	return err
+}
+
+// CopyCardLimits переносит лимиты, заданные клиентом, на перевыпущенную карту
+func (r *LimitRepository) CopyCardLimits(tx *sql.Tx, fromCardID, toCardID int) error {
+	query := `
+		INSERT INTO card_limits (card_id, daily_amount, monthly_amount, daily_count, monthly_count)
+		SELECT $2, daily_amount, monthly_amount, daily_count, monthly_count
+		FROM card_limits
+		WHERE card_id = $1
+	`
+
+	_, err := conn(r.db, tx).Exec(query, fromCardID, toCardID)
+	return err
+}
+
+// GetAccountSpending возвращает сумму и число расходных операций по счету
+// начиная с указанного момента. Учитываются списания и действующие
+// блокировки, которые станут списаниями.
+func (r *LimitRepository) GetAccountSpending(tx *sql.Tx, accountID int, since time.Time) (float64, int, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0), COUNT(*)
+		FROM (
+			SELECT -amount AS amount
+			FROM transactions
+			WHERE account_id = $1 AND amount < 0 AND type IN ($3, $4, $5) AND created_at >= $2
+			UNION ALL
+			SELECT amount
+			FROM holds
+			WHERE account_id = $1 AND status = $6 AND expires_at > NOW() AND created_at >= $2
+		) spending
+	`
+
+	var (
+		amount float64
+		count  int
+	)
+	err := conn(r.db, tx).QueryRow(
+		query,
+		accountID,
+		since,
+		models.TransactionWithdrawal,
+		models.TransactionTransfer,
+		models.TransactionPayment,
+		models.HoldStatusActive,
+	).Scan(&amount, &count)
+	return amount, count, err
+}
+
+// GetCardSpending возвращает сумму и число операций по карте начиная
+// с указанного момента: действующие и списанные блокировки по карте
+func (r *LimitRepository) GetCardSpending(tx *sql.Tx, cardID int, since time.Time) (float64, int, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN status = $3 THEN captured_amount ELSE amount END), 0),
+			COUNT(*)
+		FROM holds
+		WHERE card_id = $1
+			AND created_at >= $2
+			AND (status = $3 OR (status = $4 AND expires_at > NOW()))
+	`
+
+	var (
+		amount float64
+		count  int
+	)
+	err := conn(r.db, tx).QueryRow(query, cardID, since, models.HoldStatusCaptured, models.HoldStatusActive).Scan(&amount, &count)
+	return amount, count, err
+}
+
+func (r *LimitRepository) getLimits(tx *sql.Tx, query string, id int) (*models.SpendingLimits, error) {
+	limits := &models.SpendingLimits{}
+	err := conn(r.db, tx).QueryRow(query, id).Scan(
+		&limits.DailyAmount,
+		&limits.MonthlyAmount,
+		&limits.DailyCount,
+		&limits.MonthlyCount,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return limits, err
+}
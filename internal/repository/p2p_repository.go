@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+)
+
+type P2PRepository struct {
+	db *sql.DB
+}
+
+func NewP2PRepository(db *sql.DB) *P2PRepository {
+	return &P2PRepository{db: db}
+}
+
+func (r *P2PRepository) CreateTransfer(transfer *models.P2PTransfer) error {
+	query := `
+		INSERT INTO p2p_transfers (
+			user_id, from_account_id, recipient_user_id, to_account_id, recipient,
+			recipient_name, amount, description, status, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		transfer.UserID,
+		transfer.FromAccountID,
+		transfer.RecipientUserID,
+		transfer.ToAccountID,
+		transfer.Recipient,
+		transfer.RecipientName,
+		transfer.Amount,
+		transfer.Description,
+		transfer.Status,
+		transfer.ExpiresAt,
+	).Scan(&transfer.ID, &transfer.CreatedAt)
+}
+
+func (r *P2PRepository) GetTransferByID(id int) (*models.P2PTransfer, error) {
+	query := `
+		SELECT id, user_id, from_account_id, recipient_user_id, to_account_id, recipient,
+			recipient_name, amount, COALESCE(description, ''), status, COALESCE(error, ''),
+			expires_at, created_at
+		FROM p2p_transfers
+		WHERE id = $1
+	`
+
+	transfer := &models.P2PTransfer{}
+	err := r.db.QueryRow(query, id).Scan(
+		&transfer.ID,
+		&transfer.UserID,
+		&transfer.FromAccountID,
+		&transfer.RecipientUserID,
+		&transfer.ToAccountID,
+		&transfer.Recipient,
+		&transfer.RecipientName,
+		&transfer.Amount,
+		&transfer.Description,
+		&transfer.Status,
+		&transfer.Error,
+		&transfer.ExpiresAt,
+		&transfer.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return transfer, err
+}
+
+// ClaimPending переводит неподтвержденный перевод в обработку. Возвращает
+// false, если перевод уже подтвержден параллельным запросом.
+func (r *P2PRepository) ClaimPending(id int) (bool, error) {
+	query := `
+		UPDATE p2p_transfers
+		SET status = $1
+		WHERE id = $2 AND status = $3
+	`
+
+	result, err := r.db.Exec(query, models.P2PTransferProcessing, id, models.P2PTransferPending)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *P2PRepository) CompleteTransfer(id int, status models.P2PTransferStatus, errorText string) error {
+	query := `
+		UPDATE p2p_transfers
+		SET status = $1, error = NULLIF($2, '')
+		WHERE id = $3
+	`
+
+	_, err := r.db.Exec(query, status, errorText, id)
+	return err
+}
+
+func (r *P2PRepository) CreatePhoneVerification(verification *models.PhoneVerification) error {
+	query := `
+		INSERT INTO phone_verifications (user_id, phone, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		verification.UserID,
+		verification.Phone,
+		verification.CodeHash,
+		verification.ExpiresAt,
+	).Scan(&verification.ID, &verification.CreatedAt)
+}
+
+// GetPendingPhoneVerification возвращает последний неподтвержденный код
+// клиента
+func (r *P2PRepository) GetPendingPhoneVerification(userID int) (*models.PhoneVerification, error) {
+	query := `
+		SELECT id, user_id, phone, code_hash, attempts, expires_at, confirmed_at, created_at
+		FROM phone_verifications
+		WHERE user_id = $1 AND confirmed_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	verification := &models.PhoneVerification{}
+	err := r.db.QueryRow(query, userID).Scan(
+		&verification.ID,
+		&verification.UserID,
+		&verification.Phone,
+		&verification.CodeHash,
+		&verification.Attempts,
+		&verification.ExpiresAt,
+		&verification.ConfirmedAt,
+		&verification.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return verification, err
+}
+
+// AddPhoneVerificationAttempt учитывает попытку ввода кода. Возвращает
+// false, если попытки исчерпаны или код уже подтвержден.
+func (r *P2PRepository) AddPhoneVerificationAttempt(id, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE phone_verifications
+		SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2 AND confirmed_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// ConfirmPhoneVerification отмечает код использованным. Возвращает false,
+// если его уже использовал параллельный запрос.
+func (r *P2PRepository) ConfirmPhoneVerification(id int) (bool, error) {
+	query := `
+		UPDATE phone_verifications
+		SET confirmed_at = NOW()
+		WHERE id = $1 AND confirmed_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PaymentImportRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentImportRepository(db *sql.DB) *PaymentImportRepository {
+	return &PaymentImportRepository{db: db}
+}
+
+// ReserveImport занимает MsgId клиента до исполнения переводов. Возвращает
+// false, если загрузка с таким MsgId уже есть: уникальный ключ
+// (user_id, message_id) не дает параллельным запросам исполнить файл дважды.
+func (r *PaymentImportRepository) ReserveImport(paymentImport *models.PaymentImport) (bool, error) {
+	query := `
+		INSERT INTO payment_imports (user_id, message_id, status, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, message_id) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		paymentImport.UserID,
+		paymentImport.MessageID,
+		paymentImport.Status,
+	).Scan(&paymentImport.ID, &paymentImport.CreatedAt, &paymentImport.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ClaimStaleImport перехватывает загрузку, исполнение которой прервалось:
+// она все еще в статусе processing и не обновлялась с момента staleBefore.
+// Возвращает false, если загрузку уже завершили или перехватили.
+func (r *PaymentImportRepository) ClaimStaleImport(paymentImport *models.PaymentImport, staleBefore time.Time) (bool, error) {
+	query := `
+		UPDATE payment_imports
+		SET updated_at = NOW()
+		WHERE id = $1 AND status = $2 AND updated_at < $3
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(query, paymentImport.ID, models.PaymentImportProcessing, staleBefore).Scan(&paymentImport.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// SaveTransaction сохраняет статус строки загрузки. Возвращает false, если
+// статус строки уже сохранен.
+func (r *PaymentImportRepository) SaveTransaction(tx *sql.Tx, transaction *models.PaymentImportTransaction) (bool, error) {
+	query := `
+		INSERT INTO payment_import_transactions (import_id, status_id, status, reason, info)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (import_id, status_id) DO NOTHING
+	`
+
+	result, err := conn(r.db, tx).Exec(
+		query,
+		transaction.ImportID,
+		transaction.StatusID,
+		transaction.Status,
+		transaction.Reason,
+		transaction.Info,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetTransactions возвращает сохраненные статусы строк загрузки
+func (r *PaymentImportRepository) GetTransactions(importID int) ([]*models.PaymentImportTransaction, error) {
+	query := `
+		SELECT import_id, status_id, status, reason, info
+		FROM payment_import_transactions
+		WHERE import_id = $1
+	`
+
+	rows, err := r.db.Query(query, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.PaymentImportTransaction
+	for rows.Next() {
+		transaction := &models.PaymentImportTransaction{}
+		if err := rows.Scan(
+			&transaction.ImportID,
+			&transaction.StatusID,
+			&transaction.Status,
+			&transaction.Reason,
+			&transaction.Info,
+		); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
+// CompleteImport сохраняет итоговый статус и отчет загрузки
+func (r *PaymentImportRepository) CompleteImport(paymentImport *models.PaymentImport) error {
+	query := `
+		UPDATE payment_imports
+		SET status = $1, report = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+
+	_, err := r.db.Exec(query, paymentImport.Status, paymentImport.Report, paymentImport.ID)
+	return err
+}
+
+func (r *PaymentImportRepository) GetImportByMessageID(userID int, messageID string) (*models.PaymentImport, error) {
+	query := `
+		SELECT id, user_id, message_id, status, report, created_at, updated_at
+		FROM payment_imports
+		WHERE user_id = $1 AND message_id = $2
+	`
+
+	paymentImport := &models.PaymentImport{}
+	err := r.db.QueryRow(query, userID, messageID).Scan(
+		&paymentImport.ID,
+		&paymentImport.UserID,
+		&paymentImport.MessageID,
+		&paymentImport.Status,
+		&paymentImport.Report,
+		&paymentImport.CreatedAt,
+		&paymentImport.UpdatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return paymentImport, err
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const paymentRequestColumns = `
+	id, requester_id, to_account_id, requester_name, payer_user_id, payer,
+	from_account_id, amount, COALESCE(note, ''), status, expires_at, responded_at,
+	created_at
+`
+
+type PaymentRequestRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRequestRepository(db *sql.DB) *PaymentRequestRepository {
+	return &PaymentRequestRepository{db: db}
+}
+
+func (r *PaymentRequestRepository) CreateRequest(request *models.PaymentRequest) error {
+	query := `
+		INSERT INTO payment_requests (
+			requester_id, to_account_id, requester_name, payer_user_id, payer,
+			from_account_id, amount, note, status, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		request.RequesterID,
+		request.ToAccountID,
+		request.RequesterName,
+		request.PayerUserID,
+		request.Payer,
+		request.FromAccountID,
+		request.Amount,
+		request.Note,
+		request.Status,
+		request.ExpiresAt,
+	).Scan(&request.ID, &request.CreatedAt)
+}
+
+func (r *PaymentRequestRepository) GetRequestByID(id int) (*models.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE id = $1`
+
+	request, err := scanPaymentRequest(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return request, err
+}
+
+// GetOutgoingRequests возвращает запросы, выставленные пользователем
+func (r *PaymentRequestRepository) GetOutgoingRequests(userID int) ([]*models.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + `
+		FROM payment_requests
+		WHERE requester_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return r.queryRequests(query, userID)
+}
+
+// GetIncomingRequests возвращает запросы, выставленные пользователю
+func (r *PaymentRequestRepository) GetIncomingRequests(userID int) ([]*models.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + `
+		FROM payment_requests
+		WHERE payer_user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return r.queryRequests(query, userID)
+}
+
+// SetStatus меняет статус запроса, если он находится в статусе from.
+// Возвращает false, если запрос уже обработан параллельным запросом.
+func (r *PaymentRequestRepository) SetStatus(id int, from, to models.PaymentRequestStatus) (bool, error) {
+	query := `
+		UPDATE payment_requests
+		SET status = $1
+		WHERE id = $2 AND status = $3
+	`
+
+	result, err := r.db.Exec(query, to, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *PaymentRequestRepository) CompleteRequest(request *models.PaymentRequest) error {
+	query := `
+		UPDATE payment_requests
+		SET status = $1, from_account_id = $2, responded_at = $3
+		WHERE id = $4
+	`
+
+	_, err := r.db.Exec(query, request.Status, request.FromAccountID, request.RespondedAt, request.ID)
+	return err
+}
+
+// ExpireRequests помечает просроченные запросы и возвращает их
+func (r *PaymentRequestRepository) ExpireRequests(now time.Time) ([]*models.PaymentRequest, error) {
+	query := `
+		UPDATE payment_requests
+		SET status = $1
+		WHERE status = $2 AND expires_at <= $3
+		RETURNING ` + paymentRequestColumns
+
+	return r.queryRequests(query, models.PaymentRequestExpired, models.PaymentRequestPending, now)
+}
+
+func (r *PaymentRequestRepository) queryRequests(query string, args ...interface{}) ([]*models.PaymentRequest, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*models.PaymentRequest
+	for rows.Next() {
+		request, err := scanPaymentRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
+
+func scanPaymentRequest(row interface{ Scan(...interface{}) error }) (*models.PaymentRequest, error) {
+	request := &models.PaymentRequest{}
+	err := row.Scan(
+		&request.ID,
+		&request.RequesterID,
+		&request.ToAccountID,
+		&request.RequesterName,
+		&request.PayerUserID,
+		&request.Payer,
+		&request.FromAccountID,
+		&request.Amount,
+		&request.Note,
+		&request.Status,
+		&request.ExpiresAt,
+		&request.RespondedAt,
+		&request.CreatedAt,
+	)
+	return request, err
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const payrollBatchColumns = `
+	id, user_id, from_account_id, COALESCE(reference, ''), mode, status, total_amount,
+	item_count, completed_count, failed_count, COALESCE(error, ''), created_at
+`
+
+type PayrollRepository struct {
+	db *sql.DB
+}
+
+func NewPayrollRepository(db *sql.DB) *PayrollRepository {
+	return &PayrollRepository{db: db}
+}
+
+func scanPayrollBatch(row interface{ Scan(...interface{}) error }) (*models.PayrollBatch, error) {
+	batch := &models.PayrollBatch{}
+	err := row.Scan(
+		&batch.ID,
+		&batch.UserID,
+		&batch.FromAccountID,
+		&batch.Reference,
+		&batch.Mode,
+		&batch.Status,
+		&batch.TotalAmount,
+		&batch.ItemCount,
+		&batch.CompletedCount,
+		&batch.FailedCount,
+		&batch.Error,
+		&batch.CreatedAt,
+	)
+	return batch, err
+}
+
+// CreateBatch сохраняет ведомость со строками до исполнения переводов.
+// Возвращает false, если у клиента уже есть ведомость с тем же
+// идентификатором: уникальный ключ (user_id, reference) не дает параллельным
+// запросам исполнить ведомость дважды.
+func (r *PayrollRepository) CreateBatch(batch *models.PayrollBatch) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO payroll_batches (
+			user_id, from_account_id, reference, mode, status, total_amount,
+			item_count, completed_count, failed_count, updated_at
+		)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, 0, 0, NOW())
+		ON CONFLICT (user_id, reference) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(
+		query,
+		batch.UserID,
+		batch.FromAccountID,
+		batch.Reference,
+		batch.Mode,
+		batch.Status,
+		batch.TotalAmount,
+		batch.ItemCount,
+	).Scan(&batch.ID, &batch.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	itemQuery := `
+		INSERT INTO payroll_items (batch_id, line, to_account, amount, description, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	for _, item := range batch.Items {
+		item.BatchID = batch.ID
+		err := tx.QueryRow(
+			itemQuery,
+			item.BatchID,
+			item.Line,
+			item.ToAccount,
+			item.Amount,
+			item.Description,
+			item.Status,
+		).Scan(&item.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// ClaimStaleBatch перехватывает ведомость, исполнение которой прервалось:
+// она все еще в статусе processing и не обновлялась с момента staleBefore.
+// Возвращает false, если ведомость уже завершили или перехватили.
+func (r *PayrollRepository) ClaimStaleBatch(batchID int, staleBefore time.Time) (bool, error) {
+	query := `
+		UPDATE payroll_batches
+		SET updated_at = NOW()
+		WHERE id = $1 AND status = $2 AND updated_at < $3
+	`
+
+	result, err := r.db.Exec(query, batchID, models.PayrollProcessing, staleBefore)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// CompleteItem отмечает строку исполненной в транзакции ее перевода.
+// Возвращает false, если строка уже не ожидает исполнения.
+func (r *PayrollRepository) CompleteItem(tx *sql.Tx, item *models.PayrollItem, transactionID int) (bool, error) {
+	query := `
+		UPDATE payroll_items
+		SET status = $1, to_account_id = $2, transaction_id = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	result, err := tx.Exec(
+		query,
+		models.PayrollItemCompleted,
+		item.ToAccountID,
+		transactionID,
+		item.ID,
+		models.PayrollItemPending,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// CompleteBatch сохраняет итог ведомости и результаты строк, которые не
+// были исполнены. Исполненные строки отмечены вместе с переводами.
+func (r *PayrollRepository) CompleteBatch(batch *models.PayrollBatch) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE payroll_batches
+		SET status = $1, completed_count = $2, failed_count = $3, error = NULLIF($4, ''),
+			updated_at = NOW()
+		WHERE id = $5
+	`
+
+	_, err = tx.Exec(
+		query,
+		batch.Status,
+		batch.CompletedCount,
+		batch.FailedCount,
+		batch.Error,
+		batch.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	itemQuery := `
+		UPDATE payroll_items
+		SET status = $1, error = NULLIF($2, ''), to_account_id = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	for _, item := range batch.Items {
+		if item.Status == models.PayrollItemCompleted {
+			continue
+		}
+		_, err := tx.Exec(
+			itemQuery,
+			item.Status,
+			item.Error,
+			item.ToAccountID,
+			item.ID,
+			models.PayrollItemPending,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *PayrollRepository) GetBatchByID(id int) (*models.PayrollBatch, error) {
+	query := `SELECT ` + payrollBatchColumns + ` FROM payroll_batches WHERE id = $1`
+
+	batch, err := scanPayrollBatch(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return batch, err
+}
+
+func (r *PayrollRepository) GetBatchByReference(userID int, reference string) (*models.PayrollBatch, error) {
+	query := `SELECT ` + payrollBatchColumns + ` FROM payroll_batches WHERE user_id = $1 AND reference = $2`
+
+	batch, err := scanPayrollBatch(r.db.QueryRow(query, userID, reference))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return batch, err
+}
+
+func (r *PayrollRepository) GetBatchesByUser(userID int) ([]*models.PayrollBatch, error) {
+	query := `
+		SELECT ` + payrollBatchColumns + `
+		FROM payroll_batches
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []*models.PayrollBatch
+	for rows.Next() {
+		batch, err := scanPayrollBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, rows.Err()
+}
+
+func (r *PayrollRepository) GetItemsByBatch(batchID int) ([]*models.PayrollItem, error) {
+	query := `
+		SELECT id, batch_id, line, to_account, to_account_id, amount,
+			COALESCE(description, ''), status, COALESCE(error, ''), transaction_id
+		FROM payroll_items
+		WHERE batch_id = $1
+		ORDER BY line
+	`
+
+	rows, err := r.db.Query(query, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.PayrollItem
+	for rows.Next() {
+		item := &models.PayrollItem{}
+		err := rows.Scan(
+			&item.ID,
+			&item.BatchID,
+			&item.Line,
+			&item.ToAccount,
+			&item.ToAccountID,
+			&item.Amount,
+			&item.Description,
+			&item.Status,
+			&item.Error,
+			&item.TransactionID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
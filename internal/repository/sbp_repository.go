@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+)
+
+type SBPRepository struct {
+	db *sql.DB
+}
+
+func NewSBPRepository(db *sql.DB) *SBPRepository {
+	return &SBPRepository{db: db}
+}
+
+const merchantColumns = `id, user_id, account_id, name, merchant_id, COALESCE(callback_url, ''),
+	callback_secret, created_at`
+
+func scanMerchant(row interface{ Scan(...interface{}) error }) (*models.SBPMerchant, error) {
+	merchant := &models.SBPMerchant{}
+	err := row.Scan(
+		&merchant.ID,
+		&merchant.UserID,
+		&merchant.AccountID,
+		&merchant.Name,
+		&merchant.MerchantID,
+		&merchant.CallbackURL,
+		&merchant.CallbackSecret,
+		&merchant.CreatedAt,
+	)
+	return merchant, err
+}
+
+func (r *SBPRepository) CreateMerchant(merchant *models.SBPMerchant) error {
+	query := `
+		INSERT INTO sbp_merchants (user_id, account_id, name, merchant_id, callback_url, callback_secret)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		merchant.UserID,
+		merchant.AccountID,
+		merchant.Name,
+		merchant.MerchantID,
+		merchant.CallbackURL,
+		merchant.CallbackSecret,
+	).Scan(&merchant.ID, &merchant.CreatedAt)
+}
+
+func (r *SBPRepository) GetMerchantByID(id int) (*models.SBPMerchant, error) {
+	query := `
+		SELECT ` + merchantColumns + `
+		FROM sbp_merchants
+		WHERE id = $1
+	`
+
+	merchant, err := scanMerchant(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return merchant, err
+}
+
+func (r *SBPRepository) GetMerchantsByUser(userID int) ([]*models.SBPMerchant, error) {
+	query := `
+		SELECT ` + merchantColumns + `
+		FROM sbp_merchants
+		WHERE user_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var merchants []*models.SBPMerchant
+	for rows.Next() {
+		merchant, err := scanMerchant(rows)
+		if err != nil {
+			return nil, err
+		}
+		merchants = append(merchants, merchant)
+	}
+
+	return merchants, rows.Err()
+}
+
+// IsMerchantAccount проверяет, зачисляются ли на счет оплаты ТСП
+func (r *SBPRepository) IsMerchantAccount(accountID int) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM sbp_merchants WHERE account_id = $1)`
+
+	var exists bool
+	err := r.db.QueryRow(query, accountID).Scan(&exists)
+	return exists, err
+}
+
+func (r *SBPRepository) CreateQR(qr *models.SBPQRCode) error {
+	query := `
+		INSERT INTO sbp_qr_codes (qrc_id, merchant_id, type, amount, purpose, payload, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		qr.QRCID,
+		qr.MerchantID,
+		qr.Type,
+		qr.Amount,
+		qr.Purpose,
+		qr.Payload,
+		qr.Status,
+		qr.ExpiresAt,
+	).Scan(&qr.ID, &qr.CreatedAt)
+}
+
+func (r *SBPRepository) GetQRByQRCID(qrcID string) (*models.SBPQRCode, error) {
+	query := `
+		SELECT id, qrc_id, merchant_id, type, amount, COALESCE(purpose, ''), payload, status,
+			expires_at, created_at
+		FROM sbp_qr_codes
+		WHERE qrc_id = $1
+	`
+
+	qr := &models.SBPQRCode{}
+	err := r.db.QueryRow(query, qrcID).Scan(
+		&qr.ID,
+		&qr.QRCID,
+		&qr.MerchantID,
+		&qr.Type,
+		&qr.Amount,
+		&qr.Purpose,
+		&qr.Payload,
+		&qr.Status,
+		&qr.ExpiresAt,
+		&qr.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return qr, err
+}
+
+// SetQRStatus меняет статус кода, только если он находится в ожидаемом
+// статусе, и сообщает, удалось ли это сделать
+func (r *SBPRepository) SetQRStatus(id int, from, to models.SBPQRStatus) (bool, error) {
+	query := `
+		UPDATE sbp_qr_codes
+		SET status = $1
+		WHERE id = $2 AND status = $3
+	`
+
+	result, err := r.db.Exec(query, to, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *SBPRepository) CreatePayment(payment *models.SBPPayment) error {
+	query := `
+		INSERT INTO sbp_payments (qrc_id, merchant_id, payer_user_id, payer_account_id, amount, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		payment.QRCID,
+		payment.MerchantID,
+		payment.PayerUserID,
+		payment.PayerAccountID,
+		payment.Amount,
+		payment.Status,
+		payment.Error,
+	).Scan(&payment.ID, &payment.CreatedAt)
+}
+
+func (r *SBPRepository) GetPaymentByID(id int) (*models.SBPPayment, error) {
+	query := `
+		SELECT id, qrc_id, merchant_id, payer_user_id, payer_account_id, amount, status,
+			COALESCE(error, ''), created_at
+		FROM sbp_payments
+		WHERE id = $1
+	`
+
+	payment := &models.SBPPayment{}
+	err := r.db.QueryRow(query, id).Scan(
+		&payment.ID,
+		&payment.QRCID,
+		&payment.MerchantID,
+		&payment.PayerUserID,
+		&payment.PayerAccountID,
+		&payment.Amount,
+		&payment.Status,
+		&payment.Error,
+		&payment.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return payment, err
+}
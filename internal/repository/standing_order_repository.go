@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"bank-api/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type StandingOrderRepository struct {
+	db *sql.DB
+}
+
+func NewStandingOrderRepository(db *sql.DB) *StandingOrderRepository {
+	return &StandingOrderRepository{db: db}
+}
+
+const standingOrderColumns = `id, user_id, from_account_id, to_account_id, amount,
+	COALESCE(description, ''), schedule, weekday, day_of_month, start_date, end_date,
+	scheduled_for, next_run_at, failed_attempts, status, created_at, updated_at`
+
+func scanStandingOrder(row interface{ Scan(...interface{}) error }) (*models.StandingOrder, error) {
+	order := &models.StandingOrder{}
+	err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.FromAccountID,
+		&order.ToAccountID,
+		&order.Amount,
+		&order.Description,
+		&order.Schedule,
+		&order.Weekday,
+		&order.DayOfMonth,
+		&order.StartDate,
+		&order.EndDate,
+		&order.ScheduledFor,
+		&order.NextRunAt,
+		&order.FailedAttempts,
+		&order.Status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	return order, err
+}
+
+func (r *StandingOrderRepository) CreateOrder(order *models.StandingOrder) error {
+	query := `
+		INSERT INTO standing_orders (
+			user_id, from_account_id, to_account_id, amount, description, schedule,
+			weekday, day_of_month, start_date, end_date, scheduled_for, next_run_at,
+			failed_attempts, status
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		order.UserID,
+		order.FromAccountID,
+		order.ToAccountID,
+		order.Amount,
+		order.Description,
+		order.Schedule,
+		order.Weekday,
+		order.DayOfMonth,
+		order.StartDate,
+		order.EndDate,
+		order.ScheduledFor,
+		order.NextRunAt,
+		order.FailedAttempts,
+		order.Status,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+}
+
+func (r *StandingOrderRepository) GetOrderByID(id int) (*models.StandingOrder, error) {
+	query := `
+		SELECT ` + standingOrderColumns + `
+		FROM standing_orders
+		WHERE id = $1
+	`
+
+	order, err := scanStandingOrder(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return order, err
+}
+
+func (r *StandingOrderRepository) GetOrdersByUser(userID int) ([]*models.StandingOrder, error) {
+	query := `
+		SELECT ` + standingOrderColumns + `
+		FROM standing_orders
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return r.queryOrders(query, userID)
+}
+
+// GetDueOrders возвращает действующие поручения, время попытки по которым наступило
+func (r *StandingOrderRepository) GetDueOrders(now time.Time) ([]*models.StandingOrder, error) {
+	query := `
+		SELECT ` + standingOrderColumns + `
+		FROM standing_orders
+		WHERE status = $1 AND next_run_at <= $2
+		ORDER BY next_run_at, id
+	`
+
+	return r.queryOrders(query, models.StandingOrderStatusActive, now)
+}
+
+func (r *StandingOrderRepository) UpdateOrder(order *models.StandingOrder) error {
+	query := `
+		UPDATE standing_orders
+		SET amount = $1,
+			description = $2,
+			end_date = $3,
+			scheduled_for = $4,
+			next_run_at = $5,
+			failed_attempts = $6,
+			status = $7,
+			updated_at = NOW()
+		WHERE id = $8
+		RETURNING updated_at
+	`
+
+	return r.db.QueryRow(
+		query,
+		order.Amount,
+		order.Description,
+		order.EndDate,
+		order.ScheduledFor,
+		order.NextRunAt,
+		order.FailedAttempts,
+		order.Status,
+		order.ID,
+	).Scan(&order.UpdatedAt)
+}
+
+// ClaimRun занимает попытку выполнить перевод до его проведения. Возвращает
+// false, если попытка уже занята: уникальный ключ
+// (order_id, scheduled_for, attempt) не дает провести перевод дважды.
+func (r *StandingOrderRepository) ClaimRun(run *models.StandingOrderRun) (bool, error) {
+	query := `
+		INSERT INTO standing_order_runs (order_id, scheduled_for, attempt, status)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (order_id, scheduled_for, attempt) DO NOTHING
+		RETURNING id, executed_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		run.OrderID,
+		run.ScheduledFor,
+		run.Attempt,
+		run.Status,
+	).Scan(&run.ID, &run.ExecutedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// CompleteRun сохраняет результат попытки
+func (r *StandingOrderRepository) CompleteRun(run *models.StandingOrderRun) error {
+	query := `
+		UPDATE standing_order_runs
+		SET status = $1, error = NULLIF($2, ''), executed_at = NOW()
+		WHERE id = $3
+		RETURNING executed_at
+	`
+
+	return r.db.QueryRow(query, run.Status, run.Error, run.ID).Scan(&run.ExecutedAt)
+}
+
+func (r *StandingOrderRepository) GetRun(orderID int, scheduledFor time.Time, attempt int) (*models.StandingOrderRun, error) {
+	query := `
+		SELECT id, order_id, scheduled_for, attempt, status, COALESCE(error, ''), executed_at
+		FROM standing_order_runs
+		WHERE order_id = $1 AND scheduled_for = $2 AND attempt = $3
+	`
+
+	run := &models.StandingOrderRun{}
+	err := r.db.QueryRow(query, orderID, scheduledFor, attempt).Scan(
+		&run.ID,
+		&run.OrderID,
+		&run.ScheduledFor,
+		&run.Attempt,
+		&run.Status,
+		&run.Error,
+		&run.ExecutedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return run, err
+}
+
+func (r *StandingOrderRepository) GetRunsByOrder(orderID int) ([]*models.StandingOrderRun, error) {
+	query := `
+		SELECT id, order_id, scheduled_for, attempt, status, COALESCE(error, ''), executed_at
+		FROM standing_order_runs
+		WHERE order_id = $1
+		ORDER BY executed_at DESC
+	`
+
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*models.StandingOrderRun
+	for rows.Next() {
+		run := &models.StandingOrderRun{}
+		if err := rows.Scan(
+			&run.ID,
+			&run.OrderID,
+			&run.ScheduledFor,
+			&run.Attempt,
+			&run.Status,
+			&run.Error,
+			&run.ExecutedAt,
+		); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+func (r *StandingOrderRepository) queryOrders(query string, args ...interface{}) ([]*models.StandingOrder, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*models.StandingOrder
+	for rows.Next() {
+		order, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
@@ -3,8 +3,13 @@ package repository
 import (
 	"bank-api/internal/models"
 	"database/sql"
+	"errors"
+	"time"
 )
 
+const transactionColumns = `id, account_id, amount, type, description, created_at,
+	counterpart_transaction_id, original_transaction_id`
+
 type TransactionRepository struct {
 	db *sql.DB
 }
@@ -13,38 +18,89 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
+func scanTransaction(row interface{ Scan(...interface{}) error }) (*models.Transaction, error) {
+	transaction := &models.Transaction{}
+	err := row.Scan(
+		&transaction.ID,
+		&transaction.AccountID,
+		&transaction.Amount,
+		&transaction.Type,
+		&transaction.Description,
+		&transaction.CreatedAt,
+		&transaction.CounterpartID,
+		&transaction.OriginalTransactionID,
+	)
+	return transaction, err
+}
+
 func (r *TransactionRepository) CreateTransaction(tx *sql.Tx, transaction *models.Transaction) error {
 	query := `
-		INSERT INTO transactions (account_id, amount, type, description)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO transactions (
+			account_id, amount, type, description, counterpart_transaction_id,
+			original_transaction_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at
 	`
 
-	var err error
-	if tx != nil {
-		err = tx.QueryRow(
-			query,
-			transaction.AccountID,
-			transaction.Amount,
-			transaction.Type,
-			transaction.Description,
-		).Scan(&transaction.ID, &transaction.CreatedAt)
-	} else {
-		err = r.db.QueryRow(
-			query,
-			transaction.AccountID,
-			transaction.Amount,
-			transaction.Type,
-			transaction.Description,
-		).Scan(&transaction.ID, &transaction.CreatedAt)
+	return conn(r.db, tx).QueryRow(
+		query,
+		transaction.AccountID,
+		transaction.Amount,
+		transaction.Type,
+		transaction.Description,
+		transaction.CounterpartID,
+		transaction.OriginalTransactionID,
+	).Scan(&transaction.ID, &transaction.CreatedAt)
+}
+
+// GetTransactionByIDForUpdate блокирует проводку, чтобы параллельные сторно
+// и возвраты одной операции выполнялись по очереди
+func (r *TransactionRepository) GetTransactionByIDForUpdate(tx *sql.Tx, id int) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1 FOR UPDATE`
+
+	transaction, err := scanTransaction(tx.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
 	}
 
+	return transaction, err
+}
+
+// SetCounterpart связывает проводку со второй проводкой перевода
+func (r *TransactionRepository) SetCounterpart(tx *sql.Tx, id, counterpartID int) error {
+	query := `
+		UPDATE transactions
+		SET counterpart_transaction_id = $1
+		WHERE id = $2
+	`
+
+	_, err := conn(r.db, tx).Exec(query, counterpartID, id)
 	return err
 }
 
+// GetCompensations возвращает, сторнирована ли проводка, и сумму
+// оформленных по ней возвратов
+func (r *TransactionRepository) GetCompensations(tx *sql.Tx, originalID int) (bool, float64, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE type = $2) > 0,
+			COALESCE(SUM(ABS(amount)) FILTER (WHERE type = $3), 0)
+		FROM transactions
+		WHERE original_transaction_id = $1
+	`
+
+	var (
+		reversed bool
+		refunded float64
+	)
+	err := conn(r.db, tx).QueryRow(query, originalID, models.TransactionReversal, models.TransactionRefund).Scan(&reversed, &refunded)
+	return reversed, refunded, err
+}
+
 func (r *TransactionRepository) GetTransactionsByAccount(accountID int) ([]*models.Transaction, error) {
 	query := `
-		SELECT id, account_id, amount, type, description, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions
 		WHERE account_id = $1
 		ORDER BY created_at DESC
@@ -58,15 +114,8 @@ func (r *TransactionRepository) GetTransactionsByAccount(accountID int) ([]*mode
 
 	var transactions []*models.Transaction
 	for rows.Next() {
-		transaction := &models.Transaction{}
-		if err := rows.Scan(
-			&transaction.ID,
-			&transaction.AccountID,
-			&transaction.Amount,
-			&transaction.Type,
-			&transaction.Description,
-			&transaction.CreatedAt,
-		); err != nil {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, transaction)
@@ -74,3 +123,89 @@ func (r *TransactionRepository) GetTransactionsByAccount(accountID int) ([]*mode
 
 	return transactions, nil
 }
+
+func (r *TransactionRepository) GetTransactionsByPeriod(accountID int, from, to time.Time) ([]*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE account_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(query, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
+// SumSince возвращает сумму движений по счету начиная с указанного момента.
+// Вычитая ее из текущего баланса, получаем остаток на этот момент.
+func (r *TransactionRepository) SumSince(accountID int, since time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE account_id = $1 AND created_at >= $2
+	`
+
+	var sum float64
+	err := r.db.QueryRow(query, accountID, since).Scan(&sum)
+	return sum, err
+}
+
+// FixLegacyTransferDebits переводит в отрицательные суммы списания по
+// переводам, проведенным до того, как расходные проводки стали храниться
+// со знаком минус, и связывает обе проводки перевода. Прежний перевод
+// записывал две положительные проводки в одной транзакции БД: сначала
+// списание, затем зачисление. Поэтому пара находится по общему времени
+// создания, сумме и описанию, а списанием считается проводка с меньшим ID.
+// Группы, где пару однозначно не определить, не меняются.
+func (r *TransactionRepository) FixLegacyTransferDebits() (int64, error) {
+	query := `
+		WITH legacy AS (
+			SELECT id, account_id,
+				ROW_NUMBER() OVER leg AS position,
+				LEAD(id) OVER leg AS credit_id,
+				COUNT(*) OVER pair AS legs,
+				MIN(account_id) OVER pair AS min_account_id,
+				MAX(account_id) OVER pair AS max_account_id
+			FROM transactions
+			WHERE type = $1 AND amount > 0 AND counterpart_transaction_id IS NULL
+			WINDOW pair AS (PARTITION BY created_at, amount, description),
+				leg AS (PARTITION BY created_at, amount, description ORDER BY id)
+		),
+		pairs AS (
+			SELECT id AS debit_id, credit_id
+			FROM legacy
+			WHERE position = 1 AND legs = 2 AND min_account_id <> max_account_id
+		),
+		debits AS (
+			UPDATE transactions t
+			SET amount = -t.amount, counterpart_transaction_id = p.credit_id
+			FROM pairs p
+			WHERE t.id = p.debit_id
+		)
+		UPDATE transactions t
+		SET counterpart_transaction_id = p.debit_id
+		FROM pairs p
+		WHERE t.id = p.credit_id
+	`
+
+	result, err := r.db.Exec(query, models.TransactionTransfer)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -14,6 +14,28 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+const userColumns = `id, email, username, password_hash, created_at, updated_at,
+	COALESCE(phone, ''), default_account_id, COALESCE(role, 'client'),
+	COALESCE(timezone, ''), phone_verified_at IS NOT NULL`
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.Phone,
+		&user.DefaultAccountID,
+		&user.Role,
+		&user.Timezone,
+		&user.PhoneVerified,
+	)
+	return user, err
+}
+
 func (r *UserRepository) CreateUser(user *models.User) error {
 	query := `
 		INSERT INTO users (email, username, password_hash)
@@ -37,20 +59,12 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 
 func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, email, username, password_hash, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE email = $1
 	`
 
-	user := &models.User{}
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Username,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.db.QueryRow(query, email))
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -72,3 +86,110 @@ func (r *UserRepository) IsEmailExists(email string) (bool, error) {
 	err := r.db.QueryRow(query, email).Scan(&exists)
 	return exists, err
 }
+
+func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1
+	`
+
+	user, err := scanUser(r.db.QueryRow(query, id))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByPhone ищет клиента по подтвержденному телефону
+func (r *UserRepository) GetUserByPhone(phone string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE phone = $1 AND phone_verified_at IS NOT NULL
+	`
+
+	user, err := scanUser(r.db.QueryRow(query, phone))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return user, err
+}
+
+// IsPhoneTaken проверяет, подтвердил ли телефон другой клиент.
+// Неподтвержденный номер не мешает подтвердить его настоящему владельцу.
+func (r *UserRepository) IsPhoneTaken(phone string, exceptUserID int) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM users WHERE phone = $1 AND id <> $2 AND phone_verified_at IS NOT NULL
+		)
+	`
+
+	var exists bool
+	err := r.db.QueryRow(query, phone, exceptUserID).Scan(&exists)
+	return exists, err
+}
+
+// UpdateP2PSettings сохраняет телефон и счет для зачисления переводов.
+// Пустой телефон сохраняется как NULL. Подтверждение сохраняется, только
+// если телефон не изменился.
+func (r *UserRepository) UpdateP2PSettings(userID int, phone string, defaultAccountID *int) error {
+	query := `
+		UPDATE users
+		SET phone = NULLIF($1, ''),
+			phone_verified_at = CASE WHEN phone = NULLIF($1, '') THEN phone_verified_at END,
+			default_account_id = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+
+	_, err := r.db.Exec(query, phone, defaultAccountID, userID)
+	return err
+}
+
+// SetVerifiedPhone сохраняет подтвержденный телефон клиента. Тот же номер,
+// указанный без подтверждения другими клиентами, у них снимается.
+func (r *UserRepository) SetVerifiedPhone(userID int, phone string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET phone = NULL, updated_at = NOW()
+		WHERE phone = $1 AND id <> $2 AND phone_verified_at IS NULL
+	`, phone, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET phone = $1, phone_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+	`, phone, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserRepository) UpdateTimezone(userID int, timezone string) error {
+	query := `
+		UPDATE users
+		SET timezone = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err := r.db.Exec(query, timezone, userID)
+	return err
+}
@@ -3,23 +3,63 @@ package service
 import (
 	"bank-api/internal/models"
 	"bank-api/internal/repository"
+	"bank-api/pkg/cbr"
+	"crypto/rand"
 	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
 )
 
+// AccountNumbering - реквизиты банка для формирования номеров счетов
+type AccountNumbering struct {
+	BIC                   string
+	BalanceAccount        string
+	SavingsBalanceAccount string
+	Branch                string
+}
+
+// Коды валют в номерах счетов. Для рубля исторически используется 810,
+// а не код 643 из ОКВ.
+var accountCurrencyCodes = map[string]string{
+	"RUB": "810",
+}
+
+// Число попыток подобрать свободный порядковый номер счета
+const accountNumberAttempts = 10
+
 type AccountService struct {
 	accountRepo     *repository.AccountRepository
 	transactionRepo *repository.TransactionRepository
+	creditRepo      *repository.CreditRepository
+	depositRepo     *repository.DepositRepository
+	limitService    *LimitService
+	numbering       AccountNumbering
+	savings         SavingsProduct
 	db              *sql.DB
 }
 
 func NewAccountService(
 	accountRepo *repository.AccountRepository,
 	transactionRepo *repository.TransactionRepository,
+	creditRepo *repository.CreditRepository,
+	depositRepo *repository.DepositRepository,
+	limitService *LimitService,
+	numbering AccountNumbering,
+	savings SavingsProduct,
 	db *sql.DB,
 ) *AccountService {
 	return &AccountService{
 		accountRepo:     accountRepo,
 		transactionRepo: transactionRepo,
+		creditRepo:      creditRepo,
+		depositRepo:     depositRepo,
+		limitService:    limitService,
+		numbering:       numbering,
+		savings:         savings,
 		db:              db,
 	}
 }
@@ -31,8 +71,19 @@ func (s *AccountService) ProcessCreditDeposit(accountID int, amount float64, use
 	}
 	defer tx.Rollback()
 
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, accountID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return ErrAccountNotFound
+	}
+	if err := checkCredit(account); err != nil {
+		return err
+	}
+
 	// Обновляем баланс
-	if err := s.accountRepo.UpdateBalance(accountID, amount); err != nil {
+	if err := s.accountRepo.UpdateBalance(tx, accountID, amount); err != nil {
 		return err
 	}
 
@@ -56,10 +107,41 @@ func (s *AccountService) ProcessCreditDeposit(accountID int, amount float64, use
 }
 
 func (s *AccountService) CreateAccount(userID int, req *models.CreateAccountRequest) (*models.Account, error) {
+	accountType := req.Type
+	if accountType == "" {
+		accountType = models.AccountTypeCurrent
+	}
+
+	balanceAccount := s.numbering.BalanceAccount
+	var rate float64
+	switch accountType {
+	case models.AccountTypeCurrent:
+	case models.AccountTypeSavings:
+		balanceAccount = s.numbering.SavingsBalanceAccount
+
+		var err error
+		if rate, err = s.savings.currentRate(); err != nil {
+			// Ставка обновится при ближайшем начислении процентов
+			log.Printf("Opening savings account with fixed rate: %v", err)
+			rate = s.savings.Rate
+		}
+	default:
+		return nil, ErrInvalidAccountType
+	}
+
+	number, err := s.generateAccountNumber(balanceAccount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	account := &models.Account{
-		UserID:   userID,
-		Balance:  0,
-		Currency: req.Currency,
+		UserID:       userID,
+		Number:       number,
+		Balance:      0,
+		Currency:     req.Currency,
+		Type:         accountType,
+		Status:       models.AccountStatusActive,
+		InterestRate: rate,
 	}
 
 	if err := s.accountRepo.CreateAccount(account); err != nil {
@@ -69,10 +151,52 @@ func (s *AccountService) CreateAccount(userID int, req *models.CreateAccountRequ
 	return account, nil
 }
 
+// BackfillNumbers присваивает номера счетам, открытым до появления номеров.
+// Счета в валюте, для которой нет кода, остаются без номера.
+func (s *AccountService) BackfillNumbers() (int, error) {
+	accounts, err := s.accountRepo.GetAccountsWithoutNumber()
+	if err != nil {
+		return 0, err
+	}
+
+	numbered := 0
+	for _, account := range accounts {
+		balanceAccount := s.numbering.BalanceAccount
+		if account.Type == models.AccountTypeSavings {
+			balanceAccount = s.numbering.SavingsBalanceAccount
+		}
+
+		number, err := s.generateAccountNumber(balanceAccount, account.Currency)
+		if err == ErrUnsupportedCurrency {
+			log.Printf("Account %d is left without number: unsupported currency %q", account.ID, account.Currency)
+			continue
+		}
+		if err != nil {
+			return numbered, err
+		}
+		if err := s.accountRepo.SetNumber(account.ID, number); err != nil {
+			return numbered, err
+		}
+		numbered++
+	}
+	return numbered, nil
+}
+
 func (s *AccountService) GetAccountByID(id int) (*models.Account, error) {
 	return s.accountRepo.GetAccountByID(id)
 }
 
+func (s *AccountService) GetAccountByNumber(number string) (*models.Account, error) {
+	if !cbr.ValidateAccountNumber(s.numbering.BIC, number) {
+		return nil, ErrInvalidAccountNumber
+	}
+	return s.accountRepo.GetAccountByNumber(number)
+}
+
+func (s *AccountService) GetUserAccounts(userID int) ([]*models.Account, error) {
+	return s.accountRepo.GetAccountsByUser(userID)
+}
+
 func (s *AccountService) UpdateBalance(accountID int, amount float64) error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -81,7 +205,7 @@ func (s *AccountService) UpdateBalance(accountID int, amount float64) error {
 	defer tx.Rollback()
 
 	// Проверяем существование счета
-	account, err := s.accountRepo.GetAccountByID(accountID)
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, accountID)
 	if err != nil {
 		return err
 	}
@@ -89,13 +213,20 @@ func (s *AccountService) UpdateBalance(accountID int, amount float64) error {
 		return ErrAccountNotFound
 	}
 
-	// Проверяем достаточность средств при снятии
-	if amount < 0 && account.Balance < -amount {
-		return ErrInsufficientFunds
+	// Проверяем статус счета и достаточность средств при снятии
+	if amount < 0 {
+		if err := checkDebit(account, -amount); err != nil {
+			return err
+		}
+		if err := s.limitService.CheckAccount(tx, account, -amount); err != nil {
+			return err
+		}
+	} else if err := checkCredit(account); err != nil {
+		return err
 	}
 
 	// Обновляем баланс
-	if err := s.accountRepo.UpdateBalance(accountID, amount); err != nil {
+	if err := s.accountRepo.UpdateBalance(tx, accountID, amount); err != nil {
 		return err
 	}
 
@@ -129,57 +260,321 @@ func (s *AccountService) Transfer(req *models.TransferRequest) error {
 	}
 	defer tx.Rollback()
 
+	if err := s.transfer(tx, req); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// transfer проводит перевод в транзакции вызывающего, чтобы тот мог записать
+// вместе с переводом и свои данные
+func (s *AccountService) transfer(tx *sql.Tx, req *models.TransferRequest) error {
+	// Получатель может быть указан номером счета вместо ID
+	if req.ToAccountID == 0 && req.ToAccountNumber != "" {
+		toAccount, err := s.GetAccountByNumber(req.ToAccountNumber)
+		if err != nil {
+			return err
+		}
+		if toAccount == nil {
+			return ErrAccountNotFound
+		}
+		req.ToAccountID = toAccount.ID
+	}
+
 	// Проверяем счета
-	fromAccount, err := s.accountRepo.GetAccountByID(req.FromAccountID)
+	fromAccount, toAccount, err := lockTransferAccounts(tx, s.accountRepo, req.FromAccountID, req.ToAccountID)
 	if err != nil {
 		return err
 	}
-	if fromAccount == nil {
-		return ErrAccountNotFound
+	if err := s.limitService.CheckAccount(tx, fromAccount, req.Amount); err != nil {
+		return err
+	}
+
+	_, err = postTransfer(tx, s.accountRepo, s.transactionRepo, fromAccount, toAccount, req.Amount, req.Description)
+	return err
+}
+
+func (s *AccountService) FreezeAccount(userID, accountID int) error {
+	return s.changeStatus(userID, accountID, models.AccountStatusActive, models.AccountStatusFrozen)
+}
+
+func (s *AccountService) UnfreezeAccount(userID, accountID int) error {
+	return s.changeStatus(userID, accountID, models.AccountStatusFrozen, models.AccountStatusActive)
+}
+
+// CloseAccount закрывает счет. Счет с активными кредитами или открытыми
+// с него вкладами закрыть нельзя; ненулевой остаток переводится на
+// указанный счет того же владельца.
+func (s *AccountService) CloseAccount(userID, accountID int, req *models.CloseAccountRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	toAccount, err := s.accountRepo.GetAccountByID(req.ToAccountID)
+	var account, target *models.Account
+	if req.TransferToAccountID != 0 {
+		account, target, err = lockTransferAccounts(tx, s.accountRepo, accountID, req.TransferToAccountID)
+	} else {
+		account, err = s.accountRepo.GetAccountByIDForUpdate(tx, accountID)
+		if err == nil && account == nil {
+			err = ErrAccountNotFound
+		}
+	}
 	if err != nil {
 		return err
 	}
-	if toAccount == nil {
+
+	if account.UserID != userID || (target != nil && target.UserID != userID) {
 		return ErrAccountNotFound
 	}
+	if err := checkActive(account); err != nil {
+		return err
+	}
 
-	// Проверяем достаточность средств
-	if fromAccount.Balance < req.Amount {
-		return ErrInsufficientFunds
+	// Кредиты и вклады считаются под блокировкой счета, чтобы результат
+	// проверки не устарел к моменту закрытия
+	activeCredits, err := s.creditRepo.CountActiveCreditsByAccount(tx, accountID)
+	if err != nil {
+		return err
+	}
+	if activeCredits > 0 {
+		return ErrAccountHasCredits
+	}
+	activeDeposits, err := s.depositRepo.CountActiveDepositsByAccount(tx, accountID)
+	if err != nil {
+		return err
+	}
+	if activeDeposits > 0 {
+		return ErrAccountHasDeposits
+	}
+	if account.AvailableBalance != account.Balance {
+		return ErrAccountHasHolds
 	}
 
-	// Выполняем перевод
-	if err := s.accountRepo.UpdateBalance(req.FromAccountID, -req.Amount); err != nil {
+	if account.Balance != 0 {
+		if target == nil {
+			return ErrNonZeroBalance
+		}
+		if _, err := postTransfer(tx, s.accountRepo, s.transactionRepo, account, target, account.Balance, "Account closure"); err != nil {
+			return err
+		}
+	}
+
+	if err := s.accountRepo.UpdateStatus(tx, accountID, models.AccountStatusClosed); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResolveAccount находит счет по внешнему идентификатору, который клиент
+// указывает в платежных документах: 20-значному номеру или внутреннему ID.
+// Номер с неверным контрольным ключом считается ненайденным.
+func (s *AccountService) ResolveAccount(identifier string) (*models.Account, error) {
+	identifier = strings.TrimSpace(identifier)
+	if len(identifier) == cbr.AccountNumberLength {
+		account, err := s.GetAccountByNumber(identifier)
+		if errors.Is(err, ErrInvalidAccountNumber) {
+			return nil, nil
+		}
+		return account, err
+	}
+
+	id, err := strconv.Atoi(identifier)
+	if err != nil || id <= 0 {
+		return nil, nil
+	}
+	return s.accountRepo.GetAccountByID(id)
+}
+
+// generateAccountNumber подбирает случайный свободный номер счета. Порядковая
+// часть не связана с ID, чтобы по номеру нельзя было судить о числе счетов.
+func (s *AccountService) generateAccountNumber(balanceAccount, currency string) (string, error) {
+	currencyCode, ok := accountCurrencyCodes[currency]
+	if !ok {
+		return "", ErrUnsupportedCurrency
+	}
+
+	for i := 0; i < accountNumberAttempts; i++ {
+		serial, err := rand.Int(rand.Reader, big.NewInt(10_000_000))
+		if err != nil {
+			return "", err
+		}
+
+		number, err := cbr.BuildAccountNumber(
+			s.numbering.BIC,
+			balanceAccount,
+			currencyCode,
+			s.numbering.Branch,
+			fmt.Sprintf("%07d", serial.Int64()),
+		)
+		if err != nil {
+			return "", err
+		}
+
+		exists, err := s.accountRepo.IsNumberExists(number)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return number, nil
+		}
+	}
+
+	return "", errors.New("failed to allocate account number")
+}
+
+func (s *AccountService) changeStatus(userID, accountID int, from, to models.AccountStatus) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, accountID)
+	if err != nil {
 		return err
 	}
-	if err := s.accountRepo.UpdateBalance(req.ToAccountID, req.Amount); err != nil {
+	if account == nil || account.UserID != userID {
+		return ErrAccountNotFound
+	}
+	if account.Status != from {
+		if err := checkActive(account); err != nil {
+			return err
+		}
+		return ErrAccountNotFrozen
+	}
+
+	if err := s.accountRepo.UpdateStatus(tx, accountID, to); err != nil {
 		return err
 	}
 
-	// Создаем записи о транзакциях
+	return tx.Commit()
+}
+
+// lockTransferAccounts блокирует оба счета перевода в порядке возрастания ID,
+// чтобы встречные переводы не приводили к взаимной блокировке
+func lockTransferAccounts(tx *sql.Tx, accountRepo *repository.AccountRepository, fromID, toID int) (*models.Account, *models.Account, error) {
+	if fromID == toID {
+		return nil, nil, ErrSameAccount
+	}
+
+	firstID, secondID := fromID, toID
+	if firstID > secondID {
+		firstID, secondID = secondID, firstID
+	}
+
+	first, err := accountRepo.GetAccountByIDForUpdate(tx, firstID)
+	if err != nil {
+		return nil, nil, err
+	}
+	second, err := accountRepo.GetAccountByIDForUpdate(tx, secondID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first == nil || second == nil {
+		return nil, nil, ErrAccountNotFound
+	}
+
+	if first.ID == fromID {
+		return first, second, nil
+	}
+	return second, first, nil
+}
+
+// postTransfer проводит перевод между заблокированными счетами внутри
+// транзакции и возвращает запись о списании со счета отправителя
+func postTransfer(
+	tx *sql.Tx,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	fromAccount, toAccount *models.Account,
+	amount float64,
+	description string,
+) (*models.Transaction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	// Проверяем статусы счетов и достаточность средств
+	if err := checkDebit(fromAccount, amount); err != nil {
+		return nil, err
+	}
+	if err := checkCredit(toAccount); err != nil {
+		return nil, err
+	}
+
+	// Выполняем перевод
+	if err := accountRepo.UpdateBalance(tx, fromAccount.ID, -amount); err != nil {
+		return nil, err
+	}
+	if err := accountRepo.UpdateBalance(tx, toAccount.ID, amount); err != nil {
+		return nil, err
+	}
+
+	// Создаем записи о транзакциях: списание со счета отправителя
+	// хранится с отрицательной суммой
 	fromTransaction := &models.Transaction{
-		AccountID:   req.FromAccountID,
-		Amount:      req.Amount,
+		AccountID:   fromAccount.ID,
+		Amount:      -amount,
 		Type:        models.TransactionTransfer,
-		Description: req.Description,
+		Description: description,
 	}
 
 	toTransaction := &models.Transaction{
-		AccountID:   req.ToAccountID,
-		Amount:      req.Amount,
+		AccountID:   toAccount.ID,
+		Amount:      amount,
 		Type:        models.TransactionTransfer,
-		Description: req.Description,
+		Description: description,
 	}
 
-	if err := s.transactionRepo.CreateTransaction(tx, fromTransaction); err != nil {
-		return err
+	if err := transactionRepo.CreateTransaction(tx, fromTransaction); err != nil {
+		return nil, err
+	}
+	toTransaction.CounterpartID = &fromTransaction.ID
+	if err := transactionRepo.CreateTransaction(tx, toTransaction); err != nil {
+		return nil, err
+	}
+
+	// Связываем проводки, чтобы перевод можно было сторнировать целиком
+	if err := transactionRepo.SetCounterpart(tx, fromTransaction.ID, toTransaction.ID); err != nil {
+		return nil, err
+	}
+	fromTransaction.CounterpartID = &toTransaction.ID
+
+	return fromTransaction, nil
+}
+
+// checkActive проверяет, что по счету разрешены расходные операции
+func checkActive(account *models.Account) error {
+	switch account.Status {
+	case models.AccountStatusFrozen:
+		return ErrAccountFrozen
+	case models.AccountStatusClosed:
+		return ErrAccountClosed
 	}
-	if err := s.transactionRepo.CreateTransaction(tx, toTransaction); err != nil {
+	return nil
+}
+
+// checkDebit проверяет возможность списания суммы со счета. Заблокированные
+// средства для новых списаний недоступны.
+func checkDebit(account *models.Account, amount float64) error {
+	if err := checkActive(account); err != nil {
 		return err
 	}
+	if account.AvailableBalance < amount {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
 
-	return tx.Commit()
+// checkCredit проверяет возможность зачисления на счет. Замороженный счет
+// продолжает принимать поступления, закрытый - нет.
+func checkCredit(account *models.Account) error {
+	if account.Status == models.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	return nil
 }
@@ -0,0 +1,438 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/crypto"
+	"bank-api/pkg/pan"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Коды отказа для ошибок, которые возникают при блокировке средств
+var authorizationDeclines = map[error]string{
+	ErrInsufficientFunds:    models.ResponseInsufficientFunds,
+	ErrInvalidAmount:        models.ResponseInvalidAmount,
+	ErrDailyLimitExceeded:   models.ResponseAmountLimit,
+	ErrMonthlyLimitExceeded: models.ResponseAmountLimit,
+	ErrDailyCountExceeded:   models.ResponseCountLimit,
+	ErrMonthlyCountExceeded: models.ResponseCountLimit,
+	ErrAmountCapExceeded:    models.ResponseAmountLimit,
+	ErrCardNotFound:         models.ResponseInvalidCard,
+	ErrCardBlocked:          models.ResponseRestrictedCard,
+	ErrCardExpired:          models.ResponseExpiredCard,
+	ErrCardClosed:           models.ResponseInvalidCard,
+	ErrAccountNotFound:      models.ResponseInvalidCard,
+	ErrAccountFrozen:        models.ResponseRestrictedCard,
+	ErrAccountClosed:        models.ResponseRestrictedCard,
+}
+
+// CardAuthorizationService обрабатывает запросы эквайрера: авторизацию
+// операции по карте с блокировкой средств, расчеты и отмену
+type CardAuthorizationService struct {
+	authRepo           *repository.CardAuthorizationRepository
+	cardRepo           *repository.CardRepository
+	accountRepo        *repository.AccountRepository
+	holdService        *HoldService
+	transactionService *TransactionService
+	pinService         *PINService
+	hmacSecret         string
+}
+
+func NewCardAuthorizationService(
+	authRepo *repository.CardAuthorizationRepository,
+	cardRepo *repository.CardRepository,
+	accountRepo *repository.AccountRepository,
+	holdService *HoldService,
+	transactionService *TransactionService,
+	pinService *PINService,
+	hmacSecret string,
+) *CardAuthorizationService {
+	return &CardAuthorizationService{
+		authRepo:           authRepo,
+		cardRepo:           cardRepo,
+		accountRepo:        accountRepo,
+		holdService:        holdService,
+		transactionService: transactionService,
+		pinService:         pinService,
+		hmacSecret:         hmacSecret,
+	}
+}
+
+// Authorize проверяет реквизиты карты, ее статус, лимиты и доступный остаток
+// и блокирует сумму операции. Отказ не является ошибкой: он возвращается
+// как авторизация со статусом declined и кодом ответа.
+func (s *CardAuthorizationService) Authorize(req *models.AuthorizationRequest) (*models.CardAuthorization, error) {
+	if req.Reference != "" {
+		existing, err := s.authRepo.GetAuthorizationByReference(req.Acquirer, req.Reference)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+
+	auth := &models.CardAuthorization{
+		Amount:       req.Amount,
+		MerchantID:   req.MerchantID,
+		MerchantName: req.MerchantName,
+		MCC:          req.MCC,
+		Reference:    req.Reference,
+		Channel:      req.Channel,
+		Acquirer:     req.Acquirer,
+	}
+	if auth.Acquirer == "" {
+		auth.Acquirer = models.AcquirerAPI
+	}
+	if auth.Channel == "" {
+		auth.Channel = models.ChannelPOS
+	}
+
+	code, ownerID, err := s.authorize(auth, req)
+	if err != nil {
+		return nil, err
+	}
+
+	auth.ResponseCode = code
+	auth.Status = models.AuthorizationDeclined
+	if code == models.ResponseApproved {
+		auth.Status = models.AuthorizationApproved
+		if auth.ApprovalCode, err = randomDigits(6); err != nil {
+			s.releaseHold(ownerID, auth)
+			return nil, err
+		}
+	}
+
+	if err := s.authRepo.CreateAuthorization(auth); err != nil {
+		// Без записи об авторизации эквайрер не сможет провести расчеты,
+		// поэтому блокировку сразу снимаем
+		s.releaseHold(ownerID, auth)
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// GetAuthorization возвращает авторизацию эквайрера; чужие авторизации
+// для него не существуют
+func (s *CardAuthorizationService) GetAuthorization(acquirer string, id int) (*models.CardAuthorization, error) {
+	auth, err := s.authRepo.GetAuthorizationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil || auth.Acquirer != acquirer {
+		return nil, ErrAuthNotFound
+	}
+	return auth, nil
+}
+
+// GetAuthorizationByReference ищет авторизацию по идентификатору операции
+// у эквайрера
+func (s *CardAuthorizationService) GetAuthorizationByReference(acquirer, reference string) (*models.CardAuthorization, error) {
+	auth, err := s.authRepo.GetAuthorizationByReference(acquirer, reference)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		return nil, ErrAuthNotFound
+	}
+	return auth, nil
+}
+
+// Settle проводит расчеты по одобренной авторизации: блокировка
+// превращается в списание со счета карты
+func (s *CardAuthorizationService) Settle(acquirer string, id int, req *models.SettlementRequest) (*models.CardAuthorization, error) {
+	auth, ownerID, err := s.getApproved(acquirer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := s.holdService.CaptureCardHold(ownerID, *auth.HoldID, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	auth.Status = models.AuthorizationSettled
+	auth.TransactionID = hold.TransactionID
+	return s.complete(auth, models.AuthorizationApproved)
+}
+
+// Reverse отменяет авторизацию: до расчетов снимает блокировку, после
+// расчетов сторнирует списание. Отменить можно только авторизацию своего
+// эквайрера.
+func (s *CardAuthorizationService) Reverse(acquirer string, id int) (*models.CardAuthorization, error) {
+	auth, err := s.GetAuthorization(acquirer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.Status == models.AuthorizationSettled && auth.TransactionID != nil {
+		reason := "card payment reversed by acquirer"
+		if _, err := s.transactionService.ReverseCardPayment(*auth.TransactionID, reason); err != nil {
+			return nil, err
+		}
+
+		auth.Status = models.AuthorizationReversed
+		return s.complete(auth, models.AuthorizationSettled)
+	}
+
+	auth, ownerID, err := s.getApproved(acquirer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.holdService.ReleaseCardHold(ownerID, *auth.HoldID); err != nil {
+		return nil, err
+	}
+
+	auth.Status = models.AuthorizationReversed
+	return s.complete(auth, models.AuthorizationApproved)
+}
+
+// authorize выполняет проверки и блокирует средства. Возвращает код ответа
+// и владельца счета карты.
+func (s *CardAuthorizationService) authorize(auth *models.CardAuthorization, req *models.AuthorizationRequest) (string, int, error) {
+	number := pan.Normalize(req.PAN)
+	if !pan.Valid(number) {
+		return models.ResponseInvalidCard, 0, nil
+	}
+	if req.Amount <= 0 || roundKopecks(req.Amount) != req.Amount {
+		return models.ResponseInvalidAmount, 0, nil
+	}
+	if len(req.MCC) != 4 || strings.Trim(req.MCC, "0123456789") != "" || req.MerchantID == "" {
+		return models.ResponseInvalidTxn, 0, nil
+	}
+	switch auth.Channel {
+	case models.ChannelPOS, models.ChannelEcommerce, models.ChannelATM:
+	default:
+		return models.ResponseInvalidTxn, 0, nil
+	}
+
+	card, code, err := s.findCard(number, req.Expiry)
+	if err != nil {
+		return "", 0, err
+	}
+	if card != nil {
+		auth.CardID = &card.ID
+	}
+	if code != "" {
+		return code, 0, nil
+	}
+
+	switch {
+	case req.PIN != "":
+		code, err := s.pinService.VerifyPIN(card.ID, req.PIN)
+		if err != nil {
+			return "", 0, err
+		}
+		if code != models.ResponseApproved {
+			return code, 0, nil
+		}
+	case auth.Channel == models.ChannelATM:
+		return models.ResponseIncorrectPIN, 0, nil
+	case !crypto.VerifyHMAC(req.CVV, card.CVV, s.hmacSecret):
+		return models.ResponseInvalidCVV, 0, nil
+	}
+
+	controls, err := s.cardRepo.GetControls(card.ID)
+	if err != nil {
+		return "", 0, err
+	}
+	if controls != nil {
+		if reason := cardControlDecline(controls, req); reason != "" {
+			auth.DeclineReason = reason
+			if reason == models.DeclineAboveTransactionMax {
+				return models.ResponseAmountLimit, 0, nil
+			}
+			return models.ResponseNotPermitted, 0, nil
+		}
+	}
+
+	account, err := s.accountRepo.GetAccountByID(card.AccountID)
+	if err != nil {
+		return "", 0, err
+	}
+	if account == nil {
+		return models.ResponseInvalidCard, 0, nil
+	}
+
+	hold, err := s.holdService.PlaceCardHold(account.UserID, card.ID, &models.CardHoldRequest{
+		Amount:      req.Amount,
+		Description: authorizationDescription(req),
+	})
+	if err != nil {
+		if code, ok := authorizationDeclines[err]; ok {
+			return code, account.UserID, nil
+		}
+		return "", 0, err
+	}
+	auth.HoldID = &hold.ID
+
+	return models.ResponseApproved, account.UserID, nil
+}
+
+// VerifyPIN проверяет PIN по запросу эквайрера без проведения операции
+func (s *CardAuthorizationService) VerifyPIN(req *models.PINVerificationRequest) (*models.PINVerificationResponse, error) {
+	number := pan.Normalize(req.PAN)
+	if !pan.Valid(number) {
+		return &models.PINVerificationResponse{ResponseCode: models.ResponseInvalidCard}, nil
+	}
+
+	card, code, err := s.findCard(number, req.Expiry)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		if code, err = s.pinService.VerifyPIN(card.ID, req.PIN); err != nil {
+			return nil, err
+		}
+	}
+	return &models.PINVerificationResponse{ResponseCode: code}, nil
+}
+
+// findCard ищет карту по номеру и проверяет ее статус и срок действия.
+// Если операции по карте невозможны, возвращается код отказа.
+func (s *CardAuthorizationService) findCard(number, requestedExpiry string) (*models.Card, string, error) {
+	card, err := s.cardRepo.GetCardByNumberHash(cardNumberHash(number, s.hmacSecret))
+	if err != nil {
+		return nil, "", err
+	}
+	if card == nil {
+		return nil, models.ResponseInvalidCard, nil
+	}
+
+	if code := cardStatusResponse(card); code != "" {
+		return card, code, nil
+	}
+
+	expiry, err := crypto.DecryptPGP(card.ExpiryDate)
+	if err != nil {
+		return nil, "", err
+	}
+	if !checkCardExpiry(expiry, requestedExpiry, time.Now()) {
+		return card, models.ResponseExpiredCard, nil
+	}
+	return card, "", nil
+}
+
+// getApproved возвращает авторизацию, ожидающую расчетов или отмены,
+// и владельца счета карты
+func (s *CardAuthorizationService) getApproved(acquirer string, id int) (*models.CardAuthorization, int, error) {
+	auth, err := s.GetAuthorization(acquirer, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if auth.Status != models.AuthorizationApproved || auth.HoldID == nil || auth.CardID == nil {
+		return nil, 0, ErrAuthNotActive
+	}
+
+	card, err := s.cardRepo.GetCardByID(*auth.CardID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if card == nil {
+		return nil, 0, ErrCardNotFound
+	}
+	account, err := s.accountRepo.GetAccountByID(card.AccountID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if account == nil {
+		return nil, 0, ErrAccountNotFound
+	}
+
+	return auth, account.UserID, nil
+}
+
+func (s *CardAuthorizationService) complete(auth *models.CardAuthorization, from models.AuthorizationStatus) (*models.CardAuthorization, error) {
+	completed, err := s.authRepo.CompleteAuthorization(auth, from)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrAuthNotActive
+	}
+	return auth, nil
+}
+
+func (s *CardAuthorizationService) releaseHold(ownerID int, auth *models.CardAuthorization) {
+	if auth.HoldID == nil {
+		return
+	}
+	if _, err := s.holdService.ReleaseCardHold(ownerID, *auth.HoldID); err != nil {
+		log.Printf("Failed to release hold %d: %v", *auth.HoldID, err)
+	}
+}
+
+// cardStatusResponse возвращает код отказа для карты, по которой операции
+// запрещены, или пустую строку
+func cardStatusResponse(card *models.Card) string {
+	switch card.Status {
+	case models.CardStatusBlocked:
+		if card.BlockReason == models.CardBlockPINAttempts {
+			return models.ResponsePINTriesExceeded
+		}
+		return models.ResponseRestrictedCard
+	case models.CardStatusPermanentlyBlocked:
+		switch card.BlockReason {
+		case models.CardBlockLost:
+			return models.ResponseLostCard
+		case models.CardBlockStolen:
+			return models.ResponseStolenCard
+		}
+		return models.ResponseRestrictedCard
+	case models.CardStatusExpired:
+		return models.ResponseExpiredCard
+	case models.CardStatusClosed:
+		return models.ResponseInvalidCard
+	}
+	return ""
+}
+
+// checkCardExpiry сверяет срок действия из запроса со сроком карты и
+// проверяет, что он не истек. Карта действует до конца указанного месяца.
+func checkCardExpiry(cardExpiry, requested string, now time.Time) bool {
+	cardMonth, cardYear, ok := parseCardExpiry(cardExpiry)
+	if !ok {
+		return false
+	}
+	month, year, ok := parseCardExpiry(requested)
+	if !ok || month != cardMonth || year != cardYear {
+		return false
+	}
+
+	return now.Before(time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, now.Location()))
+}
+
+// parseCardExpiry разбирает срок действия в формате MM/YY или MM/YYYY
+func parseCardExpiry(s string) (int, int, bool) {
+	m, y, found := strings.Cut(strings.TrimSpace(s), "/")
+	if !found || len(m) != 2 || (len(y) != 2 && len(y) != 4) {
+		return 0, 0, false
+	}
+
+	month, err := strconv.Atoi(m)
+	if err != nil || month < 1 || month > 12 {
+		return 0, 0, false
+	}
+	year, err := strconv.Atoi(y)
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(y) == 2 {
+		year += 2000
+	}
+	return month, year, true
+}
+
+func authorizationDescription(req *models.AuthorizationRequest) string {
+	merchant := req.MerchantName
+	if merchant == "" {
+		merchant = req.MerchantID
+	}
+	return fmt.Sprintf("Card payment: %s (MCC %s)", merchant, req.MCC)
+}
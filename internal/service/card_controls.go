@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Страна банка: операции в ТСП других стран считаются зарубежными
+const homeCountry = "RU"
+
+// MCC снятия наличных в банкомате
+const atmCashMCC = 6011
+
+// MCC перевода денег: так проходят переводы с карты на карту
+const moneyTransferMCC = 4829
+
+type mccRange struct {
+	from, to int
+}
+
+// mccGroups - группы MCC, которыми владелец ограничивает операции по карте
+var mccGroups = []struct {
+	name   string
+	ranges []mccRange
+}{
+	{"airlines", []mccRange{{3000, 3350}, {4511, 4511}}},
+	{"car_rental", []mccRange{{3351, 3500}, {7512, 7512}}},
+	{"hotels", []mccRange{{3501, 3999}, {7011, 7011}}},
+	{"travel_agencies", []mccRange{{4722, 4722}}},
+	{"transport", []mccRange{{4111, 4131}, {4789, 4789}}},
+	{"money_transfer", []mccRange{{4829, 4829}}},
+	{"groceries", []mccRange{{5411, 5411}, {5422, 5499}}},
+	{"fuel", []mccRange{{5541, 5542}, {5983, 5983}}},
+	{"restaurants", []mccRange{{5812, 5814}}},
+	{"digital_goods", []mccRange{{5815, 5818}}},
+	{"pharmacy", []mccRange{{5912, 5912}}},
+	{"adult", []mccRange{{5967, 5967}}},
+	{"cash", []mccRange{{6010, 6011}}},
+	{"quasi_cash", []mccRange{{6012, 6012}, {6051, 6051}, {6540, 6540}}},
+	{"gambling", []mccRange{{7800, 7802}, {7995, 7995}}},
+	{"entertainment", []mccRange{{7832, 7832}, {7922, 7922}, {7991, 7994}, {7996, 7999}}},
+	{"medical", []mccRange{{8011, 8099}}},
+}
+
+// GetMCCGroups возвращает группы MCC, доступные для ограничений
+func (s *CardService) GetMCCGroups() []models.MCCGroup {
+	groups := make([]models.MCCGroup, 0, len(mccGroups))
+	for _, g := range mccGroups {
+		group := models.MCCGroup{Name: g.name}
+		for _, r := range g.ranges {
+			if r.from == r.to {
+				group.Ranges = append(group.Ranges, strconv.Itoa(r.from))
+			} else {
+				group.Ranges = append(group.Ranges, fmt.Sprintf("%d-%d", r.from, r.to))
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func (s *CardService) GetCardControls(userID, cardID int) (*models.CardControls, error) {
+	card, err := s.cardRepo.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	if err := s.checkOwner(userID, card); err != nil {
+		return nil, err
+	}
+
+	return s.cardControls(cardID)
+}
+
+func (s *CardService) UpdateCardControls(userID, cardID int, req *models.UpdateCardControlsRequest) (*models.CardControls, error) {
+	controls, err := s.GetCardControls(userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AllowedMCCGroups != nil {
+		controls.AllowedMCCGroups = *req.AllowedMCCGroups
+	}
+	if req.BlockedMCCGroups != nil {
+		controls.BlockedMCCGroups = *req.BlockedMCCGroups
+	}
+	for _, name := range append(slices.Clone(controls.AllowedMCCGroups), controls.BlockedMCCGroups...) {
+		if !isMCCGroup(name) {
+			return nil, ErrUnknownMCCGroup
+		}
+	}
+
+	if req.Ecommerce != nil {
+		controls.Ecommerce = *req.Ecommerce
+	}
+	if req.ATMCash != nil {
+		controls.ATMCash = *req.ATMCash
+	}
+	if req.Contactless != nil {
+		controls.Contactless = *req.Contactless
+	}
+	if req.Foreign != nil {
+		controls.Foreign = *req.Foreign
+	}
+	if req.MaxTransactionAmount != nil {
+		switch {
+		case *req.MaxTransactionAmount < 0:
+			return nil, ErrInvalidAmount
+		case *req.MaxTransactionAmount == 0:
+			controls.MaxTransactionAmount = nil
+		default:
+			controls.MaxTransactionAmount = req.MaxTransactionAmount
+		}
+	}
+
+	if err := s.cardRepo.SetControls(cardID, controls); err != nil {
+		return nil, err
+	}
+	return controls, nil
+}
+
+// cardControls возвращает ограничения карты; если владелец их не задавал,
+// разрешено все
+func (s *CardService) cardControls(cardID int) (*models.CardControls, error) {
+	controls, err := s.cardRepo.GetControls(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if controls == nil {
+		controls = &models.CardControls{
+			Ecommerce:   true,
+			ATMCash:     true,
+			Contactless: true,
+			Foreign:     true,
+		}
+	}
+	if controls.AllowedMCCGroups == nil {
+		controls.AllowedMCCGroups = []string{}
+	}
+	if controls.BlockedMCCGroups == nil {
+		controls.BlockedMCCGroups = []string{}
+	}
+	return controls, nil
+}
+
+// cardControlDecline проверяет операцию по ограничениям карты и возвращает
+// причину отказа или пустую строку
+func cardControlDecline(controls *models.CardControls, req *models.AuthorizationRequest) string {
+	mcc, _ := strconv.Atoi(req.MCC)
+	group := mccGroupOf(mcc)
+
+	switch {
+	case len(controls.AllowedMCCGroups) > 0 && !slices.Contains(controls.AllowedMCCGroups, group):
+		return models.DeclineMCCNotAllowed
+	case group != "" && slices.Contains(controls.BlockedMCCGroups, group):
+		return models.DeclineMCCBlocked
+	case req.Channel == models.ChannelEcommerce && !controls.Ecommerce:
+		return models.DeclineEcommerceDisabled
+	case (req.Channel == models.ChannelATM || mcc == atmCashMCC) && !controls.ATMCash:
+		return models.DeclineATMDisabled
+	case req.Contactless && !controls.Contactless:
+		return models.DeclineContactlessDisabled
+	case req.MerchantCountry != "" && req.MerchantCountry != homeCountry && !controls.Foreign:
+		return models.DeclineForeignDisabled
+	case controls.MaxTransactionAmount != nil && req.Amount > *controls.MaxTransactionAmount:
+		return models.DeclineAboveTransactionMax
+	}
+	return ""
+}
+
+// mccGroupOf возвращает группу MCC или пустую строку, если код не входит
+// ни в одну группу
+func mccGroupOf(mcc int) string {
+	for _, g := range mccGroups {
+		for _, r := range g.ranges {
+			if mcc >= r.from && mcc <= r.to {
+				return g.name
+			}
+		}
+	}
+	return ""
+}
+
+func isMCCGroup(name string) bool {
+	for _, g := range mccGroups {
+		if g.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// CardExpiryService следит за сроком действия карт: заранее выпускает
+// пластиковым картам замену и переводит карты с истекшим сроком в статус
+// expired
+type CardExpiryService struct {
+	cardRepo        *repository.CardRepository
+	accountRepo     *repository.AccountRepository
+	userRepo        *repository.UserRepository
+	cardService     *CardService
+	notificationSvc *NotificationService
+	// За сколько дней до окончания срока выпускается новая карта
+	renewalDays int
+	db          *sql.DB
+}
+
+func NewCardExpiryService(
+	cardRepo *repository.CardRepository,
+	accountRepo *repository.AccountRepository,
+	userRepo *repository.UserRepository,
+	cardService *CardService,
+	notificationSvc *NotificationService,
+	renewalDays int,
+	db *sql.DB,
+) *CardExpiryService {
+	return &CardExpiryService{
+		cardRepo:        cardRepo,
+		accountRepo:     accountRepo,
+		userRepo:        userRepo,
+		cardService:     cardService,
+		notificationSvc: notificationSvc,
+		renewalDays:     renewalDays,
+		db:              db,
+	}
+}
+
+// ProcessCards выпускает замену картам с истекающим сроком и закрывает
+// операции по картам с истекшим. Ошибка по одной карте не останавливает
+// обработку остальных.
+func (s *CardExpiryService) ProcessCards(now time.Time) error {
+	renewals, err := s.cardRepo.GetCardsForRenewal(now.AddDate(0, 0, s.renewalDays))
+	if err != nil {
+		return err
+	}
+	for _, card := range renewals {
+		if err := s.renewCard(card.ID, now); err != nil {
+			log.Printf("Failed to renew card %d: %v", card.ID, err)
+		}
+	}
+
+	expired, err := s.cardRepo.GetExpiredCards(now)
+	if err != nil {
+		return err
+	}
+	for _, card := range expired {
+		if err := s.expireCard(card.ID, now); err != nil {
+			log.Printf("Failed to expire card %d: %v", card.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// renewCard выпускает новую карту на тот же счет. Прежняя карта действует
+// до окончания своего срока.
+func (s *CardExpiryService) renewCard(cardID int, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old, err := s.cardRepo.GetCardByIDForUpdate(tx, cardID)
+	if err != nil {
+		return err
+	}
+	if old == nil || old.ExpiresAt == nil || old.ExpiresAt.After(now.AddDate(0, 0, s.renewalDays)) {
+		return nil
+	}
+	if old.Status != models.CardStatusActive && old.Status != models.CardStatusBlocked {
+		return nil
+	}
+	reissued, err := s.cardRepo.IsReissued(tx, old.ID)
+	if err != nil {
+		return err
+	}
+	if reissued {
+		return nil
+	}
+
+	// По закрытому или замороженному счету новую карту не выпускаем
+	account, err := s.accountRepo.GetAccountByID(old.AccountID)
+	if err != nil {
+		return err
+	}
+	if account == nil || checkActive(account) != nil {
+		return nil
+	}
+
+	card, details, err := s.cardService.issueReplacement(tx, account.UserID, old, "renewed")
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	revealIssuedCard(card, details)
+	s.notify(account.UserID, func(email string) error {
+		return s.notificationSvc.SendCardRenewalNotification(email, card)
+	})
+	return nil
+}
+
+func (s *CardExpiryService) expireCard(cardID int, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	card, err := s.cardRepo.GetCardByIDForUpdate(tx, cardID)
+	if err != nil {
+		return err
+	}
+	if card == nil || card.ExpiresAt == nil || card.ExpiresAt.After(now) {
+		return nil
+	}
+	if card.Status != models.CardStatusActive && card.Status != models.CardStatusBlocked {
+		return nil
+	}
+
+	account, err := s.accountRepo.GetAccountByID(card.AccountID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return ErrAccountNotFound
+	}
+
+	if err := s.cardService.setStatus(tx, account.UserID, card, models.CardStatusExpired, "", "expired"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := maskStoredCard(card); err != nil {
+		return err
+	}
+	s.notify(account.UserID, func(email string) error {
+		return s.notificationSvc.SendCardExpiredNotification(email, card)
+	})
+	return nil
+}
+
+func (s *CardExpiryService) notify(userID int, send func(email string) error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil || user == nil {
+		log.Printf("Failed to find user %d for card notification: %v", userID, err)
+		return
+	}
+
+	if err := send(user.Email); err != nil {
+		log.Printf("Failed to send card notification: %v", err)
+	}
+}
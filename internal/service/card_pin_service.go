@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/crypto"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PINPolicy - ключ для хеширования PIN и число неверных попыток ввода
+// подряд, после которого карта блокируется. Ключ должен отличаться от
+// ключа хеширования CVV.
+type PINPolicy struct {
+	Secret      string
+	MaxAttempts int
+}
+
+// PINService устанавливает и проверяет PIN карт. PIN хранится только в виде
+// ключевого хеша, привязанного к карте.
+type PINService struct {
+	cardRepo    *repository.CardRepository
+	accountRepo *repository.AccountRepository
+	userRepo    *repository.UserRepository
+	cardService *CardService
+	policy      PINPolicy
+	db          *sql.DB
+}
+
+func NewPINService(
+	cardRepo *repository.CardRepository,
+	accountRepo *repository.AccountRepository,
+	userRepo *repository.UserRepository,
+	cardService *CardService,
+	policy PINPolicy,
+	db *sql.DB,
+) *PINService {
+	return &PINService{
+		cardRepo:    cardRepo,
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		cardService: cardService,
+		policy:      policy,
+		db:          db,
+	}
+}
+
+// SetPIN устанавливает PIN карты, у которой его еще нет
+func (s *PINService) SetPIN(userID, cardID int, req *models.SetPINRequest) error {
+	if err := validatePIN(req.PIN); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	card, err := s.cardService.getOwnCardForUpdate(tx, userID, cardID)
+	if err != nil {
+		return err
+	}
+	if err := checkCardActive(card); err != nil {
+		return err
+	}
+
+	existing, err := s.cardRepo.GetPIN(tx, card.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrPINAlreadySet
+	}
+
+	if err := s.cardRepo.SetPIN(tx, card.ID, s.pinHash(card.ID, req.PIN)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ChangePIN заменяет PIN карты после повторной проверки пароля владельца.
+// Счетчик неверных попыток сбрасывается; карту, заблокированную после
+// исчерпания попыток, владелец разблокирует отдельно.
+func (s *PINService) ChangePIN(userID, cardID int, req *models.ChangePINRequest) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return ErrInvalidCredentials
+	}
+	if err := validatePIN(req.PIN); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	card, err := s.cardService.getOwnCardForUpdate(tx, userID, cardID)
+	if err != nil {
+		return err
+	}
+	if card.Status != models.CardStatusBlocked {
+		if err := checkCardActive(card); err != nil {
+			return err
+		}
+	}
+
+	if err := s.cardRepo.SetPIN(tx, card.ID, s.pinHash(card.ID, req.PIN)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// VerifyPIN сверяет введенный PIN и возвращает код ответа. Неверный PIN
+// увеличивает счетчик попыток; когда попытки исчерпаны, карта блокируется.
+func (s *PINService) VerifyPIN(cardID int, pin string) (string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	card, err := s.cardRepo.GetCardByIDForUpdate(tx, cardID)
+	if err != nil {
+		return "", err
+	}
+	if card == nil {
+		return models.ResponseInvalidCard, nil
+	}
+	if code := cardStatusResponse(card); code != "" {
+		return code, nil
+	}
+
+	stored, err := s.cardRepo.GetPIN(tx, card.ID)
+	if err != nil {
+		return "", err
+	}
+	if stored == nil {
+		return models.ResponseIncorrectPIN, nil
+	}
+
+	if crypto.VerifyHMAC(pinData(card.ID, pin), stored.Hash, s.policy.Secret) {
+		if stored.FailedAttempts > 0 {
+			if err := s.cardRepo.SetPINAttempts(tx, card.ID, 0); err != nil {
+				return "", err
+			}
+		}
+		return models.ResponseApproved, tx.Commit()
+	}
+
+	attempts := stored.FailedAttempts + 1
+	if err := s.cardRepo.SetPINAttempts(tx, card.ID, attempts); err != nil {
+		return "", err
+	}
+
+	code := models.ResponseIncorrectPIN
+	if attempts >= s.policy.MaxAttempts {
+		account, err := s.accountRepo.GetAccountByID(card.AccountID)
+		if err != nil {
+			return "", err
+		}
+		if account == nil {
+			return "", ErrAccountNotFound
+		}
+		err = s.cardService.setStatus(
+			tx,
+			account.UserID,
+			card,
+			models.CardStatusBlocked,
+			models.CardBlockPINAttempts,
+			string(models.CardBlockPINAttempts),
+		)
+		if err != nil {
+			return "", err
+		}
+		code = models.ResponsePINTriesExceeded
+	}
+
+	return code, tx.Commit()
+}
+
+func (s *PINService) pinHash(cardID int, pin string) string {
+	return crypto.GenerateHMAC(pinData(cardID, pin), s.policy.Secret)
+}
+
+// pinData привязывает хеш к карте, чтобы одинаковые PIN разных карт не
+// совпадали
+func pinData(cardID int, pin string) string {
+	return fmt.Sprintf("pin:%d:%s", cardID, pin)
+}
+
+// validatePIN проверяет, что PIN состоит из 4-6 цифр и не угадывается
+// сразу: все цифры одинаковые или идут подряд
+func validatePIN(pin string) error {
+	if len(pin) < 4 || len(pin) > 6 || strings.Trim(pin, "0123456789") != "" {
+		return ErrInvalidPIN
+	}
+
+	same, ascending, descending := true, true, true
+	for i := 1; i < len(pin); i++ {
+		diff := int(pin[i]) - int(pin[i-1])
+		same = same && diff == 0
+		ascending = ascending && diff == 1
+		descending = descending && diff == -1
+	}
+	if same || ascending || descending {
+		return ErrWeakPIN
+	}
+	return nil
+}
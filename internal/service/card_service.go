@@ -4,28 +4,95 @@ import (
 	"bank-api/internal/models"
 	"bank-api/internal/repository"
 	"bank-api/pkg/crypto"
+	"bank-api/pkg/pan"
+	"crypto/rand"
+	"database/sql"
 	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
+	"slices"
+	"strings"
 	"time"
 )
 
+// Число попыток подобрать еще не выпущенный номер карты
+const cardNumberAttempts = 10
+
+// Продукт по умолчанию, если клиент не выбрал платежную систему
+const defaultCardProduct = models.CardMir
+
+// Срок действия карты в месяцах: по умолчанию и наибольший по выбору клиента
+const (
+	defaultCardValidityMonths = 36
+	maxCardValidityMonths     = 60
+)
+
+// cardDetails - реквизиты выпущенной карты в открытом виде
+type cardDetails struct {
+	number string
+	expiry string
+	cvv    string
+}
+
 type CardService struct {
-	cardRepo    *repository.CardRepository
-	accountRepo *repository.AccountRepository
-	hmacSecret  string
+	cardRepo     *repository.CardRepository
+	accountRepo  *repository.AccountRepository
+	limitRepo    *repository.LimitRepository
+	limitService *LimitService
+	hmacSecret   string
+	binRanges    map[models.CardProduct][]pan.BINRange
+	db           *sql.DB
 }
 
 func NewCardService(
 	cardRepo *repository.CardRepository,
 	accountRepo *repository.AccountRepository,
+	limitRepo *repository.LimitRepository,
+	limitService *LimitService,
 	hmacSecret string,
+	binRanges map[models.CardProduct][]pan.BINRange,
+	db *sql.DB,
 ) *CardService {
 	return &CardService{
-		cardRepo:    cardRepo,
-		accountRepo: accountRepo,
-		hmacSecret:  hmacSecret,
+		cardRepo:     cardRepo,
+		accountRepo:  accountRepo,
+		limitRepo:    limitRepo,
+		limitService: limitService,
+		hmacSecret:   hmacSecret,
+		binRanges:    binRanges,
+		db:           db,
+	}
+}
+
+// ParseBINRanges разбирает диапазоны BIN продуктов в формате
+// "mir=220000-220099;mastercard=510000-510099,222100-222199"
+func ParseBINRanges(spec string) (map[models.CardProduct][]pan.BINRange, error) {
+	ranges := make(map[models.CardProduct][]pan.BINRange)
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		name, list, found := strings.Cut(entry, "=")
+		product := models.CardProduct(strings.ToLower(strings.TrimSpace(name)))
+		switch product {
+		case models.CardMir, models.CardVisa, models.CardMastercard:
+		default:
+			return nil, fmt.Errorf("unknown card product %q", name)
+		}
+		if !found {
+			return nil, fmt.Errorf("no BIN ranges for card product %q", name)
+		}
+
+		for _, item := range strings.Split(list, ",") {
+			r, err := pan.ParseBINRange(item)
+			if err != nil {
+				return nil, err
+			}
+			ranges[product] = append(ranges[product], r)
+		}
 	}
+	return ranges, nil
 }
 
 func (s *CardService) CreateCard(userID int, req *models.CreateCardRequest) (*models.Card, error) {
@@ -37,44 +104,105 @@ func (s *CardService) CreateCard(userID int, req *models.CreateCardRequest) (*mo
 	if account == nil || account.UserID != userID {
 		return nil, errors.New("account not found or access denied")
 	}
+	if err := checkActive(account); err != nil {
+		return nil, err
+	}
 
-	// Генерация данных карты
-	cardNumber := generateCardNumber()
-	expiryDate := generateExpiryDate()
-	cvv := generateCVV()
+	product := req.Product
+	if product == "" {
+		product = defaultCardProduct
+	}
+	cardType := req.Type
+	switch cardType {
+	case "":
+		cardType = models.CardTypePhysical
+	case models.CardTypePhysical, models.CardTypeVirtual, models.CardTypeSingleUse:
+	default:
+		return nil, ErrInvalidCardType
+	}
+	if req.AmountCap != nil && *req.AmountCap <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	validity := req.ValidityMonths
+	if validity == 0 {
+		validity = defaultCardValidityMonths
+	}
+	if validity < 0 || validity > maxCardValidityMonths {
+		return nil, ErrInvalidCardValidity
+	}
 
-	// Шифрование данных
-	encryptedNumber, err := crypto.EncryptPGP(cardNumber)
+	card, details, err := s.newCard(req.AccountID, product, validity)
 	if err != nil {
 		return nil, err
 	}
+	card.Type = cardType
+	card.AmountCap = req.AmountCap
 
-	encryptedExpiry, err := crypto.EncryptPGP(expiryDate)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	cvvHash := crypto.GenerateHMAC(cvv, s.hmacSecret)
-
-	card := &models.Card{
-		AccountID:  req.AccountID,
-		Number:     encryptedNumber,
-		ExpiryDate: encryptedExpiry,
-		CVV:        cvvHash,
+	if err := s.insertCard(tx, card, details); err != nil {
+		return nil, err
 	}
-
-	if err := s.cardRepo.CreateCard(card); err != nil {
+	if err := s.recordStatus(tx, userID, card.ID, "", card.Status, "issued"); err != nil {
 		return nil, err
 	}
 
-	// Возвращаем карту с частично скрытыми данными
-	card.Number = fmt.Sprintf("**** **** **** %s", cardNumber[len(cardNumber)-4:])
-	card.ExpiryDate = expiryDate
-	card.CVV = "***"
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
+	revealIssuedCard(card, details)
 	return card, nil
 }
 
+// BackfillNumberHashes вычисляет хеш номера для карт, выпущенных до его
+// появления, чтобы их можно было найти по номеру
+func (s *CardService) BackfillNumberHashes() (int, error) {
+	cards, err := s.cardRepo.GetCardsWithoutNumberHash()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, card := range cards {
+		number, err := crypto.DecryptPGP(card.Number)
+		if err != nil {
+			return i, err
+		}
+		if err := s.cardRepo.SetNumberHash(card.ID, cardNumberHash(number, s.hmacSecret)); err != nil {
+			return i, err
+		}
+	}
+	return len(cards), nil
+}
+
+// BackfillExpiresAt сохраняет открыто срок действия карт, для которых он
+// хранится только в зашифрованном виде
+func (s *CardService) BackfillExpiresAt() (int, error) {
+	cards, err := s.cardRepo.GetCardsWithoutExpiresAt()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, card := range cards {
+		expiry, err := crypto.DecryptPGP(card.ExpiryDate)
+		if err != nil {
+			return i, err
+		}
+		month, year, ok := parseCardExpiry(expiry)
+		if !ok {
+			return i, fmt.Errorf("card %d has invalid expiry date", card.ID)
+		}
+		if err := s.cardRepo.SetExpiresAt(card.ID, cardExpiresAt(month, year)); err != nil {
+			return i, err
+		}
+	}
+	return len(cards), nil
+}
+
 func (s *CardService) GetCard(userID, cardID int) (*models.Card, error) {
 	card, err := s.cardRepo.GetCardByID(cardID)
 	if err != nil {
@@ -110,24 +238,536 @@ func (s *CardService) GetCard(userID, cardID int) (*models.Card, error) {
 	return card, nil
 }
 
-// Вспомогательные функции генерации данных карты
-func generateCardNumber() string {
-	rand.Seed(time.Now().UnixNano())
-	number := "4" // Visa-подобные карты
-	for i := 0; i < 15; i++ {
-		number += fmt.Sprintf("%d", rand.Intn(10))
+// GetAccountCards возвращает карты счета клиента с маскированными номерами
+func (s *CardService) GetAccountCards(userID, accountID int) ([]models.CardResponse, error) {
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
 	}
-	return number
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	cards, err := s.cardRepo.GetCardsByAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.cardResponses(cards)
+}
+
+// GetUserCards возвращает карты всех счетов клиента. Пустой статус или вид
+// карты не ограничивает выборку.
+func (s *CardService) GetUserCards(userID int, status models.CardStatus, cardType models.CardType) ([]models.CardResponse, error) {
+	cards, err := s.cardRepo.GetCardsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cards = slices.DeleteFunc(cards, func(card *models.Card) bool {
+		return (status != "" && card.Status != status) || (cardType != "" && card.Type != cardType)
+	})
+	return s.cardResponses(cards)
+}
+
+// BlockCard блокирует карту. Временную блокировку владелец может снять,
+// блокировка при утере или краже необратима.
+func (s *CardService) BlockCard(userID, cardID int, req *models.BlockCardRequest) (*models.Card, error) {
+	switch req.Reason {
+	case "", models.CardBlockTemporary:
+		return s.changeStatus(
+			userID,
+			cardID,
+			[]models.CardStatus{models.CardStatusActive},
+			models.CardStatusBlocked,
+			models.CardBlockTemporary,
+		)
+	case models.CardBlockLost, models.CardBlockStolen:
+		return s.changeStatus(
+			userID,
+			cardID,
+			[]models.CardStatus{models.CardStatusActive, models.CardStatusBlocked},
+			models.CardStatusPermanentlyBlocked,
+			req.Reason,
+		)
+	}
+	return nil, ErrInvalidBlockReason
+}
+
+// UnblockCard снимает временную блокировку
+func (s *CardService) UnblockCard(userID, cardID int) (*models.Card, error) {
+	return s.changeStatus(
+		userID,
+		cardID,
+		[]models.CardStatus{models.CardStatusBlocked},
+		models.CardStatusActive,
+		"",
+	)
+}
+
+func (s *CardService) CloseCard(userID, cardID int) (*models.Card, error) {
+	return s.changeStatus(
+		userID,
+		cardID,
+		[]models.CardStatus{
+			models.CardStatusActive,
+			models.CardStatusBlocked,
+			models.CardStatusPermanentlyBlocked,
+			models.CardStatusExpired,
+		},
+		models.CardStatusClosed,
+		"",
+	)
 }
 
-func generateExpiryDate() string {
+// ReissueCard выпускает карту того же продукта с новым номером взамен
+// указанной и переносит на нее лимиты и ограничения. Прежняя карта
+// закрывается; карта, заблокированная при утере или краже, остается
+// заблокированной.
+func (s *CardService) ReissueCard(userID, cardID int) (*models.Card, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	old, err := s.getOwnCardForUpdate(tx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if old.Status == models.CardStatusClosed {
+		return nil, ErrCardClosed
+	}
+	reissued, err := s.cardRepo.IsReissued(tx, old.ID)
+	if err != nil {
+		return nil, err
+	}
+	if reissued {
+		return nil, ErrCardAlreadyReissued
+	}
+
+	account, err := s.accountRepo.GetAccountByID(old.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkActive(account); err != nil {
+		return nil, err
+	}
+
+	card, details, err := s.issueReplacement(tx, userID, old, "reissued")
+	if err != nil {
+		return nil, err
+	}
+
+	if old.Status != models.CardStatusPermanentlyBlocked {
+		if err := s.setStatus(tx, userID, old, models.CardStatusClosed, "", "reissued"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	revealIssuedCard(card, details)
+	return card, nil
+}
+
+// issueReplacement выпускает карту взамен указанной на тот же счет и
+// переносит на нее лимиты и ограничения
+func (s *CardService) issueReplacement(tx *sql.Tx, userID int, old *models.Card, note string) (*models.Card, *cardDetails, error) {
+	card, details, err := s.newCard(old.AccountID, old.Product, defaultCardValidityMonths)
+	if err != nil {
+		return nil, nil, err
+	}
+	card.ReissuedFromID = &old.ID
+	card.Type = old.Type
+	card.AmountCap = old.AmountCap
+
+	if err := s.insertCard(tx, card, details); err != nil {
+		return nil, nil, err
+	}
+	if err := s.recordStatus(tx, userID, card.ID, "", card.Status, note); err != nil {
+		return nil, nil, err
+	}
+	if err := s.limitRepo.CopyCardLimits(tx, old.ID, card.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.cardRepo.CopyControls(tx, old.ID, card.ID); err != nil {
+		return nil, nil, err
+	}
+	return card, details, nil
+}
+
+// UpdateAmountCap задает или снимает предельную сумму операций по карте
+func (s *CardService) UpdateAmountCap(userID, cardID int, req *models.UpdateAmountCapRequest) (*models.Card, error) {
+	if req.AmountCap != nil && *req.AmountCap <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	card, err := s.cardRepo.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	if err := s.checkOwner(userID, card); err != nil {
+		return nil, err
+	}
+	if card.Status == models.CardStatusClosed {
+		return nil, ErrCardClosed
+	}
+
+	if err := s.cardRepo.SetAmountCap(card.ID, req.AmountCap); err != nil {
+		return nil, err
+	}
+	card.AmountCap = req.AmountCap
+
+	if err := maskStoredCard(card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// GetCardHistory возвращает журнал изменений статуса карты
+func (s *CardService) GetCardHistory(userID, cardID int) ([]*models.CardStatusChange, error) {
+	card, err := s.cardRepo.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	if err := s.checkOwner(userID, card); err != nil {
+		return nil, err
+	}
+
+	return s.cardRepo.GetStatusHistory(cardID)
+}
+
+func (s *CardService) changeStatus(
+	userID, cardID int,
+	from []models.CardStatus,
+	to models.CardStatus,
+	reason models.CardBlockReason,
+) (*models.Card, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	card, err := s.getOwnCardForUpdate(tx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(from, card.Status) {
+		if err := checkCardActive(card); err != nil {
+			return nil, err
+		}
+		return nil, ErrCardNotBlocked
+	}
+
+	if err := s.setStatus(tx, userID, card, to, reason, string(reason)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if err := maskStoredCard(card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// setStatus меняет статус карты и записывает изменение в журнал
+func (s *CardService) setStatus(
+	tx *sql.Tx,
+	userID int,
+	card *models.Card,
+	to models.CardStatus,
+	reason models.CardBlockReason,
+	note string,
+) error {
+	if err := s.cardRepo.UpdateStatus(tx, card.ID, to, reason); err != nil {
+		return err
+	}
+	if err := s.recordStatus(tx, userID, card.ID, card.Status, to, note); err != nil {
+		return err
+	}
+	// После разблокировки держателю снова доступны все попытки ввода PIN
+	if to == models.CardStatusActive {
+		if err := s.cardRepo.SetPINAttempts(tx, card.ID, 0); err != nil {
+			return err
+		}
+	}
+
+	card.Status = to
+	card.BlockReason = reason
+	return nil
+}
+
+func (s *CardService) recordStatus(tx *sql.Tx, userID, cardID int, from, to models.CardStatus, note string) error {
+	return s.cardRepo.AddStatusChange(tx, &models.CardStatusChange{
+		CardID:     cardID,
+		UserID:     userID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     note,
+	})
+}
+
+func (s *CardService) getOwnCardForUpdate(tx *sql.Tx, userID, cardID int) (*models.Card, error) {
+	card, err := s.cardRepo.GetCardByIDForUpdate(tx, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	if err := s.checkOwner(userID, card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (s *CardService) checkOwner(userID int, card *models.Card) error {
+	account, err := s.accountRepo.GetAccountByID(card.AccountID)
+	if err != nil {
+		return err
+	}
+	if account == nil || account.UserID != userID {
+		return ErrCardNotFound
+	}
+	return nil
+}
+
+// newCard генерирует реквизиты карты и шифрует их для хранения. Кроме карты
+// возвращаются ее реквизиты в открытом виде.
+func (s *CardService) newCard(accountID int, product models.CardProduct, validityMonths int) (*models.Card, *cardDetails, error) {
+	cardNumber, numberHash, err := s.generateCardNumber(product)
+	if err != nil {
+		return nil, nil, err
+	}
+	expiryDate, expiresAt := generateExpiryDate(validityMonths)
+	cvv, err := generateCVV()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Шифрование данных
+	encryptedNumber, err := crypto.EncryptPGP(cardNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryptedExpiry, err := crypto.EncryptPGP(expiryDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	card := &models.Card{
+		AccountID:  accountID,
+		Number:     encryptedNumber,
+		ExpiryDate: encryptedExpiry,
+		CVV:        crypto.GenerateHMAC(cvv, s.hmacSecret),
+		Product:    product,
+		NumberHash: numberHash,
+		Status:     models.CardStatusActive,
+		ExpiresAt:  &expiresAt,
+	}
+	return card, &cardDetails{number: cardNumber, expiry: expiryDate, cvv: cvv}, nil
+}
+
+// insertCard сохраняет новую карту. Проверка в generateCardNumber не
+// исключает, что тот же номер одновременно выпускается другим запросом,
+// поэтому номер, занятый к моменту записи, заменяется новым.
+func (s *CardService) insertCard(tx *sql.Tx, card *models.Card, details *cardDetails) error {
+	for i := 0; i < cardNumberAttempts; i++ {
+		created, err := s.cardRepo.CreateCard(tx, card)
+		if err != nil || created {
+			return err
+		}
+
+		number, hash, err := s.generateCardNumber(card.Product)
+		if err != nil {
+			return err
+		}
+		encrypted, err := crypto.EncryptPGP(number)
+		if err != nil {
+			return err
+		}
+		card.Number, card.NumberHash = encrypted, hash
+		details.number = number
+	}
+
+	return errors.New("failed to allocate card number")
+}
+
+// revealIssuedCard подставляет в выпущенную карту реквизиты для владельца:
+// виртуальной картой пользуются сразу, поэтому ее реквизиты показываются
+// полностью, у пластиковой - частично скрытыми
+func revealIssuedCard(card *models.Card, details *cardDetails) {
+	card.ExpiryDate = details.expiry
+	if card.Type == models.CardTypePhysical {
+		card.Number = maskCardNumber(details.number)
+		card.CVV = "***"
+		return
+	}
+	card.Number = details.number
+	card.CVV = details.cvv
+}
+
+// cardResponses формирует список карт без полных реквизитов, с действующими
+// лимитами каждой карты
+func (s *CardService) cardResponses(cards []*models.Card) ([]models.CardResponse, error) {
+	responses := make([]models.CardResponse, 0, len(cards))
+	for _, card := range cards {
+		if err := maskStoredCard(card); err != nil {
+			return nil, err
+		}
+		limits, err := s.limitService.CardLimits(card.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, models.CardResponse{
+			ID:             card.ID,
+			AccountID:      card.AccountID,
+			LastFour:       card.Number[len(card.Number)-4:],
+			ExpiryDate:     card.ExpiryDate,
+			CreatedAt:      card.CreatedAt,
+			Product:        card.Product,
+			Status:         card.Status,
+			ReissuedFromID: card.ReissuedFromID,
+			Type:           card.Type,
+			AmountCap:      card.AmountCap,
+			Limits:         limits,
+		})
+	}
+	return responses, nil
+}
+
+// maskStoredCard расшифровывает срок действия карты и заменяет номер
+// частично скрытым
+func maskStoredCard(card *models.Card) error {
+	number, err := crypto.DecryptPGP(card.Number)
+	if err != nil {
+		return err
+	}
+	expiry, err := crypto.DecryptPGP(card.ExpiryDate)
+	if err != nil {
+		return err
+	}
+	card.Number = maskCardNumber(number)
+	card.ExpiryDate = expiry
+	return nil
+}
+
+// checkCardActive проверяет, что по карте можно проводить операции
+func checkCardActive(card *models.Card) error {
+	switch card.Status {
+	case models.CardStatusBlocked, models.CardStatusPermanentlyBlocked:
+		return ErrCardBlocked
+	case models.CardStatusExpired:
+		return ErrCardExpired
+	case models.CardStatusClosed:
+		return ErrCardClosed
+	}
+	return nil
+}
+
+func maskCardNumber(number string) string {
+	return fmt.Sprintf("**** **** **** %s", number[len(number)-4:])
+}
+
+// generateCardNumber выпускает номер из случайного BIN продукта со случайной
+// индивидуальной частью. Номер хранится зашифрованным, поэтому занятость
+// номера проверяется по его ключевому хешу.
+func (s *CardService) generateCardNumber(product models.CardProduct) (string, string, error) {
+	ranges := s.binRanges[product]
+	total := 0
+	for _, r := range ranges {
+		total += r.Size()
+	}
+	if total == 0 {
+		return "", "", ErrUnsupportedProduct
+	}
+
+	for i := 0; i < cardNumberAttempts; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(total)))
+		if err != nil {
+			return "", "", err
+		}
+		bin := pickBIN(ranges, int(n.Int64()))
+
+		digits, err := randomDigits(pan.Length - pan.BINLength - 1)
+		if err != nil {
+			return "", "", err
+		}
+
+		number, err := pan.Build(fmt.Sprintf("%06d", bin) + digits)
+		if err != nil {
+			return "", "", err
+		}
+
+		hash := cardNumberHash(number, s.hmacSecret)
+		exists, err := s.cardRepo.IsNumberHashExists(hash)
+		if err != nil {
+			return "", "", err
+		}
+		if !exists {
+			return number, hash, nil
+		}
+	}
+
+	return "", "", errors.New("failed to allocate card number")
+}
+
+// cardNumberHash возвращает ключевой хеш номера карты для поиска и проверки
+// уникальности без расшифровки
+func cardNumberHash(number, secret string) string {
+	return crypto.GenerateHMAC("pan:"+number, secret)
+}
+
+// pickBIN возвращает n-й по счету BIN из набора диапазонов
+func pickBIN(ranges []pan.BINRange, n int) int {
+	for _, r := range ranges {
+		if n < r.Size() {
+			return r.From + n
+		}
+		n -= r.Size()
+	}
+	return ranges[len(ranges)-1].To
+}
+
+func randomDigits(count int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(count)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", count, n), nil
+}
+
+// generateExpiryDate возвращает срок действия для карты в формате MM/YYYY
+// и момент его окончания
+func generateExpiryDate(validityMonths int) (string, time.Time) {
 	now := time.Now()
-	return fmt.Sprintf("%02d/%02d", now.Month(), now.Year()+3)
+	expiry := time.Date(now.Year(), now.Month()+time.Month(validityMonths), 1, 0, 0, 0, 0, time.UTC)
+	return fmt.Sprintf("%02d/%02d", expiry.Month(), expiry.Year()), cardExpiresAt(int(expiry.Month()), expiry.Year())
+}
+
+// cardExpiresAt возвращает момент окончания срока действия карты: карта
+// действует до конца указанного месяца
+func cardExpiresAt(month, year int) time.Time {
+	return time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.Local)
 }
 
-func generateCVV() string {
-	rand.Seed(time.Now().UnixNano())
-	return fmt.Sprintf("%03d", rand.Intn(1000))
+func generateCVV() (string, error) {
+	return randomDigits(3)
 }
 
 // Добавляем эти методы в конец файла credit_service.go
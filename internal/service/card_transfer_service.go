@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/pan"
+	"fmt"
+	"strconv"
+)
+
+// CardTransferService выполняет переводы с карты на карту банка по номеру
+// карты получателя
+type CardTransferService struct {
+	cardRepo    *repository.CardRepository
+	accountRepo *repository.AccountRepository
+	userRepo    *repository.UserRepository
+	holdService *HoldService
+	hmacSecret  string
+}
+
+func NewCardTransferService(
+	cardRepo *repository.CardRepository,
+	accountRepo *repository.AccountRepository,
+	userRepo *repository.UserRepository,
+	holdService *HoldService,
+	hmacSecret string,
+) *CardTransferService {
+	return &CardTransferService{
+		cardRepo:    cardRepo,
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		holdService: holdService,
+		hmacSecret:  hmacSecret,
+	}
+}
+
+// Transfer находит карту получателя по хешу номера и переводит деньги на ее
+// счет. Для карты отправителя действуют те же проверки статуса, лимитов и
+// ограничений, что и при оплате.
+func (s *CardTransferService) Transfer(userID int, req *models.CardTransferRequest) (*models.CardTransfer, error) {
+	number := pan.Normalize(req.ToCardNumber)
+	if !pan.Valid(number) {
+		return nil, ErrInvalidCardNumber
+	}
+	if req.Amount <= 0 || roundKopecks(req.Amount) != req.Amount {
+		return nil, ErrInvalidAmount
+	}
+
+	fromCard, err := s.cardRepo.GetCardByID(req.FromCardID)
+	if err != nil {
+		return nil, err
+	}
+	if fromCard == nil {
+		return nil, ErrCardNotFound
+	}
+	fromAccount, err := s.accountRepo.GetAccountByID(fromCard.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount == nil || fromAccount.UserID != userID {
+		return nil, ErrCardNotFound
+	}
+
+	if err := s.checkControls(fromCard.ID, req.Amount); err != nil {
+		return nil, err
+	}
+
+	toCard, err := s.cardRepo.GetCardByNumberHash(cardNumberHash(number, s.hmacSecret))
+	if err != nil {
+		return nil, err
+	}
+	// На закрытую карту зачислить нельзя: ее счет мог перейти к другой карте
+	if toCard == nil || toCard.Status == models.CardStatusClosed {
+		return nil, ErrRecipientNotFound
+	}
+	if toCard.ID == fromCard.ID {
+		return nil, ErrSameAccount
+	}
+	toAccount, err := s.accountRepo.GetAccountByID(toCard.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if toAccount == nil {
+		return nil, ErrRecipientNotFound
+	}
+	recipient, err := s.userRepo.GetUserByID(toAccount.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if recipient == nil {
+		return nil, ErrRecipientNotFound
+	}
+
+	toCardMasked := maskCardNumber(number)
+	description := req.Description
+	if description == "" {
+		description = fmt.Sprintf("Card transfer to %s", toCardMasked)
+	}
+
+	hold, err := s.holdService.TransferFromCard(userID, fromCard.ID, toAccount.ID, req.Amount, description)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CardTransfer{
+		FromCardID:    fromCard.ID,
+		ToCard:        toCardMasked,
+		RecipientName: maskName(recipient.Username),
+		Amount:        req.Amount,
+		HoldID:        hold.ID,
+		TransactionID: hold.TransactionID,
+	}, nil
+}
+
+// checkControls проверяет перевод по ограничениям карты отправителя как
+// операцию с MCC перевода денег
+func (s *CardTransferService) checkControls(cardID int, amount float64) error {
+	controls, err := s.cardRepo.GetControls(cardID)
+	if err != nil {
+		return err
+	}
+	if controls == nil {
+		return nil
+	}
+
+	switch cardControlDecline(controls, &models.AuthorizationRequest{
+		Amount: amount,
+		MCC:    strconv.Itoa(moneyTransferMCC),
+	}) {
+	case "":
+		return nil
+	case models.DeclineAboveTransactionMax:
+		return ErrAboveTransactionMax
+	default:
+		return ErrTransferNotPermitted
+	}
+}
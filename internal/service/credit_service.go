@@ -43,6 +43,9 @@ func (s *CreditService) CreateCredit(userID int, req *models.CreateCreditRequest
 	if account == nil || account.UserID != userID {
 		return nil, ErrAccountNotFound
 	}
+	if err := checkActive(account); err != nil {
+		return nil, err
+	}
 
 	keyRate, err := cbr.GetKeyRate()
 	if err != nil {
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Допустимый срок вклада, месяцев
+const (
+	minDepositTermMonths = 1
+	maxDepositTermMonths = 36
+)
+
+// DepositProduct - условия срочных вкладов
+type DepositProduct struct {
+	// Годовая ставка, %
+	Rate float64
+	// Ставка при досрочном расторжении, %
+	EarlyWithdrawalRate float64
+}
+
+type DepositService struct {
+	depositRepo     *repository.DepositRepository
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	userRepo        *repository.UserRepository
+	notificationSvc *NotificationService
+	product         DepositProduct
+	db              *sql.DB
+}
+
+func NewDepositService(
+	depositRepo *repository.DepositRepository,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	userRepo *repository.UserRepository,
+	notificationSvc *NotificationService,
+	product DepositProduct,
+	db *sql.DB,
+) *DepositService {
+	return &DepositService{
+		depositRepo:     depositRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		notificationSvc: notificationSvc,
+		product:         product,
+		db:              db,
+	}
+}
+
+// OpenDeposit открывает вклад, списывая сумму со счета клиента
+func (s *DepositService) OpenDeposit(userID int, req *models.CreateDepositRequest) (*models.Deposit, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if req.TermMonths < minDepositTermMonths || req.TermMonths > maxDepositTermMonths {
+		return nil, ErrInvalidDepositTerm
+	}
+
+	interestMode := req.InterestMode
+	switch interestMode {
+	case "":
+		interestMode = models.DepositInterestCapitalize
+	case models.DepositInterestCapitalize, models.DepositInterestPayout:
+	default:
+		return nil, ErrInvalidDepositOption
+	}
+
+	maturityAction := req.MaturityAction
+	switch maturityAction {
+	case "":
+		maturityAction = models.DepositMaturityReturn
+	case models.DepositMaturityReturn, models.DepositMaturityProlong:
+	default:
+		return nil, ErrInvalidDepositOption
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	if err := checkDebit(account, req.Amount); err != nil {
+		return nil, err
+	}
+
+	start := startOfDay(time.Now())
+	deposit := &models.Deposit{
+		AccountID:           account.ID,
+		InitialAmount:       req.Amount,
+		Amount:              req.Amount,
+		InterestRate:        s.product.Rate,
+		EarlyWithdrawalRate: s.product.EarlyWithdrawalRate,
+		TermMonths:          req.TermMonths,
+		InterestMode:        interestMode,
+		MaturityAction:      maturityAction,
+		StartDate:           start,
+		MaturityDate:        addMonths(start, req.TermMonths),
+		LastInterestDate:    start,
+		Status:              models.DepositStatusActive,
+	}
+	deposit.NextInterestDate = nextDepositInterestDate(deposit)
+
+	if err := s.accountRepo.UpdateBalance(tx, account.ID, -req.Amount); err != nil {
+		return nil, err
+	}
+
+	if err := s.depositRepo.CreateDeposit(tx, deposit); err != nil {
+		return nil, err
+	}
+
+	transaction := &models.Transaction{
+		AccountID:   account.ID,
+		Amount:      -req.Amount,
+		Type:        models.TransactionTermDeposit,
+		Description: fmt.Sprintf("Term deposit #%d placement", deposit.ID),
+	}
+	if err := s.transactionRepo.CreateTransaction(tx, transaction); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deposit, nil
+}
+
+func (s *DepositService) GetUserDeposits(userID int) ([]*models.Deposit, error) {
+	return s.depositRepo.GetDepositsByUser(userID)
+}
+
+func (s *DepositService) GetDeposit(userID, depositID int) (*models.Deposit, error) {
+	deposit, err := s.depositRepo.GetDepositByID(depositID)
+	if err != nil {
+		return nil, err
+	}
+	if deposit == nil {
+		return nil, ErrDepositNotFound
+	}
+
+	account, err := s.accountRepo.GetAccountByID(deposit.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrDepositNotFound
+	}
+
+	return deposit, nil
+}
+
+// WithdrawEarly досрочно расторгает вклад. Проценты за текущий срок
+// пересчитываются по сниженной ставке на начальную сумму; уже выплаченные
+// и капитализированные проценты удерживаются из возвращаемой суммы.
+func (s *DepositService) WithdrawEarly(userID, depositID int) (*models.DepositWithdrawalResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deposit, err := s.depositRepo.GetDepositByIDForUpdate(tx, depositID)
+	if err != nil {
+		return nil, err
+	}
+	if deposit == nil {
+		return nil, ErrDepositNotFound
+	}
+
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, deposit.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrDepositNotFound
+	}
+	if deposit.Status != models.DepositStatusActive {
+		return nil, ErrDepositNotActive
+	}
+	if err := checkCredit(account); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	interest := depositInterest(deposit.InitialAmount, deposit.EarlyWithdrawalRate, deposit.StartDate, startOfDay(now))
+	paidOut := math.Max(roundKopecks(deposit.InitialAmount+interest-deposit.InterestPaid), 0)
+
+	if paidOut > 0 {
+		if err := s.accountRepo.UpdateBalance(tx, account.ID, paidOut); err != nil {
+			return nil, err
+		}
+
+		transaction := &models.Transaction{
+			AccountID:   account.ID,
+			Amount:      paidOut,
+			Type:        models.TransactionTermDeposit,
+			Description: fmt.Sprintf("Term deposit #%d early withdrawal", deposit.ID),
+		}
+		if err := s.transactionRepo.CreateTransaction(tx, transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	deposit.Status = models.DepositStatusWithdrawn
+	deposit.ClosedAt = &now
+	if err := s.depositRepo.UpdateDeposit(tx, deposit); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &models.DepositWithdrawalResponse{Deposit: deposit, PaidOut: paidOut}, nil
+}
+
+// ProcessDeposits выплачивает проценты по наступившим датам и обрабатывает
+// окончание срока вкладов. Вызывается шедулером; пропущенные периоды
+// обрабатываются при следующем запуске.
+func (s *DepositService) ProcessDeposits(now time.Time) error {
+	deposits, err := s.depositRepo.GetDueDeposits(now)
+	if err != nil {
+		return err
+	}
+
+	for _, deposit := range deposits {
+		if err := s.processDeposit(deposit.ID, now); err != nil {
+			log.Printf("Failed to process deposit %d: %v", deposit.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *DepositService) processDeposit(depositID int, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deposit, err := s.depositRepo.GetDepositByIDForUpdate(tx, depositID)
+	if err != nil {
+		return err
+	}
+	if deposit == nil || deposit.Status != models.DepositStatusActive {
+		return nil
+	}
+
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, deposit.AccountID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return ErrAccountNotFound
+	}
+
+	matured := false
+	for deposit.Status == models.DepositStatusActive && !deposit.NextInterestDate.After(now) {
+		periodEnd := deposit.NextInterestDate
+		interest := roundKopecks(depositInterest(deposit.Amount, deposit.InterestRate, deposit.LastInterestDate, periodEnd))
+
+		if interest > 0 {
+			if deposit.InterestMode == models.DepositInterestPayout {
+				err = s.credit(tx, account, interest, models.TransactionInterest,
+					fmt.Sprintf("Term deposit #%d interest for %s", deposit.ID, periodEnd.Format("01.2006")))
+				if err != nil {
+					return err
+				}
+				deposit.InterestPaid += interest
+			} else {
+				deposit.Amount += interest
+			}
+		}
+		deposit.LastInterestDate = periodEnd
+
+		if periodEnd.Equal(deposit.MaturityDate) {
+			matured = true
+			if err := s.mature(tx, account, deposit, now); err != nil {
+				return err
+			}
+		}
+		deposit.NextInterestDate = nextDepositInterestDate(deposit)
+	}
+
+	if err := s.depositRepo.UpdateDeposit(tx, deposit); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if matured {
+		s.notifyMaturity(account.UserID, deposit)
+	}
+
+	return nil
+}
+
+// mature обрабатывает окончание срока: возвращает сумму вклада на счет
+// или продлевает вклад на тот же срок по действующей ставке
+func (s *DepositService) mature(tx *sql.Tx, account *models.Account, deposit *models.Deposit, now time.Time) error {
+	if deposit.MaturityAction == models.DepositMaturityProlong && account.Status == models.AccountStatusActive {
+		deposit.StartDate = deposit.MaturityDate
+		deposit.MaturityDate = addMonths(deposit.StartDate, deposit.TermMonths)
+		deposit.InitialAmount = deposit.Amount
+		deposit.InterestPaid = 0
+		deposit.InterestRate = s.product.Rate
+		deposit.EarlyWithdrawalRate = s.product.EarlyWithdrawalRate
+		return nil
+	}
+
+	// Вклад со счета, по которому запрещены операции, не пролонгируется
+	err := s.credit(tx, account, deposit.Amount, models.TransactionTermDeposit,
+		fmt.Sprintf("Term deposit #%d return", deposit.ID))
+	if err != nil {
+		return err
+	}
+
+	deposit.Status = models.DepositStatusClosed
+	deposit.ClosedAt = &now
+	return nil
+}
+
+func (s *DepositService) credit(tx *sql.Tx, account *models.Account, amount float64, txType models.TransactionType, description string) error {
+	if err := checkCredit(account); err != nil {
+		return err
+	}
+	if err := s.accountRepo.UpdateBalance(tx, account.ID, amount); err != nil {
+		return err
+	}
+	account.Balance += amount
+	account.AvailableBalance += amount
+
+	transaction := &models.Transaction{
+		AccountID:   account.ID,
+		Amount:      amount,
+		Type:        txType,
+		Description: description,
+	}
+	return s.transactionRepo.CreateTransaction(tx, transaction)
+}
+
+func (s *DepositService) notifyMaturity(userID int, deposit *models.Deposit) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil || user == nil {
+		log.Printf("Failed to find user %d for deposit %d notification: %v", userID, deposit.ID, err)
+		return
+	}
+
+	if err := s.notificationSvc.SendDepositMaturityNotification(user.Email, deposit); err != nil {
+		log.Printf("Failed to send deposit notification: %v", err)
+	}
+}
+
+// nextDepositInterestDate возвращает следующую дату выплаты процентов.
+// Даты отсчитываются от начала срока, чтобы короткие месяцы не сдвигали
+// график, и не выходят за дату окончания вклада.
+func nextDepositInterestDate(deposit *models.Deposit) time.Time {
+	for months := 1; ; months++ {
+		next := addMonths(deposit.StartDate, months)
+		if next.After(deposit.MaturityDate) {
+			return deposit.MaturityDate
+		}
+		if next.After(deposit.LastInterestDate) {
+			return next
+		}
+	}
+}
+
+// depositInterest рассчитывает простые проценты за период [from, to)
+// с учетом числа дней в каждом году
+func depositInterest(amount, rate float64, from, to time.Time) float64 {
+	var interest float64
+	for from.Before(to) {
+		yearEnd := time.Date(from.Year()+1, time.January, 1, 0, 0, 0, 0, from.Location())
+		if yearEnd.After(to) {
+			yearEnd = to
+		}
+		days := yearEnd.Sub(from).Hours() / 24
+		interest += amount * rate / 100 * math.Round(days) / float64(daysInYear(from.Year()))
+		from = yearEnd
+	}
+	return interest
+}
+
+// addMonths прибавляет месяцы к дате. В отличие от time.AddDate дата
+// не переносится на следующий месяц: 31 января + 1 месяц = 28 февраля.
+func addMonths(t time.Time, months int) time.Time {
+	return monthDay(t.Year(), t.Month()+time.Month(months), t.Day(), t.Location())
+}
+
+func roundKopecks(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
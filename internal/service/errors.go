@@ -3,7 +3,96 @@ package service
 import "errors"
 
 var (
-	ErrAccountNotFound   = errors.New("account not found")
-	ErrInsufficientFunds = errors.New("insufficient funds")
-	ErrInvalidUser       = errors.New("invalid user")
+	ErrAccountNotFound      = errors.New("account not found")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrInvalidUser          = errors.New("invalid user")
+	ErrInvalidAmount        = errors.New("invalid amount")
+	ErrInvalidPeriod        = errors.New("invalid period")
+	ErrUnsupportedFormat    = errors.New("unsupported format")
+	ErrSameAccount          = errors.New("source and destination accounts are the same")
+	ErrAccountFrozen        = errors.New("account is frozen")
+	ErrAccountClosed        = errors.New("account is closed")
+	ErrAccountNotFrozen     = errors.New("account is not frozen")
+	ErrAccountHasCredits    = errors.New("account has active credits")
+	ErrNonZeroBalance       = errors.New("account balance is not zero")
+	ErrInvalidAccountNumber = errors.New("invalid account number")
+	ErrNotSavingsAccount    = errors.New("not a savings account")
+	ErrInvalidAccountType   = errors.New("invalid account type")
+	ErrAccountHasDeposits   = errors.New("account has active deposits")
+	ErrDepositNotFound      = errors.New("deposit not found")
+	ErrDepositNotActive     = errors.New("deposit is not active")
+	ErrInvalidDepositTerm   = errors.New("invalid deposit term")
+	ErrInvalidDepositOption = errors.New("invalid interest mode or maturity action")
+	ErrAccountHasHolds      = errors.New("account has active holds")
+	ErrHoldNotFound         = errors.New("hold not found")
+	ErrHoldNotActive        = errors.New("hold is not active")
+	ErrHoldExpired          = errors.New("hold has expired")
+	ErrInvalidHoldExpiry    = errors.New("invalid hold expiry")
+	ErrCardNotFound         = errors.New("card not found")
+	ErrInvalidSchedule      = errors.New("invalid schedule")
+	ErrOrderNotFound        = errors.New("standing order not found")
+	ErrOrderNotActive       = errors.New("standing order is not active")
+	ErrRecipientNotFound    = errors.New("recipient not found")
+	ErrNoReceivingAccount   = errors.New("recipient has no account to receive transfers")
+	ErrInvalidPhone         = errors.New("invalid phone number")
+	ErrPhoneTaken           = errors.New("phone number is already in use")
+	ErrTransferNotFound     = errors.New("transfer not found")
+	ErrTransferNotPending   = errors.New("transfer is already confirmed")
+	ErrTransferExpired      = errors.New("transfer confirmation has expired")
+	ErrMerchantNotFound     = errors.New("merchant not found")
+	ErrInvalidCallbackURL   = errors.New("invalid callback url")
+	ErrInvalidQRParams      = errors.New("invalid QR code parameters")
+	ErrInvalidPaymentLink   = errors.New("invalid payment link")
+	ErrForeignBank          = errors.New("payment link belongs to another bank")
+	ErrQRNotFound           = errors.New("QR code not found")
+	ErrQRNotActive          = errors.New("QR code is already paid")
+	ErrQRExpired            = errors.New("QR code has expired")
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrSelfRequest          = errors.New("cannot request money from yourself")
+	ErrRequestNotFound      = errors.New("payment request not found")
+	ErrRequestNotPending    = errors.New("payment request is already answered")
+	ErrRequestExpired       = errors.New("payment request has expired")
+	ErrInvalidPayrollFile   = errors.New("invalid payroll file")
+	ErrInvalidPayrollMode   = errors.New("invalid payroll mode")
+	ErrPayrollNotFound      = errors.New("payroll batch not found")
+	ErrForbidden            = errors.New("operation is not permitted")
+	ErrTransactionNotFound  = errors.New("transaction not found")
+	ErrNotReversible        = errors.New("transaction cannot be reversed")
+	ErrNotRefundable        = errors.New("transaction is not a merchant payment")
+	ErrAlreadyReversed      = errors.New("transaction is already reversed or refunded")
+	ErrRefundTooLarge       = errors.New("refund exceeds the unrefunded amount")
+	ErrDailyLimitExceeded   = errors.New("daily spending limit exceeded")
+	ErrMonthlyLimitExceeded = errors.New("monthly spending limit exceeded")
+	ErrDailyCountExceeded   = errors.New("daily operations count limit exceeded")
+	ErrMonthlyCountExceeded = errors.New("monthly operations count limit exceeded")
+	ErrInvalidLimit         = errors.New("invalid limit")
+	ErrLimitAboveMaximum    = errors.New("limit exceeds the bank maximum")
+	ErrInvalidTimezone      = errors.New("invalid timezone")
+	ErrUnsupportedProduct   = errors.New("card product is not available")
+	ErrCardBlocked          = errors.New("card is blocked")
+	ErrCardExpired          = errors.New("card has expired")
+	ErrCardClosed           = errors.New("card is closed")
+	ErrCardNotBlocked       = errors.New("card is not blocked")
+	ErrCardAlreadyReissued  = errors.New("card is already reissued")
+	ErrInvalidBlockReason   = errors.New("invalid block reason")
+	ErrAuthNotFound         = errors.New("authorization not found")
+	ErrAuthNotActive        = errors.New("authorization is already settled or reversed")
+	ErrInvalidCardType      = errors.New("invalid card type")
+	ErrInvalidCardValidity  = errors.New("invalid card validity period")
+	ErrAmountCapExceeded    = errors.New("card amount cap exceeded")
+	ErrUnknownMCCGroup      = errors.New("unknown MCC group")
+	ErrInvalidPIN           = errors.New("invalid PIN")
+	ErrWeakPIN              = errors.New("PIN is too easy to guess")
+	ErrPINAlreadySet        = errors.New("card PIN is already set")
+	ErrInvalidCardNumber    = errors.New("invalid card number")
+	ErrCardHoldLocked       = errors.New("card holds are settled by the acquirer")
+	ErrHoldNoRecipient      = errors.New("hold has no recipient")
+	ErrPhoneCodeNotFound    = errors.New("no pending phone verification")
+	ErrPhoneCodeExpired     = errors.New("phone verification code has expired")
+	ErrInvalidPhoneCode     = errors.New("invalid phone verification code")
+	ErrImportInProgress     = errors.New("payment file is already being processed")
+	ErrPayrollInProgress    = errors.New("payroll batch is already being processed")
+	ErrTransferNotPermitted = errors.New("card controls do not allow money transfers")
+	ErrAboveTransactionMax  = errors.New("amount exceeds the card transaction maximum")
+	ErrUnsupportedCurrency  = errors.New("unsupported account currency")
 )
@@ -0,0 +1,515 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Срок действия блокировки по умолчанию и максимальный срок
+const (
+	defaultHoldTTL = 7 * 24 * time.Hour
+	maxHoldTTL     = 30 * 24 * time.Hour
+)
+
+// Блокировка по операции с картой держится весь срок, в течение которого
+// эквайрер вправе прислать расчеты
+const cardHoldTTL = 30 * 24 * time.Hour
+
+type HoldService struct {
+	holdRepo        *repository.HoldRepository
+	accountRepo     *repository.AccountRepository
+	cardRepo        *repository.CardRepository
+	transactionRepo *repository.TransactionRepository
+	accountService  *AccountService
+	limitService    *LimitService
+	db              *sql.DB
+}
+
+func NewHoldService(
+	holdRepo *repository.HoldRepository,
+	accountRepo *repository.AccountRepository,
+	cardRepo *repository.CardRepository,
+	transactionRepo *repository.TransactionRepository,
+	accountService *AccountService,
+	limitService *LimitService,
+	db *sql.DB,
+) *HoldService {
+	return &HoldService{
+		holdRepo:        holdRepo,
+		accountRepo:     accountRepo,
+		cardRepo:        cardRepo,
+		transactionRepo: transactionRepo,
+		accountService:  accountService,
+		limitService:    limitService,
+		db:              db,
+	}
+}
+
+// PlaceHold блокирует средства на счете как отложенный перевод: при списании
+// средства зачисляются на счет получателя. Без получателя списание увело бы
+// деньги со счета без встречной проводки, поэтому он обязателен.
+func (s *HoldService) PlaceHold(userID int, req *models.CreateHoldRequest) (*models.Hold, error) {
+	hold := &models.Hold{
+		AccountID:   req.AccountID,
+		Amount:      req.Amount,
+		Description: req.Description,
+	}
+
+	toAccountID := req.ToAccountID
+	if toAccountID == 0 && req.ToAccountNumber != "" {
+		toAccount, err := s.accountService.GetAccountByNumber(req.ToAccountNumber)
+		if err != nil {
+			return nil, err
+		}
+		if toAccount == nil {
+			return nil, ErrAccountNotFound
+		}
+		toAccountID = toAccount.ID
+	}
+	if toAccountID == 0 {
+		return nil, ErrHoldNoRecipient
+	}
+	if toAccountID == req.AccountID {
+		return nil, ErrSameAccount
+	}
+	hold.ToAccountID = &toAccountID
+
+	if err := s.place(userID, hold, nil, req.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// PlaceCardHold блокирует средства по операции с картой на ее счете
+func (s *HoldService) PlaceCardHold(userID, cardID int, req *models.CardHoldRequest) (*models.Hold, error) {
+	card, err := s.cardRepo.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+
+	hold := &models.Hold{
+		AccountID:   card.AccountID,
+		Amount:      req.Amount,
+		CardID:      &card.ID,
+		Description: req.Description,
+	}
+
+	if err := s.place(userID, hold, card, req.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// TransferFromCard переводит деньги со счета карты на другой счет как
+// операцию по карте: блокировка проходит проверки статуса и лимитов карты
+// и сразу списывается
+func (s *HoldService) TransferFromCard(userID, cardID, toAccountID int, amount float64, description string) (*models.Hold, error) {
+	card, err := s.cardRepo.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	if toAccountID == card.AccountID {
+		return nil, ErrSameAccount
+	}
+
+	hold := &models.Hold{
+		AccountID:   card.AccountID,
+		Amount:      amount,
+		CardID:      &card.ID,
+		ToAccountID: &toAccountID,
+		Description: description,
+	}
+	if err := s.place(userID, hold, card, nil); err != nil {
+		return nil, err
+	}
+
+	captured, err := s.CaptureCardHold(userID, hold.ID, 0)
+	if err != nil {
+		if _, releaseErr := s.ReleaseCardHold(userID, hold.ID); releaseErr != nil {
+			log.Printf("Failed to release hold %d: %v", hold.ID, releaseErr)
+		}
+		return nil, err
+	}
+	return captured, nil
+}
+
+// place блокирует средства, проверяя лимиты счета и, для операции
+// по карте, лимиты карты
+func (s *HoldService) place(userID int, hold *models.Hold, card *models.Card, expiresAt *time.Time) error {
+	if hold.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	now := time.Now()
+	hold.ExpiresAt = now.Add(defaultHoldTTL)
+	if card != nil {
+		hold.ExpiresAt = now.Add(cardHoldTTL)
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(now) || expiresAt.Sub(now) > maxHoldTTL {
+			return ErrInvalidHoldExpiry
+		}
+		hold.ExpiresAt = *expiresAt
+	}
+	hold.Status = models.HoldStatusActive
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	account, err := s.accountRepo.GetAccountByID(hold.AccountID)
+	if err != nil {
+		return err
+	}
+	if account == nil || account.UserID != userID {
+		return ErrAccountNotFound
+	}
+	if err := checkDebit(account, hold.Amount); err != nil {
+		return err
+	}
+	if err := s.limitService.CheckAccount(tx, account, hold.Amount); err != nil {
+		return err
+	}
+	if card != nil {
+		// Статус карты перечитывается под блокировкой, чтобы операция не прошла
+		// по карте, которую заблокировали параллельно
+		card, err = s.cardRepo.GetCardByIDForUpdate(tx, card.ID)
+		if err != nil {
+			return err
+		}
+		if card == nil {
+			return ErrCardNotFound
+		}
+		if err := checkCardActive(card); err != nil {
+			return err
+		}
+		if err := s.limitService.CheckCard(tx, card, userID, hold.Amount); err != nil {
+			return err
+		}
+	}
+
+	if err := s.holdRepo.CreateHold(tx, hold); err != nil {
+		return err
+	}
+
+	// Одноразовая карта блокируется вместе с блокировкой средств, чтобы
+	// параллельная операция не прошла по ней второй раз. Если операция не
+	// состоится и блокировка будет снята, release вернет карту в работу.
+	if card != nil && card.Type == models.CardTypeSingleUse {
+		if err := s.cardRepo.UpdateStatus(tx, card.ID, models.CardStatusPermanentlyBlocked, models.CardBlockUsed); err != nil {
+			return err
+		}
+		if err := s.cardRepo.AddStatusChange(tx, &models.CardStatusChange{
+			CardID:     card.ID,
+			UserID:     userID,
+			FromStatus: card.Status,
+			ToStatus:   models.CardStatusPermanentlyBlocked,
+			Reason:     string(models.CardBlockUsed),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *HoldService) GetHold(userID, holdID int) (*models.Hold, error) {
+	hold, err := s.holdRepo.GetHoldByID(holdID)
+	if err != nil {
+		return nil, err
+	}
+	if hold == nil {
+		return nil, ErrHoldNotFound
+	}
+
+	account, err := s.accountRepo.GetAccountByID(hold.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrHoldNotFound
+	}
+
+	return hold, nil
+}
+
+func (s *HoldService) GetAccountHolds(userID, accountID int) ([]*models.Hold, error) {
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	return s.holdRepo.GetHoldsByAccount(accountID)
+}
+
+// CaptureHold списывает заблокированные средства полностью или частично.
+// Остаток блокировки при частичном списании освобождается. Блокировки по
+// операциям с картой клиент не списывает: их закрывают расчеты эквайрера.
+func (s *HoldService) CaptureHold(userID, holdID int, amount float64) (*models.Hold, error) {
+	return s.capture(userID, holdID, amount, false)
+}
+
+// CaptureCardHold списывает блокировку по операции с картой. Вызывается
+// при расчетах эквайрера и переводе с карты.
+func (s *HoldService) CaptureCardHold(userID, holdID int, amount float64) (*models.Hold, error) {
+	return s.capture(userID, holdID, amount, true)
+}
+
+func (s *HoldService) capture(userID, holdID int, amount float64, card bool) (*models.Hold, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hold, err := s.holdRepo.GetHoldByIDForUpdate(tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if hold == nil {
+		return nil, ErrHoldNotFound
+	}
+	if err := checkHoldSource(hold, card); err != nil {
+		return nil, err
+	}
+	// Клиентские блокировки без получателя, оставшиеся от прежних версий,
+	// можно только отменить
+	if !card && hold.ToAccountID == nil {
+		return nil, ErrHoldNoRecipient
+	}
+
+	var account, toAccount *models.Account
+	if hold.ToAccountID != nil {
+		account, toAccount, err = lockTransferAccounts(tx, s.accountRepo, hold.AccountID, *hold.ToAccountID)
+	} else {
+		account, err = s.accountRepo.GetAccountByIDForUpdate(tx, hold.AccountID)
+		if err == nil && account == nil {
+			err = ErrAccountNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if account.UserID != userID {
+		return nil, ErrHoldNotFound
+	}
+
+	if err := checkHoldActive(hold, time.Now()); err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		amount = hold.Amount
+	}
+	if amount < 0 || amount > hold.Amount {
+		return nil, ErrInvalidAmount
+	}
+
+	// Снимаем блокировку: ее сумма снова входит в доступный остаток
+	// и покрывает списание
+	account.AvailableBalance += hold.Amount
+
+	description := hold.Description
+	if description == "" {
+		description = fmt.Sprintf("Hold #%d capture", hold.ID)
+	}
+
+	var debit *models.Transaction
+	if toAccount != nil {
+		debit, err = postTransfer(tx, s.accountRepo, s.transactionRepo, account, toAccount, amount, description)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := checkDebit(account, amount); err != nil {
+			return nil, err
+		}
+		if err := s.accountRepo.UpdateBalance(tx, account.ID, -amount); err != nil {
+			return nil, err
+		}
+
+		debit = &models.Transaction{
+			AccountID:   account.ID,
+			Amount:      -amount,
+			Type:        models.TransactionPayment,
+			Description: description,
+		}
+		if err := s.transactionRepo.CreateTransaction(tx, debit); err != nil {
+			return nil, err
+		}
+	}
+
+	hold.Status = models.HoldStatusCaptured
+	hold.CapturedAmount = amount
+	hold.TransactionID = &debit.ID
+	if err := s.holdRepo.CompleteHold(tx, hold); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// ReleaseHold отменяет блокировку без списания средств. Блокировку по
+// операции с картой может снять только эквайрер или истечение срока.
+func (s *HoldService) ReleaseHold(userID, holdID int) (*models.Hold, error) {
+	return s.release(userID, holdID, false)
+}
+
+// ReleaseCardHold снимает блокировку по операции с картой при отмене
+// авторизации
+func (s *HoldService) ReleaseCardHold(userID, holdID int) (*models.Hold, error) {
+	return s.release(userID, holdID, true)
+}
+
+func (s *HoldService) release(userID, holdID int, card bool) (*models.Hold, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hold, err := s.holdRepo.GetHoldByIDForUpdate(tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if hold == nil {
+		return nil, ErrHoldNotFound
+	}
+	if err := checkHoldSource(hold, card); err != nil {
+		return nil, err
+	}
+
+	account, err := s.accountRepo.GetAccountByID(hold.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrHoldNotFound
+	}
+	if hold.Status != models.HoldStatusActive {
+		return nil, ErrHoldNotActive
+	}
+
+	hold.Status = models.HoldStatusReleased
+	if err := s.holdRepo.CompleteHold(tx, hold); err != nil {
+		return nil, err
+	}
+	if hold.CardID != nil {
+		if err := s.restoreSingleUseCard(tx, userID, *hold.CardID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// restoreSingleUseCard возвращает в работу одноразовую карту, заблокированную
+// при размещении снятой блокировки: операция по карте не состоялась.
+// Списанная операция блокировку не снимает, поэтому карта, по которой деньги
+// уже ушли, остается заблокированной.
+func (s *HoldService) restoreSingleUseCard(tx *sql.Tx, userID, cardID int) error {
+	card, err := s.cardRepo.GetCardByIDForUpdate(tx, cardID)
+	if err != nil {
+		return err
+	}
+	if card == nil || card.Type != models.CardTypeSingleUse ||
+		card.Status != models.CardStatusPermanentlyBlocked || card.BlockReason != models.CardBlockUsed {
+		return nil
+	}
+
+	if err := s.cardRepo.UpdateStatus(tx, card.ID, models.CardStatusActive, ""); err != nil {
+		return err
+	}
+	return s.cardRepo.AddStatusChange(tx, &models.CardStatusChange{
+		CardID:     card.ID,
+		UserID:     userID,
+		FromStatus: card.Status,
+		ToStatus:   models.CardStatusActive,
+		Reason:     "card operation cancelled",
+	})
+}
+
+// ExpireHolds помечает истекшие блокировки. На доступный остаток это не
+// влияет - истекшие блокировки не учитываются и до запуска шедулера.
+// Одноразовые карты истекших блокировок возвращаются в работу.
+func (s *HoldService) ExpireHolds(now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	expired, err := s.holdRepo.ExpireHolds(tx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, hold := range expired {
+		if hold.CardID == nil {
+			continue
+		}
+		account, err := s.accountRepo.GetAccountByID(hold.AccountID)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			continue
+		}
+		if err := s.restoreSingleUseCard(tx, account.UserID, *hold.CardID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(expired) > 0 {
+		log.Printf("Expired %d holds", len(expired))
+	}
+	return nil
+}
+
+// checkHoldSource проверяет, что блокировку закрывает тот, кто вправе это
+// делать: блокировку по карте - эквайрер, остальные - клиент
+func checkHoldSource(hold *models.Hold, card bool) error {
+	switch {
+	case hold.CardID != nil && !card:
+		return ErrCardHoldLocked
+	case hold.CardID == nil && card:
+		return ErrHoldNotFound
+	}
+	return nil
+}
+
+func checkHoldActive(hold *models.Hold, now time.Time) error {
+	if hold.Status != models.HoldStatusActive {
+		return ErrHoldNotActive
+	}
+	if !hold.ExpiresAt.After(now) {
+		return ErrHoldExpired
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/cbr"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// SavingsProduct - условия сберегательных счетов
+type SavingsProduct struct {
+	// Фиксированная годовая ставка, %
+	Rate float64
+	// Привязка к ключевой ставке ЦБ: ставка = ключевая + KeyRateSpread
+	KeyRateLinked bool
+	KeyRateSpread float64
+}
+
+// currentRate возвращает действующую ставку по продукту
+func (p SavingsProduct) currentRate() (float64, error) {
+	if !p.KeyRateLinked {
+		return p.Rate, nil
+	}
+
+	keyRate, err := cbr.FetchKeyRate()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get key rate: %w", err)
+	}
+
+	return math.Max(keyRate+p.KeyRateSpread, 0), nil
+}
+
+type InterestService struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	interestRepo    *repository.InterestRepository
+	product         SavingsProduct
+	db              *sql.DB
+}
+
+func NewInterestService(
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	interestRepo *repository.InterestRepository,
+	product SavingsProduct,
+	db *sql.DB,
+) *InterestService {
+	return &InterestService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		interestRepo:    interestRepo,
+		product:         product,
+		db:              db,
+	}
+}
+
+func (s *InterestService) GetAccruals(userID, accountID int) ([]*models.InterestAccrual, error) {
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	if account.Type != models.AccountTypeSavings {
+		return nil, ErrNotSavingsAccount
+	}
+
+	return s.interestRepo.GetAccrualsByAccount(accountID)
+}
+
+// ProcessDailyAccruals начисляет проценты на остаток конца дня за все
+// завершившиеся дни, по которым начислений еще не было, и капитализирует
+// начисления за прошедшие месяцы. Вызывается шедулером.
+func (s *InterestService) ProcessDailyAccruals(now time.Time) error {
+	accounts, err := s.accountRepo.GetAccountsByType(models.AccountTypeSavings)
+	if err != nil {
+		return err
+	}
+
+	// Для ставки, привязанной к ключевой, при недоступности ЦБ
+	// используем последнюю известную ставку счета
+	var linkedRate float64
+	if s.product.KeyRateLinked {
+		if linkedRate, err = s.product.currentRate(); err != nil {
+			log.Printf("Using last known savings rates: %v", err)
+			linkedRate = -1
+		}
+	}
+
+	today := startOfDay(now)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+
+	for _, account := range accounts {
+		rate := account.InterestRate
+		if s.product.KeyRateLinked && linkedRate >= 0 && linkedRate != rate {
+			if err := s.accountRepo.UpdateInterestRate(account.ID, linkedRate); err != nil {
+				log.Printf("Failed to update interest rate for account %d: %v", account.ID, err)
+			} else {
+				rate = linkedRate
+			}
+		}
+
+		if err := s.accrueAccount(account, rate, today); err != nil {
+			log.Printf("Failed to accrue interest for account %d: %v", account.ID, err)
+			continue
+		}
+		if err := s.capitalize(account.ID, monthStart); err != nil {
+			log.Printf("Failed to capitalize interest for account %d: %v", account.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *InterestService) accrueAccount(account *models.Account, rate float64, today time.Time) error {
+	last, err := s.interestRepo.GetLastAccrualDate(account.ID)
+	if err != nil {
+		return err
+	}
+
+	day := startOfDay(account.CreatedAt.In(today.Location()))
+	if last != nil {
+		lastDay := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, today.Location())
+		day = lastDay.AddDate(0, 0, 1)
+	}
+
+	for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+
+		// Остаток на конец дня: текущий баланс за вычетом всех движений после
+		movedSince, err := s.transactionRepo.SumSince(account.ID, next)
+		if err != nil {
+			return err
+		}
+		balance := account.Balance - movedSince
+
+		accrual := &models.InterestAccrual{
+			AccountID:   account.ID,
+			AccrualDate: day,
+			Balance:     balance,
+			Rate:        rate,
+		}
+		if balance > 0 {
+			accrual.Amount = balance * rate / 100 / float64(daysInYear(day.Year()))
+		}
+
+		if err := s.interestRepo.CreateAccrual(accrual); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// capitalize зачисляет на счет проценты, начисленные до начала текущего месяца
+func (s *InterestService) capitalize(accountID int, monthStart time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, accountID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return ErrAccountNotFound
+	}
+	if err := checkCredit(account); err != nil {
+		return err
+	}
+
+	pending, err := s.interestRepo.SumUncapitalized(tx, accountID, monthStart)
+	if err != nil {
+		return err
+	}
+	// Остаток зачисляется только вместе с новыми начислениями: сумма
+	// капитализации должна быть привязана к отмеченным строкам
+	if pending <= 0 {
+		return nil
+	}
+	// Доли копейки, не зачисленные прошлыми капитализациями
+	carried, err := s.interestRepo.SumCarriedOver(tx, accountID)
+	if err != nil {
+		return err
+	}
+
+	// Зачисляются целые копейки. Остаток переносится на следующий месяц:
+	// если сумма меньше копейки, начисления остаются некапитализированными,
+	// иначе доля копейки учитывается через SumCarriedOver.
+	amount := math.Round((pending+carried)*100) / 100
+	if amount <= 0 {
+		return nil
+	}
+
+	if err := s.accountRepo.UpdateBalance(tx, accountID, amount); err != nil {
+		return err
+	}
+
+	transaction := &models.Transaction{
+		AccountID:   accountID,
+		Amount:      amount,
+		Type:        models.TransactionInterest,
+		Description: fmt.Sprintf("Interest capitalization for %s", monthStart.AddDate(0, -1, 0).Format("01.2006")),
+	}
+	if err := s.transactionRepo.CreateTransaction(tx, transaction); err != nil {
+		return err
+	}
+
+	if err := s.interestRepo.MarkCapitalized(tx, accountID, monthStart, transaction.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func daysInYear(year int) int {
+	if time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay() == 366 {
+		return 366
+	}
+	return 365
+}
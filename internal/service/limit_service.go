@@ -0,0 +1,336 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"database/sql"
+	"time"
+)
+
+// LimitPolicy - лимиты банка для счетов и карт и часовой пояс по умолчанию.
+// Клиент может только понизить лимиты относительно лимитов банка.
+type LimitPolicy struct {
+	Account         models.SpendingLimits
+	Card            models.SpendingLimits
+	DefaultTimezone string
+}
+
+type LimitService struct {
+	limitRepo   *repository.LimitRepository
+	accountRepo *repository.AccountRepository
+	cardRepo    *repository.CardRepository
+	userRepo    *repository.UserRepository
+	policy      LimitPolicy
+}
+
+func NewLimitService(
+	limitRepo *repository.LimitRepository,
+	accountRepo *repository.AccountRepository,
+	cardRepo *repository.CardRepository,
+	userRepo *repository.UserRepository,
+	policy LimitPolicy,
+) *LimitService {
+	return &LimitService{
+		limitRepo:   limitRepo,
+		accountRepo: accountRepo,
+		cardRepo:    cardRepo,
+		userRepo:    userRepo,
+		policy:      policy,
+	}
+}
+
+func (s *LimitService) GetAccountLimits(userID, accountID int) (*models.LimitsResponse, error) {
+	if _, err := s.getOwnAccount(userID, accountID); err != nil {
+		return nil, err
+	}
+
+	limits, err := s.accountLimits(nil, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.describe(userID, limits, s.policy.Account, func(since time.Time) (float64, int, error) {
+		return s.limitRepo.GetAccountSpending(nil, accountID, since)
+	})
+}
+
+func (s *LimitService) UpdateAccountLimits(userID, accountID int, req *models.UpdateLimitsRequest) (*models.LimitsResponse, error) {
+	if _, err := s.getOwnAccount(userID, accountID); err != nil {
+		return nil, err
+	}
+
+	limits, err := s.accountLimits(nil, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyLimits(limits, req, s.policy.Account); err != nil {
+		return nil, err
+	}
+	if err := s.limitRepo.SetAccountLimits(accountID, limits); err != nil {
+		return nil, err
+	}
+
+	return s.GetAccountLimits(userID, accountID)
+}
+
+func (s *LimitService) GetCardLimits(userID, cardID int) (*models.LimitsResponse, error) {
+	if _, err := s.getOwnCard(userID, cardID); err != nil {
+		return nil, err
+	}
+
+	limits, err := s.cardLimits(nil, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.describe(userID, limits, s.policy.Card, func(since time.Time) (float64, int, error) {
+		return s.limitRepo.GetCardSpending(nil, cardID, since)
+	})
+}
+
+func (s *LimitService) UpdateCardLimits(userID, cardID int, req *models.UpdateLimitsRequest) (*models.LimitsResponse, error) {
+	if _, err := s.getOwnCard(userID, cardID); err != nil {
+		return nil, err
+	}
+
+	limits, err := s.cardLimits(nil, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyLimits(limits, req, s.policy.Card); err != nil {
+		return nil, err
+	}
+	if err := s.limitRepo.SetCardLimits(cardID, limits); err != nil {
+		return nil, err
+	}
+
+	return s.GetCardLimits(userID, cardID)
+}
+
+func (s *LimitService) GetTimezone(userID int) (*models.TimezoneSettings, error) {
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TimezoneSettings{Timezone: loc.String()}, nil
+}
+
+func (s *LimitService) SetTimezone(userID int, req *models.TimezoneSettings) (*models.TimezoneSettings, error) {
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil || req.Timezone == "" || req.Timezone == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+
+	if err := s.userRepo.UpdateTimezone(userID, loc.String()); err != nil {
+		return nil, err
+	}
+	return &models.TimezoneSettings{Timezone: loc.String()}, nil
+}
+
+// CardLimits возвращает действующие лимиты карты
+func (s *LimitService) CardLimits(cardID int) (*models.SpendingLimits, error) {
+	return s.cardLimits(nil, cardID)
+}
+
+// CheckAccount проверяет, что списание суммы со счета укладывается в лимиты.
+// Вызывается внутри транзакции после блокировки счета, поэтому параллельные
+// списания проверяются по очереди.
+func (s *LimitService) CheckAccount(tx *sql.Tx, account *models.Account, amount float64) error {
+	limits, err := s.accountLimits(tx, account.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.check(account.UserID, limits, amount, func(since time.Time) (float64, int, error) {
+		return s.limitRepo.GetAccountSpending(tx, account.ID, since)
+	})
+}
+
+// CheckCard проверяет лимиты карты и ее предельную сумму операций за весь
+// срок действия. Лимиты счета карты проверяются отдельно.
+func (s *LimitService) CheckCard(tx *sql.Tx, card *models.Card, userID int, amount float64) error {
+	limits, err := s.cardLimits(tx, card.ID)
+	if err != nil {
+		return err
+	}
+
+	if card.AmountCap != nil {
+		spent, _, err := s.limitRepo.GetCardSpending(tx, card.ID, time.Time{})
+		if err != nil {
+			return err
+		}
+		if roundKopecks(spent+amount) > *card.AmountCap {
+			return ErrAmountCapExceeded
+		}
+	}
+
+	return s.check(userID, limits, amount, func(since time.Time) (float64, int, error) {
+		return s.limitRepo.GetCardSpending(tx, card.ID, since)
+	})
+}
+
+func (s *LimitService) check(
+	userID int,
+	limits *models.SpendingLimits,
+	amount float64,
+	spending func(since time.Time) (float64, int, error),
+) error {
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return err
+	}
+	dayStart, monthStart := limitPeriods(time.Now().In(loc))
+
+	monthAmount, monthCount, err := spending(monthStart)
+	if err != nil {
+		return err
+	}
+	dayAmount, dayCount, err := spending(dayStart)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dayCount+1 > limits.DailyCount:
+		return ErrDailyCountExceeded
+	case roundKopecks(dayAmount+amount) > limits.DailyAmount:
+		return ErrDailyLimitExceeded
+	case monthCount+1 > limits.MonthlyCount:
+		return ErrMonthlyCountExceeded
+	case roundKopecks(monthAmount+amount) > limits.MonthlyAmount:
+		return ErrMonthlyLimitExceeded
+	}
+	return nil
+}
+
+func (s *LimitService) describe(
+	userID int,
+	limits *models.SpendingLimits,
+	max models.SpendingLimits,
+	spending func(since time.Time) (float64, int, error),
+) (*models.LimitsResponse, error) {
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	dayStart, monthStart := limitPeriods(time.Now().In(loc))
+
+	response := &models.LimitsResponse{
+		Limits:         *limits,
+		Max:            max,
+		Timezone:       loc.String(),
+		DailyResetAt:   dayStart.AddDate(0, 0, 1),
+		MonthlyResetAt: monthStart.AddDate(0, 1, 0),
+	}
+
+	if response.Usage.DailyAmount, response.Usage.DailyCount, err = spending(dayStart); err != nil {
+		return nil, err
+	}
+	if response.Usage.MonthlyAmount, response.Usage.MonthlyCount, err = spending(monthStart); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// accountLimits возвращает действующие лимиты счета: заданные клиентом, но
+// не выше лимитов банка
+func (s *LimitService) accountLimits(tx *sql.Tx, accountID int) (*models.SpendingLimits, error) {
+	custom, err := s.limitRepo.GetAccountLimits(tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return effectiveLimits(custom, s.policy.Account), nil
+}
+
+func (s *LimitService) cardLimits(tx *sql.Tx, cardID int) (*models.SpendingLimits, error) {
+	custom, err := s.limitRepo.GetCardLimits(tx, cardID)
+	if err != nil {
+		return nil, err
+	}
+	return effectiveLimits(custom, s.policy.Card), nil
+}
+
+// userLocation возвращает часовой пояс клиента или пояс банка по умолчанию
+func (s *LimitService) userLocation(userID int) (*time.Location, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := s.policy.DefaultTimezone
+	if user != nil && user.Timezone != "" {
+		name = user.Timezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local, nil
+	}
+	return loc, nil
+}
+
+func (s *LimitService) getOwnAccount(userID, accountID int) (*models.Account, error) {
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+func (s *LimitService) getOwnCard(userID, cardID int) (*models.Card, error) {
+	card, err := s.cardRepo.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	if _, err := s.getOwnAccount(userID, card.AccountID); err != nil {
+		return nil, ErrCardNotFound
+	}
+	return card, nil
+}
+
+// limitPeriods возвращает начало текущих суток и месяца
+func limitPeriods(now time.Time) (time.Time, time.Time) {
+	return startOfDay(now), time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+func effectiveLimits(custom *models.SpendingLimits, max models.SpendingLimits) *models.SpendingLimits {
+	limits := max
+	if custom != nil {
+		limits.DailyAmount = min(custom.DailyAmount, max.DailyAmount)
+		limits.MonthlyAmount = min(custom.MonthlyAmount, max.MonthlyAmount)
+		limits.DailyCount = min(custom.DailyCount, max.DailyCount)
+		limits.MonthlyCount = min(custom.MonthlyCount, max.MonthlyCount)
+	}
+	return &limits
+}
+
+// applyLimits изменяет указанные в запросе лимиты. Превысить лимит банка нельзя.
+func applyLimits(limits *models.SpendingLimits, req *models.UpdateLimitsRequest, max models.SpendingLimits) error {
+	if req.DailyAmount != nil {
+		limits.DailyAmount = *req.DailyAmount
+	}
+	if req.MonthlyAmount != nil {
+		limits.MonthlyAmount = *req.MonthlyAmount
+	}
+	if req.DailyCount != nil {
+		limits.DailyCount = *req.DailyCount
+	}
+	if req.MonthlyCount != nil {
+		limits.MonthlyCount = *req.MonthlyCount
+	}
+
+	if limits.DailyAmount < 0 || limits.MonthlyAmount < 0 || limits.DailyCount < 0 || limits.MonthlyCount < 0 {
+		return ErrInvalidLimit
+	}
+	if limits.DailyAmount > max.DailyAmount || limits.MonthlyAmount > max.MonthlyAmount ||
+		limits.DailyCount > max.DailyCount || limits.MonthlyCount > max.MonthlyCount {
+		return ErrLimitAboveMaximum
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"bank-api/internal/models"
 	"bank-api/pkg/mail"
 	"fmt"
+	"html"
+	"time"
 )
 
 type NotificationService struct {
@@ -35,3 +38,114 @@ func (s *NotificationService) SendCreditNotification(email string, amount float6
 
 	return s.mailer.Send(email, subject, content)
 }
+
+func (s *NotificationService) SendStatementNotification(email string, from, to time.Time, attachment mail.Attachment) error {
+	subject := "Выписка по счету"
+	content := fmt.Sprintf(`
+		<h1>Выписка по счету</h1>
+		<p>Период: <strong>%s - %s</strong></p>
+		<p>Выписка приложена к письму.</p>
+		<small>Это автоматическое уведомление</small>
+	`, from.Format("02.01.2006"), to.Format("02.01.2006"))
+
+	return s.mailer.SendWithAttachments(email, subject, content, attachment)
+}
+
+func (s *NotificationService) SendDepositMaturityNotification(email string, deposit *models.Deposit) error {
+	subject := "Срок вклада истек"
+	result := fmt.Sprintf("Сумма <strong>%.2f RUB</strong> возвращена на счет.", deposit.Amount)
+	if deposit.Status == models.DepositStatusActive {
+		subject = "Вклад пролонгирован"
+		result = fmt.Sprintf("Вклад продлен до <strong>%s</strong> по ставке <strong>%.2f%%</strong>.",
+			deposit.MaturityDate.Format("02.01.2006"), deposit.InterestRate)
+	}
+
+	content := fmt.Sprintf(`
+		<h1>Вклад №%d</h1>
+		<p>%s</p>
+		<small>Это автоматическое уведомление</small>
+	`, deposit.ID, result)
+
+	return s.mailer.Send(email, subject, content)
+}
+
+func (s *NotificationService) SendStandingOrderNotification(email string, order *models.StandingOrder, run *models.StandingOrderRun) error {
+	subject := "Перевод по расписанию выполнен"
+	result := "Перевод выполнен."
+	if run.Status == models.StandingOrderRunFailed {
+		subject = "Перевод по расписанию не выполнен"
+		result = fmt.Sprintf("Перевод не выполнен: %s.", run.Error)
+		if order.Status == models.StandingOrderStatusCancelled {
+			result += " Поручение отменено."
+		}
+	}
+
+	content := fmt.Sprintf(`
+		<h1>Поручение №%d</h1>
+		<p>Сумма: <strong>%.2f RUB</strong></p>
+		<p>Дата перевода: <strong>%s</strong></p>
+		<p>%s</p>
+		<small>Это автоматическое уведомление</small>
+	`, order.ID, order.Amount, run.ScheduledFor.Format("02.01.2006"), result)
+
+	return s.mailer.Send(email, subject, content)
+}
+
+// SendPaymentRequestNotification уведомляет о запросе денег: плательщика
+// (incoming) - о новом запросе, обе стороны - о результате
+func (s *NotificationService) SendPaymentRequestNotification(email string, request *models.PaymentRequest, incoming bool) error {
+	var subject, result string
+	switch request.Status {
+	case models.PaymentRequestAccepted:
+		subject = "Запрос денег оплачен"
+		result = "Запрос оплачен."
+	case models.PaymentRequestDeclined:
+		subject = "Запрос денег отклонен"
+		result = "Плательщик отклонил запрос."
+	case models.PaymentRequestExpired:
+		subject = "Срок запроса денег истек"
+		result = "Запрос не был оплачен вовремя и закрыт."
+	default:
+		subject = "Вам пришел запрос денег"
+		result = fmt.Sprintf("%s просит перевести деньги. Запрос действует до <strong>%s</strong>.",
+			request.RequesterName, request.ExpiresAt.Format("02.01.2006 15:04"))
+	}
+	if !incoming && request.Status == models.PaymentRequestPending {
+		result = "Запрос отправлен."
+	}
+
+	content := fmt.Sprintf(`
+		<h1>Запрос денег №%d</h1>
+		<p>Сумма: <strong>%.2f RUB</strong></p>
+		<p>Комментарий: %s</p>
+		<p>%s</p>
+		<small>Это автоматическое уведомление</small>
+	`, request.ID, request.Amount, html.EscapeString(request.Note), result)
+
+	return s.mailer.Send(email, subject, content)
+}
+
+// SendCardRenewalNotification сообщает о выпуске карты взамен карты
+// с истекающим сроком
+func (s *NotificationService) SendCardRenewalNotification(email string, card *models.Card) error {
+	subject := "Выпущена новая карта"
+	content := fmt.Sprintf(`
+		<h1>Срок действия вашей карты подходит к концу</h1>
+		<p>Мы выпустили новую карту <strong>%s</strong> со сроком действия до <strong>%s</strong>.</p>
+		<p>Прежняя карта действует до окончания своего срока.</p>
+		<small>Это автоматическое уведомление</small>
+	`, html.EscapeString(card.Number), html.EscapeString(card.ExpiryDate))
+
+	return s.mailer.Send(email, subject, content)
+}
+
+func (s *NotificationService) SendCardExpiredNotification(email string, card *models.Card) error {
+	subject := "Срок действия карты истек"
+	content := fmt.Sprintf(`
+		<h1>Срок действия карты истек</h1>
+		<p>Операции по карте <strong>%s</strong> больше не проводятся.</p>
+		<small>Это автоматическое уведомление</small>
+	`, html.EscapeString(card.Number))
+
+	return s.mailer.Send(email, subject, content)
+}
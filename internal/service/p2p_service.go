@@ -0,0 +1,412 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/sms"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Время, в течение которого отправитель может подтвердить перевод
+	p2pConfirmationTTL = 5 * time.Minute
+
+	// Код подтверждения телефона действует ограниченное время и число
+	// попыток ввода
+	phoneCodeTTL         = 10 * time.Minute
+	phoneCodeMaxAttempts = 5
+)
+
+type P2PService struct {
+	p2pRepo        *repository.P2PRepository
+	userRepo       *repository.UserRepository
+	accountRepo    *repository.AccountRepository
+	accountService *AccountService
+	smsSender      sms.Sender
+}
+
+func NewP2PService(
+	p2pRepo *repository.P2PRepository,
+	userRepo *repository.UserRepository,
+	accountRepo *repository.AccountRepository,
+	accountService *AccountService,
+	smsSender sms.Sender,
+) *P2PService {
+	return &P2PService{
+		p2pRepo:        p2pRepo,
+		userRepo:       userRepo,
+		accountRepo:    accountRepo,
+		accountService: accountService,
+		smsSender:      smsSender,
+	}
+}
+
+func (s *P2PService) GetSettings(userID int) (*models.P2PSettings, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidUser
+	}
+
+	settings := &models.P2PSettings{
+		Phone:            user.Phone,
+		PhoneVerified:    user.PhoneVerified,
+		DefaultAccountID: user.DefaultAccountID,
+	}
+
+	verification, err := s.p2pRepo.GetPendingPhoneVerification(userID)
+	if err != nil {
+		return nil, err
+	}
+	if verification != nil && verification.ExpiresAt.After(time.Now()) {
+		settings.PendingPhone = verification.Phone
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings задает телефон, по которому клиенту можно перевести деньги,
+// и счет для зачисления таких переводов. Новый или еще не подтвержденный
+// телефон не сохраняется сразу: на него отправляется код, и номер начинает
+// действовать после ConfirmPhone. До этого действует прежний номер.
+func (s *P2PService) UpdateSettings(userID int, req *models.P2PSettings) (*models.P2PSettings, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidUser
+	}
+
+	phone, pending := "", ""
+	if strings.TrimSpace(req.Phone) != "" {
+		normalized, err := normalizePhone(req.Phone)
+		if err != nil {
+			return nil, err
+		}
+
+		taken, err := s.userRepo.IsPhoneTaken(normalized, userID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrPhoneTaken
+		}
+
+		if normalized == user.Phone && user.PhoneVerified {
+			phone = normalized
+		} else {
+			phone, pending = user.Phone, normalized
+		}
+	}
+
+	if req.DefaultAccountID != nil {
+		account, err := s.accountRepo.GetAccountByID(*req.DefaultAccountID)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil || account.UserID != userID {
+			return nil, ErrAccountNotFound
+		}
+		if err := checkCredit(account); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.UpdateP2PSettings(userID, phone, req.DefaultAccountID); err != nil {
+		return nil, err
+	}
+
+	if pending != "" {
+		if err := s.sendPhoneCode(userID, pending); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetSettings(userID)
+}
+
+// ConfirmPhone подтверждает телефон кодом из SMS и делает его номером для
+// переводов
+func (s *P2PService) ConfirmPhone(userID int, req *models.ConfirmPhoneRequest) (*models.P2PSettings, error) {
+	verification, err := s.p2pRepo.GetPendingPhoneVerification(userID)
+	if err != nil {
+		return nil, err
+	}
+	if verification == nil {
+		return nil, ErrPhoneCodeNotFound
+	}
+	if !verification.ExpiresAt.After(time.Now()) {
+		return nil, ErrPhoneCodeExpired
+	}
+
+	// Попытка учитывается до сравнения, чтобы параллельные запросы
+	// не обходили ограничение
+	counted, err := s.p2pRepo.AddPhoneVerificationAttempt(verification.ID, phoneCodeMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !counted {
+		return nil, ErrPhoneCodeExpired
+	}
+	if bcrypt.CompareHashAndPassword([]byte(verification.CodeHash), []byte(req.Code)) != nil {
+		return nil, ErrInvalidPhoneCode
+	}
+
+	taken, err := s.userRepo.IsPhoneTaken(verification.Phone, userID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrPhoneTaken
+	}
+
+	confirmed, err := s.p2pRepo.ConfirmPhoneVerification(verification.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrPhoneCodeNotFound
+	}
+	if err := s.userRepo.SetVerifiedPhone(userID, verification.Phone); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(userID)
+}
+
+// sendPhoneCode создает код подтверждения телефона и отправляет его в SMS.
+// Новый код заменяет все прежние неподтвержденные.
+func (s *P2PService) sendPhoneCode(userID int, phone string) error {
+	code, err := randomDigits(6)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	verification := &models.PhoneVerification{
+		UserID:    userID,
+		Phone:     phone,
+		CodeHash:  string(hash),
+		ExpiresAt: time.Now().Add(phoneCodeTTL),
+	}
+	if err := s.p2pRepo.CreatePhoneVerification(verification); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("Код подтверждения телефона для переводов: %s. Никому его не сообщайте.", code)
+	if err := s.smsSender.Send(phone, text); err != nil {
+		return fmt.Errorf("failed to send phone verification code: %w", err)
+	}
+	return nil
+}
+
+// CreateTransfer находит получателя по телефону или email и создает перевод,
+// ожидающий подтверждения. Отправителю возвращается маскированное имя
+// получателя, чтобы он мог убедиться, что переводит нужному человеку.
+func (s *P2PService) CreateTransfer(userID int, req *models.CreateP2PTransferRequest) (*models.P2PTransfer, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	fromAccount, err := s.accountRepo.GetAccountByID(req.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount == nil || fromAccount.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	if err := checkDebit(fromAccount, req.Amount); err != nil {
+		return nil, err
+	}
+
+	recipient, identifier, err := s.findRecipient(req.Recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	toAccount, err := s.receivingAccount(recipient)
+	if err != nil {
+		return nil, err
+	}
+	if toAccount.ID == fromAccount.ID {
+		return nil, ErrSameAccount
+	}
+
+	transfer := &models.P2PTransfer{
+		UserID:          userID,
+		FromAccountID:   fromAccount.ID,
+		RecipientUserID: recipient.ID,
+		ToAccountID:     toAccount.ID,
+		Recipient:       identifier,
+		RecipientName:   maskName(recipient.Username),
+		Amount:          req.Amount,
+		Description:     req.Description,
+		Status:          models.P2PTransferPending,
+		ExpiresAt:       time.Now().Add(p2pConfirmationTTL),
+	}
+
+	if err := s.p2pRepo.CreateTransfer(transfer); err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// ConfirmTransfer выполняет подтвержденный отправителем перевод
+func (s *P2PService) ConfirmTransfer(userID, transferID int) (*models.P2PTransfer, error) {
+	transfer, err := s.p2pRepo.GetTransferByID(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil || transfer.UserID != userID {
+		return nil, ErrTransferNotFound
+	}
+	if transfer.Status != models.P2PTransferPending {
+		return nil, ErrTransferNotPending
+	}
+	if !transfer.ExpiresAt.After(time.Now()) {
+		return nil, ErrTransferExpired
+	}
+
+	claimed, err := s.p2pRepo.ClaimPending(transfer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrTransferNotPending
+	}
+
+	description := transfer.Description
+	if description == "" {
+		description = fmt.Sprintf("Transfer to %s", transfer.Recipient)
+	}
+
+	transferErr := s.accountService.Transfer(&models.TransferRequest{
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		Description:   description,
+	})
+
+	transfer.Status = models.P2PTransferCompleted
+	if transferErr != nil {
+		transfer.Status = models.P2PTransferFailed
+		transfer.Error = transferErr.Error()
+	}
+	if err := s.p2pRepo.CompleteTransfer(transfer.ID, transfer.Status, transfer.Error); err != nil {
+		return nil, err
+	}
+
+	if transferErr != nil {
+		return nil, transferErr
+	}
+	return transfer, nil
+}
+
+// findRecipient ищет клиента по email или номеру телефона и возвращает его
+// вместе с нормализованным идентификатором
+func (s *P2PService) findRecipient(identifier string) (*models.User, string, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	var (
+		user *models.User
+		err  error
+	)
+	if strings.Contains(identifier, "@") {
+		user, err = s.userRepo.GetUserByEmail(identifier)
+	} else {
+		if identifier, err = normalizePhone(identifier); err != nil {
+			return nil, "", err
+		}
+		user, err = s.userRepo.GetUserByPhone(identifier)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if user == nil {
+		return nil, "", ErrRecipientNotFound
+	}
+
+	return user, identifier, nil
+}
+
+// receivingAccount возвращает счет для зачисления перевода: выбранный
+// получателем счет по умолчанию или его первый действующий текущий счет
+func (s *P2PService) receivingAccount(user *models.User) (*models.Account, error) {
+	if user.DefaultAccountID != nil {
+		account, err := s.accountRepo.GetAccountByID(*user.DefaultAccountID)
+		if err != nil {
+			return nil, err
+		}
+		if account != nil && account.UserID == user.ID && account.Status != models.AccountStatusClosed {
+			return account, nil
+		}
+	}
+
+	accounts, err := s.accountRepo.GetAccountsByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Type == models.AccountTypeCurrent && account.Status == models.AccountStatusActive {
+			return account, nil
+		}
+	}
+
+	return nil, ErrNoReceivingAccount
+}
+
+// normalizePhone приводит российский номер к виду +7XXXXXXXXXX
+func normalizePhone(phone string) (string, error) {
+	var digits strings.Builder
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' || r == '-' || r == '(' || r == ')' || unicode.IsSpace(r):
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case len(number) == 10:
+		number = "7" + number
+	case len(number) == 11 && number[0] == '8':
+		number = "7" + number[1:]
+	}
+	if len(number) != 11 || number[0] != '7' {
+		return "", ErrInvalidPhone
+	}
+
+	return "+" + number, nil
+}
+
+// maskName скрывает имя получателя так же, как СБП: имя показывается
+// полностью, от фамилии остается первая буква ("Иван И.")
+func maskName(name string) string {
+	parts := strings.Fields(name)
+	if len(parts) == 0 {
+		return ""
+	}
+	if len(parts) == 1 {
+		first, size := utf8.DecodeRuneInString(parts[0])
+		return string(unicode.ToUpper(first)) + strings.Repeat("*", utf8.RuneCountInString(parts[0][size:]))
+	}
+
+	last, _ := utf8.DecodeRuneInString(parts[len(parts)-1])
+	return parts[0] + " " + string(unicode.ToUpper(last)) + "."
+}
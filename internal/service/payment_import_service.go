@@ -0,0 +1,290 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/iso20022"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Загрузка в статусе processing, которая не обновлялась дольше этого срока,
+// считается прерванной сбоем, и повторная загрузка ее продолжает
+const staleImportTTL = 15 * time.Minute
+
+type PaymentImportService struct {
+	importRepo     *repository.PaymentImportRepository
+	accountService *AccountService
+	db             *sql.DB
+}
+
+func NewPaymentImportService(
+	importRepo *repository.PaymentImportRepository,
+	accountService *AccountService,
+	db *sql.DB,
+) *PaymentImportService {
+	return &PaymentImportService{
+		importRepo:     importRepo,
+		accountService: accountService,
+		db:             db,
+	}
+}
+
+// ImportCreditTransfers исполняет переводы из файла pain.001 и возвращает
+// отчет pain.002 со статусом каждой строки. Повторная загрузка файла с тем же
+// MsgId не исполняет переводы заново, а возвращает сохраненный отчет. Если
+// исполнение прервалось, повторная загрузка продолжает его: статус каждой
+// строки сохраняется вместе с переводом, и исполненные строки пропускаются.
+func (s *PaymentImportService) ImportCreditTransfers(userID int, data []byte) ([]byte, error) {
+	msg, err := iso20022.ParseCreditTransferInitiation(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// MsgId занимается до исполнения, чтобы параллельная загрузка того же
+	// файла не провела переводы второй раз
+	paymentImport := &models.PaymentImport{
+		UserID:    userID,
+		MessageID: msg.MessageID,
+		Status:    models.PaymentImportProcessing,
+	}
+	reserved, err := s.importRepo.ReserveImport(paymentImport)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		existing, err := s.importRepo.GetImportByMessageID(userID, msg.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrImportInProgress
+		}
+		if existing.Status != models.PaymentImportProcessing {
+			return existing.Report, nil
+		}
+		claimed, err := s.importRepo.ClaimStaleImport(existing, time.Now().Add(-staleImportTTL))
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, ErrImportInProgress
+		}
+		paymentImport = existing
+	}
+
+	saved, err := s.importRepo.GetTransactions(paymentImport.ID)
+	if err != nil {
+		return nil, err
+	}
+	executed := make(map[string]*models.PaymentImportTransaction, len(saved))
+	for _, transaction := range saved {
+		executed[transaction.StatusID] = transaction
+	}
+
+	report := &iso20022.StatusReport{
+		MessageID:           fmt.Sprintf("STS-%s", msg.MessageID),
+		CreatedAt:           time.Now(),
+		OriginalMessageID:   msg.MessageID,
+		OriginalNumberOfTxs: msg.NumberOfTxs,
+		OriginalControlSum:  msg.ControlSum,
+	}
+
+	if err := msg.Validate(); err != nil {
+		// Файл с нарушенной структурой отклоняется целиком
+		report.GroupStatus = iso20022.StatusRejected
+		report.GroupReason = iso20022.ReasonFormatError
+		report.GroupInfo = err.Error()
+	} else {
+		var statuses []string
+		for _, info := range msg.PaymentInfos {
+			infoStatus := s.executePaymentInfo(userID, paymentImport.ID, info, executed)
+			report.PaymentInfos = append(report.PaymentInfos, infoStatus)
+			for _, tx := range infoStatus.Transactions {
+				statuses = append(statuses, tx.Status)
+			}
+		}
+		report.GroupStatus = iso20022.AggregateStatus(statuses)
+	}
+
+	reportData, err := report.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	paymentImport.Status = report.GroupStatus
+	paymentImport.Report = reportData
+	if err := s.importRepo.CompleteImport(paymentImport); err != nil {
+		// Переводы уже исполнены, поэтому отчет клиенту все равно отдаем.
+		// Загрузка останется в статусе processing, и повторная загрузка
+		// соберет отчет из сохраненных статусов строк.
+		log.Printf("Failed to save payment import %s: %v", msg.MessageID, err)
+	}
+
+	return reportData, nil
+}
+
+// executePaymentInfo исполняет строки блока PmtInf. Строки, статус которых
+// уже сохранен, повторно не исполняются.
+func (s *PaymentImportService) executePaymentInfo(
+	userID, importID int,
+	info iso20022.PaymentInfo,
+	executed map[string]*models.PaymentImportTransaction,
+) iso20022.PaymentInfoStatus {
+	result := iso20022.PaymentInfoStatus{OriginalPaymentInfoID: info.ID}
+	dateReason, dateDetail := checkExecutionDate(info.ExecutionDate, time.Now())
+
+	debtor, err := s.accountService.ResolveAccount(info.DebtorAccount)
+	if err != nil {
+		log.Printf("Failed to resolve debtor account %s: %v", info.DebtorAccount, err)
+	}
+	if debtor != nil && debtor.UserID != userID {
+		debtor = nil
+	}
+
+	var statuses []string
+	for i, transfer := range info.CreditTransfer {
+		status := iso20022.TransactionStatus{
+			StatusID:              fmt.Sprintf("%s-%d", info.ID, i+1),
+			OriginalInstructionID: transfer.InstructionID,
+			OriginalEndToEndID:    transfer.EndToEndID,
+			Status:                iso20022.StatusAcceptedSettled,
+		}
+
+		if transaction, ok := executed[status.StatusID]; ok {
+			status.Status = transaction.Status
+			status.Reason = transaction.Reason
+			status.AdditionalInfo = transaction.Info
+		} else {
+			var reason, detail string
+			switch {
+			case dateReason != "":
+				reason, detail = dateReason, dateDetail
+			case debtor == nil:
+				reason, detail = iso20022.ReasonIncorrectAccount, "debtor account not found"
+			default:
+				reason, detail = s.executeTransfer(importID, status.StatusID, debtor, transfer)
+			}
+			if reason != "" {
+				status.Status = iso20022.StatusRejected
+				status.Reason = reason
+				status.AdditionalInfo = detail
+				s.saveRejection(importID, status)
+			}
+		}
+
+		statuses = append(statuses, status.Status)
+		result.Transactions = append(result.Transactions, status)
+	}
+
+	result.Status = iso20022.AggregateStatus(statuses)
+	return result
+}
+
+// checkExecutionDate проверяет запрошенную дату исполнения (ReqdExctnDt).
+// Поручения исполняются сразу при загрузке, поэтому дата позже сегодняшней
+// отклоняется, а не исполняется досрочно.
+func checkExecutionDate(date string, now time.Time) (string, string) {
+	if date == "" {
+		return "", ""
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, now.Location())
+	if err != nil {
+		return iso20022.ReasonInvalidDate, "invalid requested execution date"
+	}
+	if day.After(now) {
+		return iso20022.ReasonInvalidDate, "future-dated execution is not supported"
+	}
+	return "", ""
+}
+
+// saveRejection сохраняет статус отклоненной строки. Перевода по ней нет,
+// поэтому при ошибке строка просто будет проверена заново.
+func (s *PaymentImportService) saveRejection(importID int, status iso20022.TransactionStatus) {
+	_, err := s.importRepo.SaveTransaction(nil, &models.PaymentImportTransaction{
+		ImportID: importID,
+		StatusID: status.StatusID,
+		Status:   status.Status,
+		Reason:   status.Reason,
+		Info:     status.AdditionalInfo,
+	})
+	if err != nil {
+		log.Printf("Failed to save payment import transaction %s: %v", status.StatusID, err)
+	}
+}
+
+// executeTransfer проводит одну строку поручения и в той же транзакции
+// сохраняет ее статус. Возвращает код причины отказа и пояснение либо пустые
+// строки при успехе.
+func (s *PaymentImportService) executeTransfer(importID int, statusID string, debtor *models.Account, transfer iso20022.CreditTransfer) (string, string) {
+	if transfer.Amount <= 0 {
+		return iso20022.ReasonInvalidAmount, "amount must be positive"
+	}
+	if transfer.Currency != debtor.Currency {
+		return iso20022.ReasonNotAllowedCurrency, "currency does not match debtor account"
+	}
+
+	creditor, err := s.accountService.ResolveAccount(transfer.CreditorAccount)
+	if err != nil {
+		return iso20022.ReasonNarrative, "failed to resolve creditor account"
+	}
+	if creditor == nil {
+		return iso20022.ReasonIncorrectAccount, "creditor account not found"
+	}
+
+	description := transfer.RemittanceInfo
+	if description == "" {
+		description = transfer.EndToEndID
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Printf("Failed to execute transfer %s: %v", transfer.EndToEndID, err)
+		return iso20022.ReasonNarrative, "transfer failed"
+	}
+	defer tx.Rollback()
+
+	err = s.accountService.transfer(tx, &models.TransferRequest{
+		FromAccountID: debtor.ID,
+		ToAccountID:   creditor.ID,
+		Amount:        transfer.Amount,
+		Description:   description,
+	})
+	if err == nil {
+		var saved bool
+		saved, err = s.importRepo.SaveTransaction(tx, &models.PaymentImportTransaction{
+			ImportID: importID,
+			StatusID: statusID,
+			Status:   iso20022.StatusAcceptedSettled,
+		})
+		if err == nil && !saved {
+			// Строку уже исполнила перехватившая загрузку копия запроса
+			return iso20022.ReasonDuplicate, "transaction is already executed"
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+
+	switch err {
+	case nil:
+		return "", ""
+	case ErrAccountNotFound:
+		return iso20022.ReasonIncorrectAccount, "account not found"
+	case ErrInsufficientFunds:
+		return iso20022.ReasonInsufficientFunds, "insufficient funds"
+	case ErrAccountFrozen:
+		return iso20022.ReasonBlockedAccount, "account is frozen"
+	case ErrAccountClosed:
+		return iso20022.ReasonClosedAccount, "account is closed"
+	case ErrSameAccount:
+		return iso20022.ReasonIncorrectAccount, "debtor and creditor accounts are the same"
+	case ErrDailyLimitExceeded, ErrMonthlyLimitExceeded, ErrDailyCountExceeded, ErrMonthlyCountExceeded:
+		return iso20022.ReasonLimitExceeded, err.Error()
+	default:
+		log.Printf("Failed to execute transfer %s: %v", transfer.EndToEndID, err)
+		return iso20022.ReasonNarrative, "transfer failed"
+	}
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+type PaymentRequestService struct {
+	requestRepo     *repository.PaymentRequestRepository
+	userRepo        *repository.UserRepository
+	accountRepo     *repository.AccountRepository
+	accountService  *AccountService
+	notificationSvc *NotificationService
+	ttl             time.Duration
+}
+
+func NewPaymentRequestService(
+	requestRepo *repository.PaymentRequestRepository,
+	userRepo *repository.UserRepository,
+	accountRepo *repository.AccountRepository,
+	accountService *AccountService,
+	notificationSvc *NotificationService,
+	ttl time.Duration,
+) *PaymentRequestService {
+	return &PaymentRequestService{
+		requestRepo:     requestRepo,
+		userRepo:        userRepo,
+		accountRepo:     accountRepo,
+		accountService:  accountService,
+		notificationSvc: notificationSvc,
+		ttl:             ttl,
+	}
+}
+
+// CreateRequest выставляет запрос денег другому клиенту по email или номеру
+// счета. Плательщик получает уведомление о запросе.
+func (s *PaymentRequestService) CreateRequest(userID int, req *models.CreatePaymentRequestRequest) (*models.PaymentRequest, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	toAccount, err := s.accountRepo.GetAccountByID(req.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if toAccount == nil || toAccount.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	if err := checkCredit(toAccount); err != nil {
+		return nil, err
+	}
+
+	requester, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if requester == nil {
+		return nil, ErrInvalidUser
+	}
+
+	request := &models.PaymentRequest{
+		RequesterID:   userID,
+		ToAccountID:   toAccount.ID,
+		RequesterName: maskName(requester.Username),
+		Payer:         strings.TrimSpace(req.Payer),
+		Amount:        req.Amount,
+		Note:          req.Note,
+		Status:        models.PaymentRequestPending,
+		ExpiresAt:     time.Now().Add(s.ttl),
+	}
+
+	payer, err := s.findPayer(request)
+	if err != nil {
+		return nil, err
+	}
+	if payer.ID == userID {
+		return nil, ErrSelfRequest
+	}
+	request.PayerUserID = payer.ID
+
+	if err := s.requestRepo.CreateRequest(request); err != nil {
+		return nil, err
+	}
+
+	s.notify(payer, request)
+	return request, nil
+}
+
+func (s *PaymentRequestService) GetOutgoingRequests(userID int) ([]*models.PaymentRequest, error) {
+	return s.requestRepo.GetOutgoingRequests(userID)
+}
+
+func (s *PaymentRequestService) GetIncomingRequests(userID int) ([]*models.PaymentRequest, error) {
+	return s.requestRepo.GetIncomingRequests(userID)
+}
+
+// GetRequest возвращает запрос отправителю или плательщику
+func (s *PaymentRequestService) GetRequest(userID, requestID int) (*models.PaymentRequest, error) {
+	request, err := s.requestRepo.GetRequestByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || (request.RequesterID != userID && request.PayerUserID != userID) {
+		return nil, ErrRequestNotFound
+	}
+
+	return request, nil
+}
+
+// AcceptRequest оплачивает запрос переводом со счета плательщика. Если
+// перевод не прошел, запрос остается ожидающим и его можно оплатить повторно.
+func (s *PaymentRequestService) AcceptRequest(userID, requestID int, req *models.AcceptPaymentRequestRequest) (*models.PaymentRequest, error) {
+	request, err := s.getIncoming(userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromAccountID := req.FromAccountID
+	if fromAccountID == 0 && request.FromAccountID != nil {
+		fromAccountID = *request.FromAccountID
+	}
+	fromAccount, err := s.accountRepo.GetAccountByID(fromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount == nil || fromAccount.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	claimed, err := s.requestRepo.SetStatus(request.ID, models.PaymentRequestPending, models.PaymentRequestProcessing)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrRequestNotPending
+	}
+
+	description := request.Note
+	if description == "" {
+		description = fmt.Sprintf("Payment request #%d", request.ID)
+	}
+
+	transferErr := s.accountService.Transfer(&models.TransferRequest{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   request.ToAccountID,
+		Amount:        request.Amount,
+		Description:   description,
+	})
+	if transferErr != nil {
+		if _, err := s.requestRepo.SetStatus(request.ID, models.PaymentRequestProcessing, models.PaymentRequestPending); err != nil {
+			log.Printf("Failed to return payment request %d to pending: %v", request.ID, err)
+		}
+		return nil, transferErr
+	}
+
+	if err := s.respond(request, models.PaymentRequestAccepted, &fromAccount.ID); err != nil {
+		return nil, err
+	}
+
+	s.notifyUser(request.RequesterID, request)
+	s.notifyUser(request.PayerUserID, request)
+	return request, nil
+}
+
+// DeclineRequest отклоняет запрос; отправитель получает уведомление
+func (s *PaymentRequestService) DeclineRequest(userID, requestID int) (*models.PaymentRequest, error) {
+	request, err := s.getIncoming(userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed, err := s.requestRepo.SetStatus(request.ID, models.PaymentRequestPending, models.PaymentRequestDeclined)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrRequestNotPending
+	}
+
+	if err := s.respond(request, models.PaymentRequestDeclined, request.FromAccountID); err != nil {
+		return nil, err
+	}
+
+	s.notifyUser(request.RequesterID, request)
+	return request, nil
+}
+
+// ExpireRequests закрывает просроченные запросы и уведомляет обе стороны.
+// Вызывается шедулером.
+func (s *PaymentRequestService) ExpireRequests(now time.Time) error {
+	expired, err := s.requestRepo.ExpireRequests(now)
+	if err != nil {
+		return err
+	}
+
+	for _, request := range expired {
+		s.notifyUser(request.RequesterID, request)
+		s.notifyUser(request.PayerUserID, request)
+	}
+	return nil
+}
+
+// getIncoming возвращает ожидающий ответа запрос, выставленный пользователю
+func (s *PaymentRequestService) getIncoming(userID, requestID int) (*models.PaymentRequest, error) {
+	request, err := s.requestRepo.GetRequestByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || request.PayerUserID != userID {
+		return nil, ErrRequestNotFound
+	}
+	if request.Status != models.PaymentRequestPending {
+		return nil, ErrRequestNotPending
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return nil, ErrRequestExpired
+	}
+
+	return request, nil
+}
+
+func (s *PaymentRequestService) respond(request *models.PaymentRequest, status models.PaymentRequestStatus, fromAccountID *int) error {
+	now := time.Now()
+	request.Status = status
+	request.FromAccountID = fromAccountID
+	request.RespondedAt = &now
+
+	return s.requestRepo.CompleteRequest(request)
+}
+
+// findPayer находит плательщика по email или номеру счета. Если указан
+// счет, списание по умолчанию будет с него.
+func (s *PaymentRequestService) findPayer(request *models.PaymentRequest) (*models.User, error) {
+	if strings.Contains(request.Payer, "@") {
+		user, err := s.userRepo.GetUserByEmail(request.Payer)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrRecipientNotFound
+		}
+		return user, nil
+	}
+
+	account, err := s.accountService.GetAccountByNumber(request.Payer)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrRecipientNotFound
+	}
+	request.FromAccountID = &account.ID
+
+	user, err := s.userRepo.GetUserByID(account.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrRecipientNotFound
+	}
+	return user, nil
+}
+
+func (s *PaymentRequestService) notifyUser(userID int, request *models.PaymentRequest) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil || user == nil {
+		log.Printf("Failed to find user %d for payment request %d notification: %v", userID, request.ID, err)
+		return
+	}
+	s.notify(user, request)
+}
+
+func (s *PaymentRequestService) notify(user *models.User, request *models.PaymentRequest) {
+	incoming := user.ID == request.PayerUserID
+	if err := s.notificationSvc.SendPaymentRequestNotification(user.Email, request, incoming); err != nil {
+		log.Printf("Failed to send payment request notification: %v", err)
+	}
+}
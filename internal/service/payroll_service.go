@@ -0,0 +1,534 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Максимальное число строк в одной ведомости
+const maxPayrollItems = 5000
+
+// Ведомость в статусе processing, которая не обновлялась дольше этого срока,
+// считается прерванной сбоем, и повторная загрузка ее продолжает
+const stalePayrollTTL = 15 * time.Minute
+
+type PayrollService struct {
+	payrollRepo     *repository.PayrollRepository
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	accountService  *AccountService
+	limitService    *LimitService
+	db              *sql.DB
+}
+
+func NewPayrollService(
+	payrollRepo *repository.PayrollRepository,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	accountService *AccountService,
+	limitService *LimitService,
+	db *sql.DB,
+) *PayrollService {
+	return &PayrollService{
+		payrollRepo:     payrollRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		accountService:  accountService,
+		limitService:    limitService,
+		db:              db,
+	}
+}
+
+// ParsePayrollCSV разбирает ведомость в CSV. Первая строка - заголовок
+// с колонками account, amount и необязательной description; разделитель -
+// запятая или точка с запятой. При разделителе ";" допускается десятичная
+// запятая в суммах.
+func ParsePayrollCSV(data []byte) ([]models.PayrollItemRequest, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	columns, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidPayrollFile)
+	}
+	index := map[string]int{"description": -1}
+	for i, name := range columns {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "to_account" {
+			name = "account"
+		}
+		index[name] = i
+	}
+	if _, ok := index["account"]; !ok {
+		return nil, fmt.Errorf("%w: missing account column", ErrInvalidPayrollFile)
+	}
+	if _, ok := index["amount"]; !ok {
+		return nil, fmt.Errorf("%w: missing amount column", ErrInvalidPayrollFile)
+	}
+
+	var items []models.PayrollItemRequest
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidPayrollFile, line, err)
+		}
+
+		rawAmount := strings.TrimSpace(record[index["amount"]])
+		if reader.Comma == ';' {
+			rawAmount = strings.Replace(rawAmount, ",", ".", 1)
+		}
+		amount, err := strconv.ParseFloat(rawAmount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid amount %q", ErrInvalidPayrollFile, line, rawAmount)
+		}
+
+		item := models.PayrollItemRequest{
+			ToAccount: strings.TrimSpace(record[index["account"]]),
+			Amount:    amount,
+		}
+		if i := index["description"]; i >= 0 {
+			item.Description = strings.TrimSpace(record[i])
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// SubmitPayroll проверяет ведомость и исполняет переводы. Перед исполнением
+// проверяются все строки и достаточность средств на счете списания.
+// В режиме all_or_nothing любая ошибка отклоняет ведомость целиком, в режиме
+// best_effort исполняются все строки, прошедшие проверку. Если исполнение
+// прервалось, повторная загрузка продолжает его с неисполненных строк.
+func (s *PayrollService) SubmitPayroll(userID int, req *models.PayrollRequest) (*models.PayrollBatch, error) {
+	if req.Mode == "" {
+		req.Mode = models.PayrollAllOrNothing
+	}
+	if req.Mode != models.PayrollAllOrNothing && req.Mode != models.PayrollBestEffort {
+		return nil, ErrInvalidPayrollMode
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: no transfers", ErrInvalidPayrollFile)
+	}
+	if len(req.Items) > maxPayrollItems {
+		return nil, fmt.Errorf("%w: more than %d transfers", ErrInvalidPayrollFile, maxPayrollItems)
+	}
+
+	fromAccount, err := s.accountRepo.GetAccountByID(req.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount == nil || fromAccount.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	if err := checkActive(fromAccount); err != nil {
+		return nil, err
+	}
+
+	batch := &models.PayrollBatch{
+		UserID:        userID,
+		FromAccountID: fromAccount.ID,
+		Reference:     req.Reference,
+		Mode:          req.Mode,
+		Status:        models.PayrollProcessing,
+		ItemCount:     len(req.Items),
+	}
+	for i, line := range req.Items {
+		batch.Items = append(batch.Items, &models.PayrollItem{
+			Line:        i + 1,
+			ToAccount:   strings.TrimSpace(line.ToAccount),
+			Amount:      line.Amount,
+			Description: line.Description,
+			Status:      models.PayrollItemPending,
+		})
+		batch.TotalAmount += line.Amount
+	}
+	batch.TotalAmount = roundKopecks(batch.TotalAmount)
+
+	// Ведомость сохраняется до исполнения, чтобы повторная загрузка с тем же
+	// идентификатором, в том числе параллельная, не провела переводы дважды
+	created, err := s.payrollRepo.CreateBatch(batch)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		existing, err := s.payrollRepo.GetBatchByReference(userID, req.Reference)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrPayrollInProgress
+		}
+		if existing.Items, err = s.payrollRepo.GetItemsByBatch(existing.ID); err != nil {
+			return nil, err
+		}
+		if existing.Status != models.PayrollProcessing {
+			return existing, nil
+		}
+
+		// Исполнение прервалось сбоем. Исполненные строки отмечены вместе
+		// с переводами, поэтому продолжаем с остальных.
+		claimed, err := s.payrollRepo.ClaimStaleBatch(existing.ID, time.Now().Add(-stalePayrollTTL))
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, ErrPayrollInProgress
+		}
+		if existing.FromAccountID != fromAccount.ID {
+			if fromAccount, err = s.accountRepo.GetAccountByID(existing.FromAccountID); err != nil {
+				return nil, err
+			}
+			if fromAccount == nil {
+				return nil, ErrAccountNotFound
+			}
+		}
+		batch = existing
+	}
+
+	var valid, pending []*models.PayrollItem
+	for _, item := range batch.Items {
+		if item.Status != models.PayrollItemPending {
+			continue
+		}
+		pending = append(pending, item)
+		if err := s.validateItem(fromAccount, item); err != nil {
+			item.Status = models.PayrollItemFailed
+			item.Error = err.Error()
+			continue
+		}
+		valid = append(valid, item)
+	}
+
+	if batch.Mode == models.PayrollAllOrNothing {
+		switch {
+		case len(valid) < len(pending):
+			batch.Error = fmt.Sprintf("%d of %d lines failed validation", len(pending)-len(valid), len(batch.Items))
+			skipPayrollItems(valid)
+		case len(valid) == 0:
+			// Все строки исполнены до того, как исполнение прервалось
+		case fromAccount.AvailableBalance < payrollTotal(valid):
+			batch.Error = fmt.Sprintf("insufficient funds: total %.2f, available %.2f", payrollTotal(valid), fromAccount.AvailableBalance)
+			skipPayrollItems(valid)
+		default:
+			if err := s.executeAtomic(batch, fromAccount.ID, valid); err != nil {
+				// Переводы не проведены: ведомость отклоняется, чтобы ее
+				// идентификатор не остался занятым
+				log.Printf("Failed to execute payroll batch %d: %v", batch.ID, err)
+				batch.Error = "failed to execute transfers"
+				skipPayrollItems(valid)
+			}
+		}
+	} else if len(valid) > 0 {
+		// Ведомость проверяется по лимитам как одна операция на общую сумму
+		if err := s.limitService.CheckAccount(nil, fromAccount, payrollTotal(valid)); err != nil {
+			if !isPayrollLineError(err) {
+				return nil, err
+			}
+			batch.Error = err.Error()
+			for _, item := range valid {
+				item.Status = models.PayrollItemFailed
+				item.Error = err.Error()
+			}
+		} else {
+			for _, item := range valid {
+				s.executeItem(fromAccount.ID, item)
+			}
+		}
+	}
+
+	for _, item := range batch.Items {
+		switch item.Status {
+		case models.PayrollItemCompleted:
+			batch.CompletedCount++
+		case models.PayrollItemFailed:
+			batch.FailedCount++
+		}
+	}
+	switch {
+	case batch.CompletedCount == batch.ItemCount:
+		batch.Status = models.PayrollCompleted
+	case batch.CompletedCount > 0:
+		batch.Status = models.PayrollPartial
+	default:
+		batch.Status = models.PayrollRejected
+	}
+
+	if err := s.payrollRepo.CompleteBatch(batch); err != nil {
+		// Переводы уже исполнены, поэтому результат клиенту все равно отдаем.
+		// Ведомость останется в статусе processing, и повторная загрузка
+		// завершит ее по сохраненным строкам.
+		log.Printf("Failed to save payroll batch %d: %v", batch.ID, err)
+	}
+
+	return batch, nil
+}
+
+func (s *PayrollService) GetUserBatches(userID int) ([]*models.PayrollBatch, error) {
+	return s.payrollRepo.GetBatchesByUser(userID)
+}
+
+// GetBatch возвращает ведомость с результатами по строкам
+func (s *PayrollService) GetBatch(userID, batchID int) (*models.PayrollBatch, error) {
+	batch, err := s.payrollRepo.GetBatchByID(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil || batch.UserID != userID {
+		return nil, ErrPayrollNotFound
+	}
+
+	batch.Items, err = s.payrollRepo.GetItemsByBatch(batch.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// RenderReport формирует отчет об исполнении ведомости в CSV
+func (s *PayrollService) RenderReport(batch *models.PayrollBatch) (*StatementFile, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	writer.Write([]string{"line", "account", "amount", "description", "status", "error", "transaction_id"})
+	for _, item := range batch.Items {
+		transactionID := ""
+		if item.TransactionID != nil {
+			transactionID = strconv.Itoa(*item.TransactionID)
+		}
+		writer.Write([]string{
+			strconv.Itoa(item.Line),
+			item.ToAccount,
+			strconv.FormatFloat(item.Amount, 'f', 2, 64),
+			item.Description,
+			string(item.Status),
+			item.Error,
+			transactionID,
+		})
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return &StatementFile{
+		Filename:    fmt.Sprintf("payroll_%d_report.csv", batch.ID),
+		ContentType: "text/csv; charset=utf-8",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// validateItem проверяет сумму и счет получателя строки
+func (s *PayrollService) validateItem(fromAccount *models.Account, item *models.PayrollItem) error {
+	if item.Amount <= 0 || roundKopecks(item.Amount) != item.Amount {
+		return ErrInvalidAmount
+	}
+
+	toAccount, err := s.accountService.ResolveAccount(item.ToAccount)
+	if err != nil {
+		return err
+	}
+	if toAccount == nil {
+		return ErrAccountNotFound
+	}
+	if toAccount.ID == fromAccount.ID {
+		return ErrSameAccount
+	}
+	if err := checkCredit(toAccount); err != nil {
+		return err
+	}
+
+	item.ToAccountID = &toAccount.ID
+	return nil
+}
+
+// executeAtomic проводит все строки в одной транзакции. При ошибке в любой
+// строке ни один перевод не проводится, а причина записывается в ведомость.
+func (s *PayrollService) executeAtomic(batch *models.PayrollBatch, fromAccountID int, items []*models.PayrollItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Блокируем счета в порядке возрастания ID, как и при одиночных переводах
+	ids := []int{fromAccountID}
+	for _, item := range items {
+		ids = append(ids, *item.ToAccountID)
+	}
+	sort.Ints(ids)
+
+	accounts := make(map[int]*models.Account)
+	for _, id := range ids {
+		if _, ok := accounts[id]; ok {
+			continue
+		}
+		account, err := s.accountRepo.GetAccountByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return ErrAccountNotFound
+		}
+		accounts[id] = account
+	}
+
+	// Ведомость проверяется по лимитам как одна операция на общую сумму
+	fromAccount := accounts[fromAccountID]
+	if err := s.limitService.CheckAccount(tx, fromAccount, payrollTotal(items)); err != nil {
+		if !isPayrollLineError(err) {
+			return err
+		}
+		batch.Error = err.Error()
+		skipPayrollItems(items)
+		return nil
+	}
+
+	debits := make([]*models.Transaction, len(items))
+	for i, item := range items {
+		toAccount := accounts[*item.ToAccountID]
+		debit, err := postTransfer(tx, s.accountRepo, s.transactionRepo, fromAccount, toAccount, item.Amount, payrollDescription(item))
+		if err != nil {
+			if !isPayrollLineError(err) {
+				return err
+			}
+			item.Status = models.PayrollItemFailed
+			item.Error = err.Error()
+			batch.Error = fmt.Sprintf("line %d failed: %v", item.Line, err)
+			skipPayrollItems(items)
+			return nil
+		}
+		debits[i] = debit
+
+		// Строка отмечается исполненной в транзакции перевода
+		saved, err := s.payrollRepo.CompleteItem(tx, item, debit.ID)
+		if err != nil {
+			return err
+		}
+		if !saved {
+			return ErrPayrollInProgress
+		}
+
+		// postTransfer не меняет загруженные остатки, а следующие строки
+		// проверяются по ним
+		fromAccount.Balance -= item.Amount
+		fromAccount.AvailableBalance -= item.Amount
+		toAccount.Balance += item.Amount
+		toAccount.AvailableBalance += item.Amount
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for i, item := range items {
+		item.Status = models.PayrollItemCompleted
+		item.TransactionID = &debits[i].ID
+	}
+	return nil
+}
+
+// executeItem проводит одну строку в отдельной транзакции
+func (s *PayrollService) executeItem(fromAccountID int, item *models.PayrollItem) {
+	debit, err := s.transfer(fromAccountID, item)
+	if err != nil {
+		if !isPayrollLineError(err) {
+			log.Printf("Failed to execute payroll line %d: %v", item.Line, err)
+			err = errors.New("transfer failed")
+		}
+		item.Status = models.PayrollItemFailed
+		item.Error = err.Error()
+		return
+	}
+
+	item.Status = models.PayrollItemCompleted
+	item.TransactionID = &debit.ID
+}
+
+func (s *PayrollService) transfer(fromAccountID int, item *models.PayrollItem) (*models.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Лимиты проверены для ведомости в целом
+	fromAccount, toAccount, err := lockTransferAccounts(tx, s.accountRepo, fromAccountID, *item.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	debit, err := postTransfer(tx, s.accountRepo, s.transactionRepo, fromAccount, toAccount, item.Amount, payrollDescription(item))
+	if err != nil {
+		return nil, err
+	}
+
+	// Строка отмечается исполненной в транзакции перевода
+	saved, err := s.payrollRepo.CompleteItem(tx, item, debit.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrPayrollInProgress
+	}
+
+	return debit, tx.Commit()
+}
+
+func payrollDescription(item *models.PayrollItem) string {
+	if item.Description != "" {
+		return item.Description
+	}
+	return "Payroll payment"
+}
+
+// payrollTotal возвращает общую сумму строк ведомости
+func payrollTotal(items []*models.PayrollItem) float64 {
+	var total float64
+	for _, item := range items {
+		total += item.Amount
+	}
+	return roundKopecks(total)
+}
+
+// skipPayrollItems помечает неисполненные строки отклоненной ведомости
+func skipPayrollItems(items []*models.PayrollItem) {
+	for _, item := range items {
+		if item.Status == models.PayrollItemPending {
+			item.Status = models.PayrollItemSkipped
+		}
+	}
+}
+
+// isPayrollLineError отделяет отказы по строке ведомости от сбоев БД
+func isPayrollLineError(err error) bool {
+	switch err {
+	case ErrAccountNotFound, ErrInsufficientFunds, ErrInvalidAmount, ErrSameAccount, ErrAccountFrozen, ErrAccountClosed:
+		return true
+	case ErrDailyLimitExceeded, ErrMonthlyLimitExceeded, ErrDailyCountExceeded, ErrMonthlyCountExceeded:
+		return true
+	}
+	return false
+}
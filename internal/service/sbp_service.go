@@ -0,0 +1,506 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/crypto"
+	"bank-api/pkg/sbp"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Срок действия динамического QR-кода по умолчанию
+	defaultDynamicQRTTL = 72 * time.Hour
+	maxSBPPurposeLength = 140
+
+	sbpCurrency = "RUB"
+
+	// Уведомления ТСП повторяются с растущей паузой
+	sbpCallbackAttempts = 3
+	sbpCallbackDelay    = 2 * time.Second
+)
+
+var sbpQRTypes = map[string]sbp.QRType{
+	"static":  sbp.QRTypeStatic,
+	"dynamic": sbp.QRTypeDynamic,
+}
+
+type SBPService struct {
+	sbpRepo        *repository.SBPRepository
+	accountRepo    *repository.AccountRepository
+	accountService *AccountService
+	nspk           sbp.NSPK
+	bankID         string
+	httpClient     *http.Client
+}
+
+func NewSBPService(
+	sbpRepo *repository.SBPRepository,
+	accountRepo *repository.AccountRepository,
+	accountService *AccountService,
+	nspk sbp.NSPK,
+	bankID string,
+) *SBPService {
+	return &SBPService{
+		sbpRepo:        sbpRepo,
+		accountRepo:    accountRepo,
+		accountService: accountService,
+		nspk:           nspk,
+		bankID:         bankID,
+		httpClient:     newCallbackClient(),
+	}
+}
+
+// RegisterMerchant регистрирует ТСП в НСПК. Оплаты зачисляются на указанный
+// счет, уведомления о платежах отправляются на callback_url.
+func (s *SBPService) RegisterMerchant(userID int, req *models.RegisterMerchantRequest) (*models.SBPMerchant, error) {
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxSBPPurposeLength {
+		return nil, ErrInvalidQRParams
+	}
+	if req.CallbackURL != "" {
+		if err := checkCallbackURL(req.CallbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	account, err := s.accountRepo.GetAccountByID(req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	if err := checkActive(account); err != nil {
+		return nil, err
+	}
+
+	merchantID, err := s.nspk.RegisterMerchant(&sbp.RegisterMerchantRequest{BankID: s.bankID, Name: req.Name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register merchant in NSPK: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	merchant := &models.SBPMerchant{
+		UserID:         userID,
+		AccountID:      account.ID,
+		Name:           req.Name,
+		MerchantID:     merchantID,
+		CallbackURL:    req.CallbackURL,
+		CallbackSecret: hex.EncodeToString(secret),
+	}
+	if err := s.sbpRepo.CreateMerchant(merchant); err != nil {
+		return nil, err
+	}
+
+	return merchant, nil
+}
+
+func (s *SBPService) GetUserMerchants(userID int) ([]*models.SBPMerchant, error) {
+	merchants, err := s.sbpRepo.GetMerchantsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, merchant := range merchants {
+		merchant.CallbackSecret = ""
+	}
+	return merchants, nil
+}
+
+// CreateQR регистрирует QR-код в НСПК и возвращает функциональную ссылку
+func (s *SBPService) CreateQR(userID, merchantID int, req *models.CreateQRRequest) (*models.SBPQRCode, error) {
+	merchant, err := s.getOwnMerchant(userID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	qrType, ok := sbpQRTypes[req.Type]
+	if !ok || req.Amount < 0 || utf8.RuneCountInString(req.Purpose) > maxSBPPurposeLength {
+		return nil, ErrInvalidQRParams
+	}
+
+	var expiresAt *time.Time
+	if qrType == sbp.QRTypeDynamic {
+		if req.Amount <= 0 {
+			return nil, ErrInvalidQRParams
+		}
+		ttl := defaultDynamicQRTTL
+		if req.ExpiresInMinutes > 0 {
+			ttl = time.Duration(req.ExpiresInMinutes) * time.Minute
+		}
+		expires := time.Now().Add(ttl)
+		expiresAt = &expires
+	}
+
+	registered, err := s.nspk.RegisterQR(&sbp.RegisterQRRequest{
+		BankID:     s.bankID,
+		MerchantID: merchant.MerchantID,
+		Type:       qrType,
+		Amount:     toKopecks(req.Amount),
+		Currency:   sbpCurrency,
+		Purpose:    req.Purpose,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register QR code in NSPK: %w", err)
+	}
+
+	qr := &models.SBPQRCode{
+		QRCID:      registered.QRCID,
+		MerchantID: merchant.ID,
+		Type:       req.Type,
+		Amount:     req.Amount,
+		Purpose:    req.Purpose,
+		Payload:    registered.Payload,
+		Status:     models.SBPQRActive,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.sbpRepo.CreateQR(qr); err != nil {
+		return nil, err
+	}
+
+	return qr, nil
+}
+
+// GetQR возвращает QR-код и его статус владельцу ТСП
+func (s *SBPService) GetQR(userID int, qrcID string) (*models.SBPQRCode, error) {
+	qr, err := s.sbpRepo.GetQRByQRCID(qrcID)
+	if err != nil {
+		return nil, err
+	}
+	if qr == nil {
+		return nil, ErrQRNotFound
+	}
+
+	if _, err := s.getOwnMerchant(userID, qr.MerchantID); err != nil {
+		if err == ErrMerchantNotFound {
+			return nil, ErrQRNotFound
+		}
+		return nil, err
+	}
+
+	return qr, nil
+}
+
+// ResolveLink показывает плательщику получателя и сумму по ссылке
+func (s *SBPService) ResolveLink(raw string) (*models.SBPPaymentPreview, error) {
+	qr, merchant, err := s.resolve(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SBPPaymentPreview{
+		QRCID:        qr.QRCID,
+		MerchantName: merchant.Name,
+		Type:         qr.Type,
+		Amount:       qr.Amount,
+		Currency:     sbpCurrency,
+		Purpose:      qr.Purpose,
+	}, nil
+}
+
+// Pay оплачивает QR-код переводом на счет ТСП и уведомляет ТСП о результате.
+// Динамический код оплачивается один раз.
+func (s *SBPService) Pay(userID int, req *models.SBPPayRequest) (*models.SBPPayment, error) {
+	qr, merchant, err := s.resolve(req.Link)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := qr.Amount
+	switch {
+	case amount == 0 && req.Amount > 0:
+		amount = req.Amount
+	case amount == 0 || (req.Amount != 0 && req.Amount != amount):
+		return nil, ErrInvalidAmount
+	}
+
+	fromAccount, err := s.accountRepo.GetAccountByID(req.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount == nil || fromAccount.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	dynamic := qr.Type == "dynamic"
+	if dynamic {
+		claimed, err := s.sbpRepo.SetQRStatus(qr.ID, models.SBPQRActive, models.SBPQRPaid)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, ErrQRNotActive
+		}
+	}
+
+	description := fmt.Sprintf("SBP payment to %s", merchant.Name)
+	if qr.Purpose != "" {
+		description += ": " + qr.Purpose
+	}
+
+	transferErr := s.accountService.Transfer(&models.TransferRequest{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   merchant.AccountID,
+		Amount:        amount,
+		Description:   description,
+	})
+
+	payment := &models.SBPPayment{
+		QRCID:          qr.QRCID,
+		MerchantID:     merchant.ID,
+		PayerUserID:    userID,
+		PayerAccountID: fromAccount.ID,
+		Amount:         amount,
+		Status:         models.SBPPaymentAccepted,
+	}
+	if transferErr != nil {
+		payment.Status = models.SBPPaymentRejected
+		payment.Error = transferErr.Error()
+
+		// Неоплаченный динамический код снова доступен для оплаты
+		if dynamic {
+			if _, err := s.sbpRepo.SetQRStatus(qr.ID, models.SBPQRPaid, models.SBPQRActive); err != nil {
+				log.Printf("Failed to reactivate QR code %s: %v", qr.QRCID, err)
+			}
+		}
+	}
+
+	if err := s.sbpRepo.CreatePayment(payment); err != nil {
+		return nil, err
+	}
+
+	go s.sendCallback(merchant, qr, payment)
+
+	if transferErr != nil {
+		return nil, transferErr
+	}
+	return payment, nil
+}
+
+func (s *SBPService) GetPayment(userID, paymentID int) (*models.SBPPayment, error) {
+	payment, err := s.sbpRepo.GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil || payment.PayerUserID != userID {
+		return nil, ErrPaymentNotFound
+	}
+
+	return payment, nil
+}
+
+// resolve разбирает ссылку, сверяет ее с реестром НСПК и находит QR-код
+// и ТСП в банке. Поддерживаются только ТСП, обслуживаемые этим банком.
+func (s *SBPService) resolve(raw string) (*models.SBPQRCode, *models.SBPMerchant, error) {
+	link, err := sbp.ParseLink(raw)
+	if err != nil {
+		return nil, nil, ErrInvalidPaymentLink
+	}
+
+	registered, err := s.nspk.ResolveQR(link.QRCID)
+	if errors.Is(err, sbp.ErrQRNotFound) {
+		return nil, nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve QR code in NSPK: %w", err)
+	}
+
+	// Параметры ссылки должны совпадать с зарегистрированными в НСПК
+	if registered.Type != link.Type || registered.BankID != link.BankID || registered.Amount != link.Amount {
+		return nil, nil, ErrInvalidPaymentLink
+	}
+	if registered.BankID != s.bankID {
+		return nil, nil, ErrForeignBank
+	}
+
+	qr, err := s.sbpRepo.GetQRByQRCID(link.QRCID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if qr == nil {
+		return nil, nil, ErrQRNotFound
+	}
+	if qr.Status != models.SBPQRActive {
+		return nil, nil, ErrQRNotActive
+	}
+	if qr.ExpiresAt != nil && !qr.ExpiresAt.After(time.Now()) {
+		return nil, nil, ErrQRExpired
+	}
+
+	merchant, err := s.sbpRepo.GetMerchantByID(qr.MerchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if merchant == nil {
+		return nil, nil, ErrQRNotFound
+	}
+
+	return qr, merchant, nil
+}
+
+func (s *SBPService) getOwnMerchant(userID, merchantID int) (*models.SBPMerchant, error) {
+	merchant, err := s.sbpRepo.GetMerchantByID(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil || merchant.UserID != userID {
+		return nil, ErrMerchantNotFound
+	}
+
+	return merchant, nil
+}
+
+// sendCallback уведомляет ТСП о статусе платежа. Тело подписывается
+// HMAC-SHA256 ключом ТСП и передается в заголовке X-Signature.
+func (s *SBPService) sendCallback(merchant *models.SBPMerchant, qr *models.SBPQRCode, payment *models.SBPPayment) {
+	if merchant.CallbackURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(models.SBPCallback{
+		QRCID:     qr.QRCID,
+		PaymentID: payment.ID,
+		Amount:    payment.Amount,
+		Currency:  sbpCurrency,
+		Purpose:   qr.Purpose,
+		Status:    payment.Status,
+		Timestamp: payment.CreatedAt,
+	})
+	if err != nil {
+		log.Printf("Failed to encode SBP callback for payment %d: %v", payment.ID, err)
+		return
+	}
+	signature := crypto.GenerateHMAC(string(body), merchant.CallbackSecret)
+
+	for attempt := 1; attempt <= sbpCallbackAttempts; attempt++ {
+		if err = s.postCallback(merchant.CallbackURL, body, signature); err == nil {
+			return
+		}
+		time.Sleep(time.Duration(attempt) * sbpCallbackDelay)
+	}
+
+	log.Printf("Failed to deliver SBP callback for payment %d to merchant %d: %v", payment.ID, merchant.ID, err)
+}
+
+func (s *SBPService) postCallback(callbackURL string, body []byte, signature string) error {
+	// Адреса, сохраненные до проверки, тоже должны быть https
+	if u, err := url.Parse(callbackURL); err != nil || u.Scheme != "https" {
+		return ErrInvalidCallbackURL
+	}
+
+	req, err := http.NewRequest("POST", callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature", signature)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("merchant returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func toKopecks(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// Адреса ТСП, куда банк может отправлять уведомления, должны быть
+// публичными: иначе через callback_url можно обращаться к внутренней сети
+// банка. Немаршрутизируемых в интернете сетей, кроме частных и локальных,
+// немного; их перечисляем явно.
+var nonPublicNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "64:ff9b::/96"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCallbackURL допускает только https-адреса, все IP которых публичные
+func checkCallbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidCallbackURL
+	}
+
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return ErrInvalidCallbackURL
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return ErrInvalidCallbackURL
+		}
+	}
+	return nil
+}
+
+// newCallbackClient создает клиента для уведомлений ТСП. Адрес проверяется
+// еще раз при каждом соединении: DNS мог измениться после регистрации ТСП.
+// Перенаправления не выполняются.
+func newCallbackClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("callback address %s is not public", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Задержки повторных попыток после неудачного перевода. После исчерпания
+// попыток очередной перевод пропускается до следующей даты по расписанию.
+var standingOrderRetryDelays = []time.Duration{
+	time.Hour,
+	4 * time.Hour,
+	12 * time.Hour,
+}
+
+type StandingOrderService struct {
+	orderRepo       *repository.StandingOrderRepository
+	accountRepo     *repository.AccountRepository
+	userRepo        *repository.UserRepository
+	accountService  *AccountService
+	notificationSvc *NotificationService
+}
+
+func NewStandingOrderService(
+	orderRepo *repository.StandingOrderRepository,
+	accountRepo *repository.AccountRepository,
+	userRepo *repository.UserRepository,
+	accountService *AccountService,
+	notificationSvc *NotificationService,
+) *StandingOrderService {
+	return &StandingOrderService{
+		orderRepo:       orderRepo,
+		accountRepo:     accountRepo,
+		userRepo:        userRepo,
+		accountService:  accountService,
+		notificationSvc: notificationSvc,
+	}
+}
+
+func (s *StandingOrderService) CreateOrder(userID int, req *models.CreateStandingOrderRequest) (*models.StandingOrder, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	fromAccount, err := s.accountRepo.GetAccountByID(req.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount == nil || fromAccount.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	if err := checkActive(fromAccount); err != nil {
+		return nil, err
+	}
+
+	// Получатель может быть указан номером счета вместо ID
+	toAccountID := req.ToAccountID
+	if toAccountID == 0 && req.ToAccountNumber != "" {
+		toAccount, err := s.accountService.GetAccountByNumber(req.ToAccountNumber)
+		if err != nil {
+			return nil, err
+		}
+		if toAccount == nil {
+			return nil, ErrAccountNotFound
+		}
+		toAccountID = toAccount.ID
+	}
+	toAccount, err := s.accountRepo.GetAccountByID(toAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if toAccount == nil {
+		return nil, ErrAccountNotFound
+	}
+	if toAccount.ID == fromAccount.ID {
+		return nil, ErrSameAccount
+	}
+	if err := checkCredit(toAccount); err != nil {
+		return nil, err
+	}
+
+	order := &models.StandingOrder{
+		UserID:        userID,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        req.Amount,
+		Description:   req.Description,
+		Schedule:      req.Schedule,
+		Status:        models.StandingOrderStatusActive,
+	}
+
+	switch req.Schedule {
+	case models.StandingOrderOnce:
+	case models.StandingOrderWeekly:
+		if req.Weekday < 0 || req.Weekday > 6 {
+			return nil, ErrInvalidSchedule
+		}
+		order.Weekday = req.Weekday
+	case models.StandingOrderMonthly:
+		if req.DayOfMonth < 1 || req.DayOfMonth > 31 {
+			return nil, ErrInvalidSchedule
+		}
+		order.DayOfMonth = req.DayOfMonth
+	default:
+		return nil, ErrInvalidSchedule
+	}
+
+	today := startOfDay(time.Now())
+	order.StartDate = today
+	if req.StartDate != nil {
+		order.StartDate = startOfDay(req.StartDate.In(today.Location()))
+		if order.StartDate.Before(today) {
+			return nil, ErrInvalidSchedule
+		}
+	}
+
+	order.ScheduledFor = occurrenceOnOrAfter(order, order.StartDate)
+	order.NextRunAt = order.ScheduledFor
+
+	if req.EndDate != nil {
+		endDate := startOfDay(req.EndDate.In(today.Location()))
+		if endDate.Before(order.ScheduledFor) {
+			return nil, ErrInvalidSchedule
+		}
+		order.EndDate = &endDate
+	}
+
+	if err := s.orderRepo.CreateOrder(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *StandingOrderService) GetUserOrders(userID int) ([]*models.StandingOrder, error) {
+	return s.orderRepo.GetOrdersByUser(userID)
+}
+
+func (s *StandingOrderService) GetOrder(userID, orderID int) (*models.StandingOrder, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+
+	return order, nil
+}
+
+func (s *StandingOrderService) GetOrderRuns(userID, orderID int) ([]*models.StandingOrderRun, error) {
+	if _, err := s.GetOrder(userID, orderID); err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.GetRunsByOrder(orderID)
+}
+
+func (s *StandingOrderService) UpdateOrder(userID, orderID int, req *models.UpdateStandingOrderRequest) (*models.StandingOrder, error) {
+	order, err := s.GetOrder(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.StandingOrderStatusActive {
+		return nil, ErrOrderNotActive
+	}
+
+	if req.Amount != nil {
+		if *req.Amount <= 0 {
+			return nil, ErrInvalidAmount
+		}
+		order.Amount = *req.Amount
+	}
+	if req.Description != nil {
+		order.Description = *req.Description
+	}
+	if req.EndDate != nil {
+		endDate := startOfDay(req.EndDate.In(order.ScheduledFor.Location()))
+		if endDate.Before(order.ScheduledFor) {
+			return nil, ErrInvalidSchedule
+		}
+		order.EndDate = &endDate
+	}
+
+	if err := s.orderRepo.UpdateOrder(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *StandingOrderService) CancelOrder(userID, orderID int) (*models.StandingOrder, error) {
+	order, err := s.GetOrder(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.StandingOrderStatusActive {
+		return nil, ErrOrderNotActive
+	}
+
+	order.Status = models.StandingOrderStatusCancelled
+	if err := s.orderRepo.UpdateOrder(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ProcessDueOrders выполняет переводы, время которых наступило. Вызывается
+// шедулером.
+func (s *StandingOrderService) ProcessDueOrders(now time.Time) error {
+	orders, err := s.orderRepo.GetDueOrders(now)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if err := s.execute(order, now); err != nil {
+			log.Printf("Failed to process standing order %d: %v", order.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// execute выполняет очередную попытку. Попытка занимается до перевода,
+// поэтому сбой между переводом и обновлением поручения не приводит
+// к повторному списанию: на следующем запуске поручение доводится по
+// сохраненному результату.
+func (s *StandingOrderService) execute(order *models.StandingOrder, now time.Time) error {
+	run := &models.StandingOrderRun{
+		OrderID:      order.ID,
+		ScheduledFor: order.ScheduledFor,
+		Attempt:      order.FailedAttempts + 1,
+		Status:       models.StandingOrderRunPending,
+	}
+
+	claimed, err := s.orderRepo.ClaimRun(run)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return s.resume(order, now)
+	}
+
+	transferErr := s.transfer(order)
+	run.Status = models.StandingOrderRunSuccess
+	if transferErr != nil {
+		run.Status = models.StandingOrderRunFailed
+		run.Error = transferErr.Error()
+	}
+	if err := s.orderRepo.CompleteRun(run); err != nil {
+		// Перевод уже выполнен или отклонен, поэтому поручение все равно
+		// обновляем
+		log.Printf("Failed to save run %d of standing order %d: %v", run.ID, order.ID, err)
+	}
+
+	return s.finish(order, run, transferErr, now)
+}
+
+// resume доводит поручение, попытка по которому уже занята. Если ее
+// результат сохранен, поручение обновляется без повторного перевода.
+// Попытка без результата прервана во время перевода: прошел ли он,
+// неизвестно, поэтому поручение остается до ручной проверки.
+func (s *StandingOrderService) resume(order *models.StandingOrder, now time.Time) error {
+	run, err := s.orderRepo.GetRun(order.ID, order.ScheduledFor, order.FailedAttempts+1)
+	if err != nil {
+		return err
+	}
+	if run == nil || run.Status == models.StandingOrderRunPending {
+		return fmt.Errorf("attempt %d for %s was interrupted, the transfer must be checked manually",
+			order.FailedAttempts+1, order.ScheduledFor.Format("2006-01-02"))
+	}
+
+	var transferErr error
+	if run.Status == models.StandingOrderRunFailed {
+		transferErr = runTransferError(run)
+	}
+	return s.finish(order, run, transferErr, now)
+}
+
+// finish переносит поручение на следующую попытку или дату по результату
+// перевода и уведомляет клиента
+func (s *StandingOrderService) finish(order *models.StandingOrder, run *models.StandingOrderRun, transferErr error, now time.Time) error {
+	notify := true
+	switch {
+	case transferErr == nil:
+		order.FailedAttempts = 0
+		s.advance(order)
+	case !isRetryableTransferError(transferErr):
+		// Счет закрыт или удален - последующие переводы тоже не пройдут
+		order.Status = models.StandingOrderStatusCancelled
+	case order.FailedAttempts < len(standingOrderRetryDelays):
+		order.NextRunAt = now.Add(standingOrderRetryDelays[order.FailedAttempts])
+		order.FailedAttempts++
+		notify = false
+	default:
+		order.FailedAttempts = 0
+		s.advance(order)
+	}
+
+	if err := s.orderRepo.UpdateOrder(order); err != nil {
+		return err
+	}
+
+	if notify {
+		s.notify(order, run)
+	}
+	return nil
+}
+
+// transfer выполняет перевод тем же путем, что и перевод по запросу клиента
+func (s *StandingOrderService) transfer(order *models.StandingOrder) error {
+	fromAccount, err := s.accountRepo.GetAccountByID(order.FromAccountID)
+	if err != nil {
+		return err
+	}
+	if fromAccount == nil || fromAccount.UserID != order.UserID {
+		return ErrAccountNotFound
+	}
+
+	description := order.Description
+	if description == "" {
+		description = fmt.Sprintf("Standing order #%d", order.ID)
+	}
+
+	return s.accountService.Transfer(&models.TransferRequest{
+		FromAccountID: order.FromAccountID,
+		ToAccountID:   order.ToAccountID,
+		Amount:        order.Amount,
+		Description:   description,
+	})
+}
+
+// advance переводит поручение на следующую дату по расписанию или завершает его
+func (s *StandingOrderService) advance(order *models.StandingOrder) {
+	if order.Schedule == models.StandingOrderOnce {
+		order.Status = models.StandingOrderStatusCompleted
+		return
+	}
+
+	next := occurrenceOnOrAfter(order, order.ScheduledFor.AddDate(0, 0, 1))
+	if order.EndDate != nil && next.After(*order.EndDate) {
+		order.Status = models.StandingOrderStatusCompleted
+		return
+	}
+
+	order.ScheduledFor = next
+	order.NextRunAt = next
+}
+
+func (s *StandingOrderService) notify(order *models.StandingOrder, run *models.StandingOrderRun) {
+	user, err := s.userRepo.GetUserByID(order.UserID)
+	if err != nil || user == nil {
+		log.Printf("Failed to find user %d for standing order %d notification: %v", order.UserID, order.ID, err)
+		return
+	}
+
+	if err := s.notificationSvc.SendStandingOrderNotification(user.Email, order, run); err != nil {
+		log.Printf("Failed to send standing order notification: %v", err)
+	}
+}
+
+// occurrenceOnOrAfter возвращает первую дату перевода по расписанию,
+// не раньше указанного дня
+func occurrenceOnOrAfter(order *models.StandingOrder, day time.Time) time.Time {
+	switch order.Schedule {
+	case models.StandingOrderWeekly:
+		offset := (order.Weekday - int(day.Weekday()) + 7) % 7
+		return day.AddDate(0, 0, offset)
+	case models.StandingOrderMonthly:
+		candidate := monthDay(day.Year(), day.Month(), order.DayOfMonth, day.Location())
+		if candidate.Before(day) {
+			candidate = monthDay(day.Year(), day.Month()+1, order.DayOfMonth, day.Location())
+		}
+		return candidate
+	default:
+		return day
+	}
+}
+
+// monthDay возвращает указанный день месяца или последний день, если
+// в месяце меньше дней
+func monthDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// isRetryableTransferError отделяет временные причины отказа (нехватка
+// средств, заморозка счета, сбой БД) от тех, что повтор не исправит
+func isRetryableTransferError(err error) bool {
+	for _, permanent := range permanentTransferErrors {
+		if err == permanent {
+			return false
+		}
+	}
+	return true
+}
+
+var permanentTransferErrors = []error{
+	ErrAccountNotFound, ErrAccountClosed, ErrSameAccount, ErrInvalidAmount, ErrInvalidAccountNumber,
+}
+
+// runTransferError восстанавливает ошибку перевода по сохраненному тексту
+func runTransferError(run *models.StandingOrderRun) error {
+	for _, permanent := range permanentTransferErrors {
+		if run.Error == permanent.Error() {
+			return permanent
+		}
+	}
+	return errors.New(run.Error)
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bank-api/internal/models"
+	"bank-api/internal/repository"
+	"bank-api/pkg/iso20022"
+	"bank-api/pkg/mail"
+	"bank-api/pkg/onec"
+	"bank-api/pkg/pdf"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type StatementFile struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type StatementService struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	userRepo        *repository.UserRepository
+	notificationSvc *NotificationService
+	bankName        string
+	bankBIC         string
+}
+
+func NewStatementService(
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	userRepo *repository.UserRepository,
+	notificationSvc *NotificationService,
+	bankName string,
+	bankBIC string,
+) *StatementService {
+	return &StatementService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		notificationSvc: notificationSvc,
+		bankName:        bankName,
+		bankBIC:         bankBIC,
+	}
+}
+
+// GenerateStatement собирает выписку за период с from по to включительно.
+// Остатки вычисляются от текущего баланса счета: из него вычитаются все
+// движения, совершенные после начала (конца) периода.
+func (s *StatementService) GenerateStatement(userID, accountID int, from, to time.Time) (*models.Statement, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	periodEnd := to.AddDate(0, 0, 1)
+
+	sinceFrom, err := s.transactionRepo.SumSince(accountID, from)
+	if err != nil {
+		return nil, err
+	}
+	sinceEnd, err := s.transactionRepo.SumSince(accountID, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.transactionRepo.GetTransactionsByPeriod(accountID, from, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.Statement{
+		AccountID:      account.ID,
+		AccountNumber:  account.Number,
+		Currency:       account.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: account.Balance - sinceFrom,
+		ClosingBalance: account.Balance - sinceEnd,
+		Transactions:   transactions,
+		GeneratedAt:    time.Now(),
+	}
+	if user != nil {
+		statement.OwnerName = user.Username
+	}
+
+	for _, t := range transactions {
+		if t.Amount >= 0 {
+			statement.TotalCredit += t.Amount
+		} else {
+			statement.TotalDebit -= t.Amount
+		}
+	}
+
+	return statement, nil
+}
+
+func (s *StatementService) Render(statement *models.Statement, format models.StatementFormat) (*StatementFile, error) {
+	name := fmt.Sprintf("statement_%d_%s_%s",
+		statement.AccountID,
+		statement.From.Format("20060102"),
+		statement.To.Format("20060102"),
+	)
+
+	switch format {
+	case models.StatementFormatCSV:
+		data, err := s.renderCSV(statement)
+		if err != nil {
+			return nil, err
+		}
+		return &StatementFile{Filename: name + ".csv", ContentType: "text/csv; charset=utf-8", Data: data}, nil
+	case models.StatementFormatPDF:
+		return &StatementFile{Filename: name + ".pdf", ContentType: "application/pdf", Data: s.renderPDF(statement)}, nil
+	case models.StatementFormat1C:
+		return &StatementFile{Filename: name + ".txt", ContentType: "text/plain; charset=windows-1251", Data: s.render1C(statement)}, nil
+	case models.StatementFormatCamt053:
+		data, err := s.renderCamt053(statement)
+		if err != nil {
+			return nil, err
+		}
+		return &StatementFile{Filename: name + ".xml", ContentType: "application/xml", Data: data}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func (s *StatementService) EmailStatement(userID, accountID int, from, to time.Time, format models.StatementFormat) error {
+	statement, err := s.GenerateStatement(userID, accountID, from, to)
+	if err != nil {
+		return err
+	}
+
+	file, err := s.Render(statement, format)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrInvalidUser
+	}
+
+	return s.notificationSvc.SendStatementNotification(user.Email, from, to, mail.Attachment{
+		Filename:    file.Filename,
+		ContentType: file.ContentType,
+		Data:        file.Data,
+	})
+}
+
+func (s *StatementService) renderCSV(statement *models.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	records := [][]string{
+		{"date", "transaction_id", "type", "description", "credit", "debit", "balance"},
+		{statement.From.Format("2006-01-02"), "", "", "Opening balance", "", "", formatMoney(statement.OpeningBalance)},
+	}
+
+	balance := statement.OpeningBalance
+	for _, t := range statement.Transactions {
+		balance += t.Amount
+		credit, debit := splitAmount(t.Amount)
+		records = append(records, []string{
+			t.CreatedAt.Format("2006-01-02 15:04:05"),
+			strconv.Itoa(t.ID),
+			string(t.Type),
+			t.Description,
+			credit,
+			debit,
+			formatMoney(balance),
+		})
+	}
+
+	records = append(records, []string{
+		statement.To.Format("2006-01-02"), "", "", "Closing balance",
+		formatMoney(statement.TotalCredit),
+		formatMoney(statement.TotalDebit),
+		formatMoney(statement.ClosingBalance),
+	})
+
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *StatementService) renderPDF(statement *models.Statement) []byte {
+	doc := pdf.New()
+	doc.AddLines(
+		s.bankName,
+		"Account statement",
+		"",
+		fmt.Sprintf("Account:   %s (%s)", accountIdentifier(statement), statement.Currency),
+		fmt.Sprintf("Owner:     %s", statement.OwnerName),
+		fmt.Sprintf("Period:    %s - %s", statement.From.Format("02.01.2006"), statement.To.Format("02.01.2006")),
+		fmt.Sprintf("Generated: %s", statement.GeneratedAt.Format("02.01.2006 15:04")),
+		"",
+		fmt.Sprintf("Opening balance: %15s", formatMoney(statement.OpeningBalance)),
+		"",
+		fmt.Sprintf("%-16s %-10s %-30s %15s %15s", "Date", "Type", "Description", "Credit", "Debit"),
+	)
+
+	for _, t := range statement.Transactions {
+		credit, debit := splitAmount(t.Amount)
+		doc.AddLine(fmt.Sprintf("%-16s %-10s %-30s %15s %15s",
+			t.CreatedAt.Format("02.01.2006 15:04"),
+			truncate(string(t.Type), 10),
+			truncate(pdf.Transliterate(t.Description), 30),
+			credit,
+			debit,
+		))
+	}
+
+	doc.AddLines(
+		"",
+		fmt.Sprintf("Total credit:    %15s", formatMoney(statement.TotalCredit)),
+		fmt.Sprintf("Total debit:     %15s", formatMoney(statement.TotalDebit)),
+		fmt.Sprintf("Closing balance: %15s", formatMoney(statement.ClosingBalance)),
+	)
+
+	return doc.Bytes()
+}
+
+func (s *StatementService) render1C(statement *models.Statement) []byte {
+	account := accountIdentifier(statement)
+
+	exchange := &onec.Exchange{
+		Sender:         s.bankName,
+		Receiver:       "Бухгалтерия",
+		CreatedAt:      statement.GeneratedAt,
+		From:           statement.From,
+		To:             statement.To,
+		Account:        account,
+		OpeningBalance: statement.OpeningBalance,
+		TotalCredit:    statement.TotalCredit,
+		TotalDebit:     statement.TotalDebit,
+		ClosingBalance: statement.ClosingBalance,
+	}
+
+	for _, t := range statement.Transactions {
+		date := t.CreatedAt
+		doc := onec.Document{
+			Number:  strconv.Itoa(t.ID),
+			Date:    date,
+			Purpose: t.Description,
+		}
+		if doc.Purpose == "" {
+			doc.Purpose = string(t.Type)
+		}
+
+		// Вторая сторона операции в транзакциях не хранится,
+		// поэтому заполняются только реквизиты самого счета
+		if t.Amount >= 0 {
+			doc.Amount = t.Amount
+			doc.RecipientAccount = account
+			doc.Recipient = statement.OwnerName
+			doc.RecipientBIC = s.bankBIC
+			doc.CreditedAt = &date
+		} else {
+			doc.Amount = -t.Amount
+			doc.PayerAccount = account
+			doc.Payer = statement.OwnerName
+			doc.PayerBIC = s.bankBIC
+			doc.DebitedAt = &date
+		}
+
+		exchange.Documents = append(exchange.Documents, doc)
+	}
+
+	return exchange.Bytes()
+}
+
+func (s *StatementService) renderCamt053(statement *models.Statement) ([]byte, error) {
+	id := fmt.Sprintf("STMT-%d-%s", statement.AccountID, statement.GeneratedAt.Format("20060102150405"))
+
+	camt := &iso20022.Statement{
+		MessageID:      id,
+		StatementID:    id,
+		CreatedAt:      statement.GeneratedAt,
+		From:           statement.From,
+		To:             statement.To.AddDate(0, 0, 1).Add(-time.Second),
+		Account:        accountIdentifier(statement),
+		Currency:       statement.Currency,
+		OwnerName:      statement.OwnerName,
+		ServicerBIC:    s.bankBIC,
+		OpeningBalance: statement.OpeningBalance,
+		ClosingBalance: statement.ClosingBalance,
+	}
+
+	for _, t := range statement.Transactions {
+		amount := t.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		camt.Entries = append(camt.Entries, iso20022.Entry{
+			Reference:   strconv.Itoa(t.ID),
+			Amount:      amount,
+			Credit:      t.Amount >= 0,
+			BookedAt:    t.CreatedAt,
+			Code:        string(t.Type),
+			Information: t.Description,
+		})
+	}
+
+	return camt.Bytes()
+}
+
+// accountIdentifier возвращает номер счета для документов выписки. Для
+// счетов, открытых до появления номеров, используется внутренний ID.
+func accountIdentifier(statement *models.Statement) string {
+	if statement.AccountNumber != "" {
+		return statement.AccountNumber
+	}
+	return strconv.Itoa(statement.AccountID)
+}
+
+func splitAmount(amount float64) (credit, debit string) {
+	if amount >= 0 {
+		return formatMoney(amount), ""
+	}
+	return "", formatMoney(-amount)
+}
+
+func formatMoney(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
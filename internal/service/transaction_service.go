@@ -4,12 +4,16 @@ import (
 	"bank-api/internal/models"
 	"bank-api/internal/repository"
 	"database/sql"
+	"fmt"
 	"log"
 )
 
 type TransactionService struct {
 	transactionRepo *repository.TransactionRepository
 	accountRepo     *repository.AccountRepository
+	userRepo        *repository.UserRepository
+	sbpRepo         *repository.SBPRepository
+	limitService    *LimitService
 	notificationSvc *NotificationService
 	db              *sql.DB
 }
@@ -17,35 +21,55 @@ type TransactionService struct {
 func NewTransactionService(
 	transactionRepo *repository.TransactionRepository,
 	accountRepo *repository.AccountRepository,
+	userRepo *repository.UserRepository,
+	sbpRepo *repository.SBPRepository,
+	limitService *LimitService,
 	notificationSvc *NotificationService,
 	db *sql.DB,
 ) *TransactionService {
 	return &TransactionService{
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
+		userRepo:        userRepo,
+		sbpRepo:         sbpRepo,
+		limitService:    limitService,
 		notificationSvc: notificationSvc,
 		db:              db,
 	}
 }
 
-func (s *TransactionService) ProcessDeposit(accountID int, amount float64, description string, userEmail string) error {
+// FixLegacyTransferDebits приводит списания по старым переводам к
+// отрицательным суммам, на которые рассчитаны выписки, лимиты и сторно.
+// Возвращает число исправленных переводов.
+func (s *TransactionService) FixLegacyTransferDebits() (int64, error) {
+	return s.transactionRepo.FixLegacyTransferDebits()
+}
+
+func (s *TransactionService) ProcessDeposit(userID, accountID int, amount float64, description string) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Проверяем существование счета
-	account, err := s.accountRepo.GetAccountByID(accountID)
+	// Проверяем существование счета, права доступа и статус
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, accountID)
 	if err != nil {
 		return err
 	}
-	if account == nil {
+	if account == nil || account.UserID != userID {
 		return ErrAccountNotFound
 	}
+	if err := checkCredit(account); err != nil {
+		return err
+	}
 
 	// Обновляем баланс
-	if err := s.accountRepo.UpdateBalance(accountID, amount); err != nil {
+	if err := s.accountRepo.UpdateBalance(tx, accountID, amount); err != nil {
 		return err
 	}
 
@@ -61,45 +85,50 @@ func (s *TransactionService) ProcessDeposit(accountID int, amount float64, descr
 		return err
 	}
 
-	// Отправляем уведомление
-	if err := s.notificationSvc.SendPaymentNotification(
-		userEmail,
-		amount,
-	); err != nil {
-		log.Printf("Failed to send deposit notification: %v", err)
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 
-	return tx.Commit()
+	s.notifyPayment(userID, amount, "deposit")
+	return nil
 }
 
-func (s *TransactionService) ProcessWithdrawal(accountID int, amount float64, description string, userEmail string) error {
+func (s *TransactionService) ProcessWithdrawal(userID, accountID int, amount float64, description string) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Проверяем существование счета и достаточность средств
-	account, err := s.accountRepo.GetAccountByID(accountID)
+	// Проверяем существование счета, права доступа, статус и достаточность средств
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, accountID)
 	if err != nil {
 		return err
 	}
-	if account == nil {
+	if account == nil || account.UserID != userID {
 		return ErrAccountNotFound
 	}
-	if account.Balance < amount {
-		return ErrInsufficientFunds
+	if err := checkDebit(account, amount); err != nil {
+		return err
+	}
+	if err := s.limitService.CheckAccount(tx, account, amount); err != nil {
+		return err
 	}
 
 	// Обновляем баланс
-	if err := s.accountRepo.UpdateBalance(accountID, -amount); err != nil {
+	if err := s.accountRepo.UpdateBalance(tx, accountID, -amount); err != nil {
 		return err
 	}
 
-	// Создаем запись о транзакции
+	// Создаем запись о транзакции (списание хранится с отрицательной суммой,
+	// как и в AccountService.UpdateBalance)
 	transaction := &models.Transaction{
 		AccountID:   accountID,
-		Amount:      amount,
+		Amount:      -amount,
 		Type:        models.TransactionWithdrawal,
 		Description: description,
 	}
@@ -108,18 +137,19 @@ func (s *TransactionService) ProcessWithdrawal(accountID int, amount float64, de
 		return err
 	}
 
-	// Отправляем уведомление
-	if err := s.notificationSvc.SendPaymentNotification(
-		userEmail,
-		amount,
-	); err != nil {
-		log.Printf("Failed to send withdrawal notification: %v", err)
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 
-	return tx.Commit()
+	s.notifyPayment(userID, amount, "withdrawal")
+	return nil
 }
 
-func (s *TransactionService) ProcessTransfer(fromAccountID, toAccountID int, amount float64, description string, userEmail string) error {
+func (s *TransactionService) ProcessTransfer(userID, fromAccountID, toAccountID int, amount float64, description string) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -127,64 +157,337 @@ func (s *TransactionService) ProcessTransfer(fromAccountID, toAccountID int, amo
 	defer tx.Rollback()
 
 	// Проверяем счета
-	fromAccount, err := s.accountRepo.GetAccountByID(fromAccountID)
+	fromAccount, toAccount, err := lockTransferAccounts(tx, s.accountRepo, fromAccountID, toAccountID)
 	if err != nil {
 		return err
 	}
-	if fromAccount == nil {
+	if fromAccount.UserID != userID {
 		return ErrAccountNotFound
 	}
+	if err := s.limitService.CheckAccount(tx, fromAccount, amount); err != nil {
+		return err
+	}
 
-	toAccount, err := s.accountRepo.GetAccountByID(toAccountID)
-	if err != nil {
+	if _, err := postTransfer(tx, s.accountRepo, s.transactionRepo, fromAccount, toAccount, amount, description); err != nil {
 		return err
 	}
-	if toAccount == nil {
-		return ErrAccountNotFound
+
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 
-	// Проверяем достаточность средств
-	if fromAccount.Balance < amount {
-		return ErrInsufficientFunds
+	s.notifyPayment(userID, amount, "transfer")
+	return nil
+}
+
+func (s *TransactionService) GetTransactions(userID, accountID int) ([]*models.Transaction, error) {
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrAccountNotFound
 	}
 
-	// Выполняем перевод
-	if err := s.accountRepo.UpdateBalance(fromAccountID, -amount); err != nil {
-		return err
+	return s.transactionRepo.GetTransactionsByAccount(accountID)
+}
+
+// ReverseTransaction сторнирует ошибочное пополнение, снятие или перевод.
+// Доступно только операторам. Исходные проводки не удаляются: по каждой
+// создается компенсирующая проводка со ссылкой на нее, перевод сторнируется
+// целиком по обоим счетам.
+func (s *TransactionService) ReverseTransaction(operatorID, transactionID int, reason string) ([]*models.Transaction, error) {
+	operator, err := s.userRepo.GetUserByID(operatorID)
+	if err != nil {
+		return nil, err
 	}
-	if err := s.accountRepo.UpdateBalance(toAccountID, amount); err != nil {
-		return err
+	if operator == nil || operator.Role != models.UserRoleOperator {
+		return nil, ErrForbidden
 	}
 
-	// Создаем записи о транзакциях
-	fromTransaction := &models.Transaction{
-		AccountID:   fromAccountID,
-		Amount:      amount,
-		Type:        models.TransactionTransfer,
-		Description: description,
+	reversals, err := s.reverse(transactionID, reason, false)
+	if err != nil {
+		return nil, err
 	}
 
-	toTransaction := &models.Transaction{
-		AccountID:   toAccountID,
-		Amount:      amount,
-		Type:        models.TransactionTransfer,
-		Description: description,
+	log.Printf("Transaction %d reversed by operator %d: %s", transactionID, operatorID, reason)
+	return reversals, nil
+}
+
+// ReverseCardPayment сторнирует списание по операции с картой, когда
+// эквайрер отменяет уже рассчитанную операцию
+func (s *TransactionService) ReverseCardPayment(transactionID int, reason string) ([]*models.Transaction, error) {
+	return s.reverse(transactionID, reason, true)
+}
+
+// reverse проводит сторно операции и, для перевода, обеих его частей.
+// Платежи сторнируются только для операций по картам.
+func (s *TransactionService) reverse(transactionID int, reason string, cardPayment bool) ([]*models.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	original, err := s.transactionRepo.GetTransactionByIDForUpdate(tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, ErrTransactionNotFound
+	}
+
+	legs := []*models.Transaction{original}
+	switch original.Type {
+	case models.TransactionDeposit, models.TransactionWithdrawal:
+	case models.TransactionPayment:
+		if !cardPayment {
+			return nil, ErrNotReversible
+		}
+	case models.TransactionTransfer:
+		if original.CounterpartID == nil {
+			return nil, ErrNotReversible
+		}
+		counterpart, err := s.transactionRepo.GetTransactionByIDForUpdate(tx, *original.CounterpartID)
+		if err != nil {
+			return nil, err
+		}
+		if counterpart == nil {
+			return nil, ErrNotReversible
+		}
+		legs = append(legs, counterpart)
+	default:
+		return nil, ErrNotReversible
+	}
+
+	for _, leg := range legs {
+		reversed, refunded, err := s.transactionRepo.GetCompensations(tx, leg.ID)
+		if err != nil {
+			return nil, err
+		}
+		if reversed || refunded > 0 {
+			return nil, ErrAlreadyReversed
+		}
+	}
+
+	accounts, err := s.lockLegAccounts(tx, legs)
+	if err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Reversal of transaction #%d", original.ID)
+	if reason != "" {
+		description += ": " + reason
+	}
+
+	var reversals []*models.Transaction
+	for i, leg := range legs {
+		reversal, err := s.postCompensation(tx, accounts[i], leg, -leg.Amount, models.TransactionReversal, description)
+		if err != nil {
+			return nil, err
+		}
+		reversals = append(reversals, reversal)
+	}
+	if err := s.linkCompensations(tx, reversals); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return reversals, nil
+}
+
+// RefundTransaction возвращает плательщику оплату, поступившую на счет ТСП,
+// полностью или частично. Возвраты по одной оплате не могут превысить ее сумму.
+func (s *TransactionService) RefundTransaction(userID, transactionID int, req *models.RefundRequest) ([]*models.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	original, err := s.transactionRepo.GetTransactionByIDForUpdate(tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, ErrTransactionNotFound
+	}
+
+	merchantAccount, err := s.accountRepo.GetAccountByID(original.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if merchantAccount == nil || merchantAccount.UserID != userID {
+		return nil, ErrTransactionNotFound
+	}
+
+	// Возврат возможен только по поступившему на счет ТСП переводу
+	if original.Type != models.TransactionTransfer || original.Amount <= 0 || original.CounterpartID == nil {
+		return nil, ErrNotRefundable
+	}
+	isMerchant, err := s.sbpRepo.IsMerchantAccount(original.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMerchant {
+		return nil, ErrNotRefundable
+	}
+
+	payment, err := s.transactionRepo.GetTransactionByIDForUpdate(tx, *original.CounterpartID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrNotRefundable
+	}
+
+	reversed, refunded, err := s.transactionRepo.GetCompensations(tx, original.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := roundKopecks(original.Amount - refunded)
+	if reversed || remaining <= 0 {
+		return nil, ErrAlreadyReversed
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount < 0 || roundKopecks(amount) != amount {
+		return nil, ErrInvalidAmount
+	}
+	if amount > remaining {
+		return nil, ErrRefundTooLarge
+	}
+
+	legs := []*models.Transaction{original, payment}
+	accounts, err := s.lockLegAccounts(tx, legs)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkActive(accounts[0]); err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Refund of transaction #%d", original.ID)
+	if req.Reason != "" {
+		description += ": " + req.Reason
+	}
+
+	var refunds []*models.Transaction
+	for i, leg := range legs {
+		legAmount := amount
+		if leg.Amount > 0 {
+			legAmount = -amount
+		}
+		refund, err := s.postCompensation(tx, accounts[i], leg, legAmount, models.TransactionRefund, description)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	if err := s.linkCompensations(tx, refunds); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// lockLegAccounts блокирует счета проводок в порядке возрастания ID
+func (s *TransactionService) lockLegAccounts(tx *sql.Tx, legs []*models.Transaction) ([]*models.Account, error) {
+	if len(legs) == 2 {
+		first, second, err := lockTransferAccounts(tx, s.accountRepo, legs[0].AccountID, legs[1].AccountID)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Account{first, second}, nil
+	}
+
+	account, err := s.accountRepo.GetAccountByIDForUpdate(tx, legs[0].AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+	return []*models.Account{account}, nil
+}
+
+// postCompensation проводит по счету сумму, компенсирующую исходную проводку.
+// Списание возможно только в пределах доступного остатка.
+func (s *TransactionService) postCompensation(
+	tx *sql.Tx,
+	account *models.Account,
+	original *models.Transaction,
+	amount float64,
+	transactionType models.TransactionType,
+	description string,
+) (*models.Transaction, error) {
+	if err := checkCredit(account); err != nil {
+		return nil, err
+	}
+	if amount < 0 && account.AvailableBalance < -amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	if err := s.accountRepo.UpdateBalance(tx, account.ID, amount); err != nil {
+		return nil, err
+	}
+
+	compensation := &models.Transaction{
+		AccountID:             account.ID,
+		Amount:                amount,
+		Type:                  transactionType,
+		Description:           description,
+		OriginalTransactionID: &original.ID,
+	}
+	if err := s.transactionRepo.CreateTransaction(tx, compensation); err != nil {
+		return nil, err
 	}
 
-	if err := s.transactionRepo.CreateTransaction(tx, fromTransaction); err != nil {
+	return compensation, nil
+}
+
+// linkCompensations связывает компенсирующие проводки перевода между собой
+func (s *TransactionService) linkCompensations(tx *sql.Tx, compensations []*models.Transaction) error {
+	if len(compensations) != 2 {
+		return nil
+	}
+
+	first, second := compensations[0], compensations[1]
+	if err := s.transactionRepo.SetCounterpart(tx, first.ID, second.ID); err != nil {
 		return err
 	}
-	if err := s.transactionRepo.CreateTransaction(tx, toTransaction); err != nil {
+	if err := s.transactionRepo.SetCounterpart(tx, second.ID, first.ID); err != nil {
 		return err
 	}
+	first.CounterpartID = &second.ID
+	second.CounterpartID = &first.ID
+	return nil
+}
+
+// notifyPayment отправляет уведомление владельцу счета. Ошибки только
+// логируются: операция к этому моменту уже проведена.
+func (s *TransactionService) notifyPayment(userID int, amount float64, operation string) {
+	if s.notificationSvc == nil {
+		return
+	}
 
-	// Отправляем уведомление
-	if err := s.notificationSvc.SendPaymentNotification(
-		userEmail,
-		amount,
-	); err != nil {
-		log.Printf("Failed to send transfer notification: %v", err)
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil || user == nil {
+		log.Printf("Failed to find user %d for %s notification: %v", userID, operation, err)
+		return
 	}
 
-	return tx.Commit()
+	if err := s.notificationSvc.SendPaymentNotification(user.Email, amount); err != nil {
+		log.Printf("Failed to send %s notification: %v", operation, err)
+	}
 }
@@ -0,0 +1,90 @@
+package cbr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Номер лицевого счета по правилам Банка России состоит из 20 цифр:
+//
+//	ББББB ВВВ К ФФФФ ССССССС
+//
+// где Б - номер балансового счета второго порядка, В - код валюты,
+// К - контрольный ключ, Ф - код филиала, С - порядковый номер счета.
+// Ключ рассчитывается вместе с условным номером банка (три последние
+// цифры БИК) с весовыми коэффициентами 7, 1, 3.
+
+const (
+	AccountNumberLength = 20
+	controlKeyPosition  = 8
+)
+
+var (
+	ErrInvalidBIC           = errors.New("BIC must consist of 9 digits")
+	ErrInvalidAccountNumber = errors.New("invalid account number")
+)
+
+var controlWeights = [3]int{7, 1, 3}
+
+// BuildAccountNumber собирает номер счета и рассчитывает контрольный ключ
+func BuildAccountNumber(bic, balanceAccount, currencyCode, branch, serial string) (string, error) {
+	number := balanceAccount + currencyCode + "0" + branch + serial
+	if len(number) != AccountNumberLength || len(balanceAccount) != 5 || len(currencyCode) != 3 || !isDigits(number) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAccountNumber, number)
+	}
+
+	key, err := ControlKey(bic, number)
+	if err != nil {
+		return "", err
+	}
+
+	digits := []byte(number)
+	digits[controlKeyPosition] = byte('0' + key)
+	return string(digits), nil
+}
+
+// ControlKey рассчитывает контрольный ключ номера счета. Значение цифры
+// в позиции ключа при расчете не учитывается.
+func ControlKey(bic, account string) (int, error) {
+	if len(bic) != 9 || !isDigits(bic) {
+		return 0, ErrInvalidBIC
+	}
+	if len(account) != AccountNumberLength || !isDigits(account) {
+		return 0, ErrInvalidAccountNumber
+	}
+
+	digits := []byte(account)
+	digits[controlKeyPosition] = '0'
+
+	sum := weightedSum(bic[6:] + string(digits))
+	return (sum % 10) * 3 % 10, nil
+}
+
+// ValidateAccountNumber проверяет формат номера счета и его контрольный ключ
+func ValidateAccountNumber(bic, account string) bool {
+	if len(bic) != 9 || !isDigits(bic) {
+		return false
+	}
+	if len(account) != AccountNumberLength || !isDigits(account) {
+		return false
+	}
+	return weightedSum(bic[6:]+account)%10 == 0
+}
+
+// weightedSum суммирует младшие разряды произведений цифр на веса
+func weightedSum(digits string) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		sum += int(digits[i]-'0') * controlWeights[i%3] % 10
+	}
+	return sum
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
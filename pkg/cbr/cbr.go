@@ -81,14 +81,20 @@ func parseXMLResponse(rawBody []byte) (float64, error) {
 	return rate, nil
 }
 
-func GetKeyRate() (float64, error) {
+// FetchKeyRate возвращает ключевую ставку Банка России без надбавок
+func FetchKeyRate() (float64, error) {
 	soapRequest := buildSOAPRequest()
 	rawBody, err := sendRequest(soapRequest)
 	if err != nil {
 		return 0, err
 	}
 
-	rate, err := parseXMLResponse(rawBody)
+	return parseXMLResponse(rawBody)
+}
+
+// GetKeyRate возвращает ставку для кредитов: ключевую ставку с маржой банка
+func GetKeyRate() (float64, error) {
+	rate, err := FetchKeyRate()
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,132 @@
+package iso20022
+
+import (
+	"time"
+
+	"github.com/beevik/etree"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// Код клиринговой системы Банка России для идентификации банка по БИК
+const russianClearingSystem = "RUCBC"
+
+type Statement struct {
+	MessageID      string
+	StatementID    string
+	CreatedAt      time.Time
+	From           time.Time
+	To             time.Time
+	Account        string
+	Currency       string
+	OwnerName      string
+	ServicerBIC    string
+	OpeningBalance float64
+	ClosingBalance float64
+	Entries        []Entry
+}
+
+type Entry struct {
+	Reference   string
+	Amount      float64
+	Credit      bool
+	BookedAt    time.Time
+	Code        string
+	Information string
+}
+
+func (s *Statement) Bytes() ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+
+	root := doc.CreateElement("Document")
+	root.CreateAttr("xmlns", camt053Namespace)
+	msg := root.CreateElement("BkToCstmrStmt")
+
+	grpHdr := msg.CreateElement("GrpHdr")
+	grpHdr.CreateElement("MsgId").SetText(s.MessageID)
+	grpHdr.CreateElement("CreDtTm").SetText(formatDateTime(s.CreatedAt))
+
+	stmt := msg.CreateElement("Stmt")
+	stmt.CreateElement("Id").SetText(s.StatementID)
+	stmt.CreateElement("CreDtTm").SetText(formatDateTime(s.CreatedAt))
+	period := stmt.CreateElement("FrToDt")
+	period.CreateElement("FrDtTm").SetText(formatDateTime(s.From))
+	period.CreateElement("ToDtTm").SetText(formatDateTime(s.To))
+
+	acct := stmt.CreateElement("Acct")
+	acct.CreateElement("Id").CreateElement("Othr").CreateElement("Id").SetText(s.Account)
+	acct.CreateElement("Ccy").SetText(s.Currency)
+	if s.OwnerName != "" {
+		acct.CreateElement("Ownr").CreateElement("Nm").SetText(s.OwnerName)
+	}
+	if s.ServicerBIC != "" {
+		mmb := acct.CreateElement("Svcr").CreateElement("FinInstnId").CreateElement("ClrSysMmbId")
+		mmb.CreateElement("ClrSysId").CreateElement("Cd").SetText(russianClearingSystem)
+		mmb.CreateElement("MmbId").SetText(s.ServicerBIC)
+	}
+
+	addBalance(stmt, "OPBD", s.OpeningBalance, s.Currency, s.From)
+	addBalance(stmt, "CLBD", s.ClosingBalance, s.Currency, s.To)
+
+	var credits, debits []Entry
+	for _, e := range s.Entries {
+		if e.Credit {
+			credits = append(credits, e)
+		} else {
+			debits = append(debits, e)
+		}
+	}
+	summary := stmt.CreateElement("TxsSummry")
+	addSummary(summary.CreateElement("TtlCdtNtries"), credits)
+	addSummary(summary.CreateElement("TtlDbtNtries"), debits)
+
+	for _, e := range s.Entries {
+		ntry := stmt.CreateElement("Ntry")
+		ntry.CreateElement("NtryRef").SetText(e.Reference)
+		addAmount(ntry, "Amt", e.Amount, s.Currency)
+		ntry.CreateElement("CdtDbtInd").SetText(creditDebit(e.Credit))
+		ntry.CreateElement("Sts").SetText("BOOK")
+		ntry.CreateElement("BookgDt").CreateElement("DtTm").SetText(formatDateTime(e.BookedAt))
+		ntry.CreateElement("ValDt").CreateElement("Dt").SetText(formatDate(e.BookedAt))
+		ntry.CreateElement("BkTxCd").CreateElement("Prtry").CreateElement("Cd").SetText(e.Code)
+
+		details := ntry.CreateElement("NtryDtls").CreateElement("TxDtls")
+		details.CreateElement("Refs").CreateElement("AcctSvcrRef").SetText(e.Reference)
+		if e.Information != "" {
+			details.CreateElement("RmtInf").CreateElement("Ustrd").SetText(e.Information)
+		}
+	}
+
+	doc.Indent(2)
+	return doc.WriteToBytes()
+}
+
+func addBalance(stmt *etree.Element, code string, amount float64, currency string, date time.Time) {
+	bal := stmt.CreateElement("Bal")
+	bal.CreateElement("Tp").CreateElement("CdOrPrtry").CreateElement("Cd").SetText(code)
+
+	credit := amount >= 0
+	if !credit {
+		amount = -amount
+	}
+	addAmount(bal, "Amt", amount, currency)
+	bal.CreateElement("CdtDbtInd").SetText(creditDebit(credit))
+	bal.CreateElement("Dt").CreateElement("Dt").SetText(formatDate(date))
+}
+
+func addSummary(el *etree.Element, entries []Entry) {
+	var sum float64
+	for _, e := range entries {
+		sum += e.Amount
+	}
+	el.CreateElement("NbOfNtries").SetText(itoa(len(entries)))
+	el.CreateElement("Sum").SetText(formatAmount(sum))
+}
+
+func creditDebit(credit bool) string {
+	if credit {
+		return "CRDT"
+	}
+	return "DBIT"
+}
@@ -0,0 +1,43 @@
+package iso20022
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/beevik/etree"
+)
+
+func formatDateTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05")
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
+
+func addAmount(parent *etree.Element, tag string, amount float64, currency string) {
+	amt := parent.CreateElement(tag)
+	amt.CreateAttr("Ccy", currency)
+	amt.SetText(formatAmount(amount))
+}
+
+// childText возвращает текст вложенного элемента по относительному пути
+// или пустую строку, если элемента нет
+func childText(el *etree.Element, path string) string {
+	if el == nil {
+		return ""
+	}
+	child := el.FindElement(path)
+	if child == nil {
+		return ""
+	}
+	return child.Text()
+}
@@ -0,0 +1,171 @@
+package iso20022
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/beevik/etree"
+)
+
+var ErrInvalidDocument = errors.New("invalid pain.001 document")
+
+// CreditTransferInitiation - разобранное поручение клиента на перевод
+// средств (pain.001, CustomerCreditTransferInitiation)
+type CreditTransferInitiation struct {
+	MessageID       string
+	CreatedAt       string
+	NumberOfTxs     int
+	ControlSum      float64
+	InitiatingParty string
+	PaymentInfos    []PaymentInfo
+}
+
+type PaymentInfo struct {
+	ID             string
+	Method         string
+	ExecutionDate  string
+	DebtorName     string
+	DebtorAccount  string
+	NumberOfTxs    int
+	ControlSum     float64
+	HasControlSum  bool
+	CreditTransfer []CreditTransfer
+}
+
+type CreditTransfer struct {
+	InstructionID   string
+	EndToEndID      string
+	Amount          float64
+	Currency        string
+	CreditorName    string
+	CreditorAccount string
+	RemittanceInfo  string
+}
+
+func ParseCreditTransferInitiation(data []byte) (*CreditTransferInitiation, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	root := doc.FindElement("./Document/CstmrCdtTrfInitn")
+	if root == nil {
+		return nil, fmt.Errorf("%w: CstmrCdtTrfInitn not found", ErrInvalidDocument)
+	}
+
+	msg := &CreditTransferInitiation{
+		MessageID:       childText(root, "./GrpHdr/MsgId"),
+		CreatedAt:       childText(root, "./GrpHdr/CreDtTm"),
+		InitiatingParty: childText(root, "./GrpHdr/InitgPty/Nm"),
+	}
+	if msg.MessageID == "" {
+		return nil, fmt.Errorf("%w: MsgId is required", ErrInvalidDocument)
+	}
+
+	var err error
+	if msg.NumberOfTxs, err = strconv.Atoi(childText(root, "./GrpHdr/NbOfTxs")); err != nil {
+		return nil, fmt.Errorf("%w: invalid NbOfTxs", ErrInvalidDocument)
+	}
+	if ctrlSum := childText(root, "./GrpHdr/CtrlSum"); ctrlSum != "" {
+		if msg.ControlSum, err = strconv.ParseFloat(ctrlSum, 64); err != nil {
+			return nil, fmt.Errorf("%w: invalid CtrlSum", ErrInvalidDocument)
+		}
+	}
+
+	for _, pmtInf := range root.SelectElements("PmtInf") {
+		info := PaymentInfo{
+			ID:            childText(pmtInf, "./PmtInfId"),
+			Method:        childText(pmtInf, "./PmtMtd"),
+			ExecutionDate: childText(pmtInf, "./ReqdExctnDt"),
+			DebtorName:    childText(pmtInf, "./Dbtr/Nm"),
+			DebtorAccount: accountID(pmtInf.FindElement("./DbtrAcct/Id")),
+		}
+		if n := childText(pmtInf, "./NbOfTxs"); n != "" {
+			if info.NumberOfTxs, err = strconv.Atoi(n); err != nil {
+				return nil, fmt.Errorf("%w: invalid NbOfTxs in %s", ErrInvalidDocument, info.ID)
+			}
+		}
+		if sum := childText(pmtInf, "./CtrlSum"); sum != "" {
+			if info.ControlSum, err = strconv.ParseFloat(sum, 64); err != nil {
+				return nil, fmt.Errorf("%w: invalid CtrlSum in %s", ErrInvalidDocument, info.ID)
+			}
+			info.HasControlSum = true
+		}
+
+		for _, txInf := range pmtInf.SelectElements("CdtTrfTxInf") {
+			transfer := CreditTransfer{
+				InstructionID:   childText(txInf, "./PmtId/InstrId"),
+				EndToEndID:      childText(txInf, "./PmtId/EndToEndId"),
+				CreditorName:    childText(txInf, "./Cdtr/Nm"),
+				CreditorAccount: accountID(txInf.FindElement("./CdtrAcct/Id")),
+				RemittanceInfo:  childText(txInf, "./RmtInf/Ustrd"),
+			}
+			if amt := txInf.FindElement("./Amt/InstdAmt"); amt != nil {
+				transfer.Currency = amt.SelectAttrValue("Ccy", "")
+				// Некорректная сумма не отклоняет весь файл: такая строка
+				// получит отказ при исполнении
+				transfer.Amount, _ = strconv.ParseFloat(amt.Text(), 64)
+			}
+			info.CreditTransfer = append(info.CreditTransfer, transfer)
+		}
+
+		msg.PaymentInfos = append(msg.PaymentInfos, info)
+	}
+
+	return msg, nil
+}
+
+// Validate проверяет согласованность заголовков с содержимым файла
+func (m *CreditTransferInitiation) Validate() error {
+	if len(m.PaymentInfos) == 0 {
+		return fmt.Errorf("%w: no PmtInf blocks", ErrInvalidDocument)
+	}
+
+	total := 0
+	var sum float64
+	for _, info := range m.PaymentInfos {
+		if info.Method != "TRF" {
+			return fmt.Errorf("%w: unsupported PmtMtd %q in %s", ErrInvalidDocument, info.Method, info.ID)
+		}
+		if info.DebtorAccount == "" {
+			return fmt.Errorf("%w: DbtrAcct is required in %s", ErrInvalidDocument, info.ID)
+		}
+
+		var infoSum float64
+		for _, t := range info.CreditTransfer {
+			infoSum += t.Amount
+		}
+		if info.NumberOfTxs != 0 && info.NumberOfTxs != len(info.CreditTransfer) {
+			return fmt.Errorf("%w: NbOfTxs mismatch in %s", ErrInvalidDocument, info.ID)
+		}
+		if info.HasControlSum && !amountsEqual(info.ControlSum, infoSum) {
+			return fmt.Errorf("%w: CtrlSum mismatch in %s", ErrInvalidDocument, info.ID)
+		}
+
+		total += len(info.CreditTransfer)
+		sum += infoSum
+	}
+
+	if m.NumberOfTxs != total {
+		return fmt.Errorf("%w: NbOfTxs mismatch", ErrInvalidDocument)
+	}
+	if m.ControlSum != 0 && !amountsEqual(m.ControlSum, sum) {
+		return fmt.Errorf("%w: CtrlSum mismatch", ErrInvalidDocument)
+	}
+
+	return nil
+}
+
+// accountID извлекает идентификатор счета: IBAN либо прочий (Othr/Id)
+func accountID(id *etree.Element) string {
+	if iban := childText(id, "./IBAN"); iban != "" {
+		return iban
+	}
+	return childText(id, "./Othr/Id")
+}
+
+func amountsEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
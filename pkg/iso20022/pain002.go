@@ -0,0 +1,134 @@
+package iso20022
+
+import (
+	"time"
+
+	"github.com/beevik/etree"
+)
+
+const pain002Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.002.001.03"
+
+// Статусы групп и отдельных переводов
+const (
+	StatusAccepted          = "ACCP"
+	StatusAcceptedSettled   = "ACSC"
+	StatusPartiallyAccepted = "PART"
+	StatusRejected          = "RJCT"
+)
+
+// Коды причин отказа из внешнего справочника ISO 20022
+const (
+	ReasonIncorrectAccount   = "AC01"
+	ReasonClosedAccount      = "AC04"
+	ReasonBlockedAccount     = "AC06"
+	ReasonInvalidAmount      = "AM12"
+	ReasonNotAllowedCurrency = "AM03"
+	ReasonInsufficientFunds  = "AM04"
+	ReasonLimitExceeded      = "AM14"
+	ReasonDuplicate          = "DUPL"
+	ReasonNarrative          = "NARR"
+	ReasonFormatError        = "FF01"
+	ReasonInvalidDate        = "DT01"
+)
+
+type StatusReport struct {
+	MessageID           string
+	CreatedAt           time.Time
+	OriginalMessageID   string
+	OriginalNumberOfTxs int
+	OriginalControlSum  float64
+	GroupStatus         string
+	GroupReason         string
+	GroupInfo           string
+	PaymentInfos        []PaymentInfoStatus
+}
+
+type PaymentInfoStatus struct {
+	OriginalPaymentInfoID string
+	Status                string
+	Transactions          []TransactionStatus
+}
+
+type TransactionStatus struct {
+	StatusID              string
+	OriginalInstructionID string
+	OriginalEndToEndID    string
+	Status                string
+	Reason                string
+	AdditionalInfo        string
+}
+
+func (r *StatusReport) Bytes() ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+
+	root := doc.CreateElement("Document")
+	root.CreateAttr("xmlns", pain002Namespace)
+	msg := root.CreateElement("CstmrPmtStsRpt")
+
+	grpHdr := msg.CreateElement("GrpHdr")
+	grpHdr.CreateElement("MsgId").SetText(r.MessageID)
+	grpHdr.CreateElement("CreDtTm").SetText(formatDateTime(r.CreatedAt))
+
+	orgnl := msg.CreateElement("OrgnlGrpInfAndSts")
+	orgnl.CreateElement("OrgnlMsgId").SetText(r.OriginalMessageID)
+	orgnl.CreateElement("OrgnlMsgNmId").SetText("pain.001.001.03")
+	orgnl.CreateElement("OrgnlNbOfTxs").SetText(itoa(r.OriginalNumberOfTxs))
+	if r.OriginalControlSum != 0 {
+		orgnl.CreateElement("OrgnlCtrlSum").SetText(formatAmount(r.OriginalControlSum))
+	}
+	orgnl.CreateElement("GrpSts").SetText(r.GroupStatus)
+	addReason(orgnl, r.GroupReason, r.GroupInfo)
+
+	for _, info := range r.PaymentInfos {
+		pmt := msg.CreateElement("OrgnlPmtInfAndSts")
+		pmt.CreateElement("OrgnlPmtInfId").SetText(info.OriginalPaymentInfoID)
+		pmt.CreateElement("PmtInfSts").SetText(info.Status)
+
+		for _, tx := range info.Transactions {
+			el := pmt.CreateElement("TxInfAndSts")
+			el.CreateElement("StsId").SetText(tx.StatusID)
+			if tx.OriginalInstructionID != "" {
+				el.CreateElement("OrgnlInstrId").SetText(tx.OriginalInstructionID)
+			}
+			el.CreateElement("OrgnlEndToEndId").SetText(tx.OriginalEndToEndID)
+			el.CreateElement("TxSts").SetText(tx.Status)
+			addReason(el, tx.Reason, tx.AdditionalInfo)
+		}
+	}
+
+	doc.Indent(2)
+	return doc.WriteToBytes()
+}
+
+func addReason(parent *etree.Element, code, info string) {
+	if code == "" {
+		return
+	}
+	rsn := parent.CreateElement("StsRsnInf")
+	rsn.CreateElement("Rsn").CreateElement("Cd").SetText(code)
+	if info != "" {
+		rsn.CreateElement("AddtlInf").SetText(info)
+	}
+}
+
+// AggregateStatus вычисляет итоговый статус по статусам отдельных переводов
+func AggregateStatus(statuses []string) string {
+	accepted, rejected := 0, 0
+	for _, s := range statuses {
+		if s == StatusRejected {
+			rejected++
+		} else {
+			accepted++
+		}
+	}
+
+	switch {
+	case rejected == 0:
+		return StatusAcceptedSettled
+	case accepted == 0:
+		return StatusRejected
+	default:
+		return StatusPartiallyAccepted
+	}
+}
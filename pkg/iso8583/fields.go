@@ -0,0 +1,74 @@
+package iso8583
+
+// lengthType - способ задания длины поля
+type lengthType int
+
+const (
+	fixed lengthType = iota
+	// Длина передается двумя цифрами перед значением
+	llvar
+	// Длина передается тремя цифрами перед значением
+	lllvar
+)
+
+// charset - допустимые символы поля: n - цифры, an - буквы и цифры,
+// ans - любые печатные символы, b - двоичные данные
+type charset int
+
+const (
+	charsetNumeric charset = iota
+	charsetAlphanumeric
+	charsetAny
+	charsetBinary
+)
+
+// fieldSpec описывает формат поля. Для двоичных полей длина указывается
+// в байтах, а значение передается в шестнадцатеричном виде.
+type fieldSpec struct {
+	length  lengthType
+	max     int
+	charset charset
+}
+
+// Поля ISO 8583:1987, которые банк принимает и отправляет. Сообщение с полем,
+// формат которого неизвестен, разобрать нельзя.
+var fieldSpecs = map[int]fieldSpec{
+	2:   {llvar, 19, charsetNumeric},      // номер карты
+	3:   {fixed, 6, charsetNumeric},       // код обработки
+	4:   {fixed, 12, charsetNumeric},      // сумма операции в минимальных единицах
+	7:   {fixed, 10, charsetNumeric},      // дата и время передачи, MMDDhhmmss
+	11:  {fixed, 6, charsetNumeric},       // номер операции у отправителя (STAN)
+	12:  {fixed, 6, charsetNumeric},       // местное время операции, hhmmss
+	13:  {fixed, 4, charsetNumeric},       // местная дата операции, MMDD
+	14:  {fixed, 4, charsetNumeric},       // срок действия карты, YYMM
+	15:  {fixed, 4, charsetNumeric},       // дата расчетов, MMDD
+	18:  {fixed, 4, charsetNumeric},       // код категории ТСП (MCC)
+	22:  {fixed, 3, charsetNumeric},       // способ ввода реквизитов
+	23:  {fixed, 3, charsetNumeric},       // порядковый номер карты
+	25:  {fixed, 2, charsetNumeric},       // условия проведения операции
+	32:  {llvar, 11, charsetNumeric},      // идентификатор эквайрера
+	33:  {llvar, 11, charsetNumeric},      // идентификатор отправителя
+	35:  {llvar, 37, charsetAny},          // вторая дорожка
+	37:  {fixed, 12, charsetAlphanumeric}, // RRN
+	38:  {fixed, 6, charsetAlphanumeric},  // код авторизации
+	39:  {fixed, 2, charsetAlphanumeric},  // код ответа
+	41:  {fixed, 8, charsetAny},           // идентификатор терминала
+	42:  {fixed, 15, charsetAny},          // идентификатор ТСП
+	43:  {fixed, 40, charsetAny},          // название и адрес ТСП
+	48:  {lllvar, 999, charsetAny},        // дополнительные данные
+	49:  {fixed, 3, charsetNumeric},       // код валюты операции
+	52:  {fixed, 8, charsetBinary},        // PIN-блок
+	53:  {fixed, 16, charsetNumeric},      // параметры безопасности
+	54:  {lllvar, 120, charsetAny},        // дополнительные суммы
+	55:  {lllvar, 999, charsetBinary},     // данные чипа
+	60:  {lllvar, 999, charsetAny},        // зарезервировано для национального использования
+	61:  {lllvar, 999, charsetAny},        // зарезервировано для национального использования
+	62:  {lllvar, 999, charsetAny},        // зарезервировано для частного использования
+	63:  {lllvar, 999, charsetAny},        // зарезервировано для частного использования
+	70:  {fixed, 3, charsetNumeric},       // код сетевого управления
+	90:  {fixed, 42, charsetNumeric},      // данные исходной операции
+	95:  {fixed, 42, charsetAny},          // суммы замены при частичной отмене
+	102: {llvar, 28, charsetAny},          // счет списания
+	103: {llvar, 28, charsetAny},          // счет зачисления
+	128: {fixed, 8, charsetBinary},        // MAC
+}
@@ -0,0 +1,81 @@
+package iso8583
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Сообщения подписываются MAC в поле 128: первые 8 байт HMAC-SHA256 от
+// упакованного сообщения без самого поля. Ключ выбирается по коду эквайрера
+// в поле 32.
+
+const (
+	FieldAcquirer = 32
+	FieldMAC      = 128
+	// Длина поля 128 в упакованном сообщении
+	macLength = 16
+)
+
+var ErrUnauthenticated = errors.New("ISO 8583 message is not authenticated")
+
+// ComputeMAC вычисляет MAC для упакованного сообщения без поля 128
+func ComputeMAC(data, key []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)[:macLength/2]))
+}
+
+// VerifyMAC проверяет MAC разобранного сообщения по его упакованному виду.
+// Поле 128 - последнее, поэтому подписана вся строка до него.
+func VerifyMAC(message *Message, data []byte, key []byte) bool {
+	if !message.Has(FieldMAC) || len(data) < macLength {
+		return false
+	}
+	expected := ComputeMAC(data[:len(data)-macLength], key)
+	return hmac.Equal([]byte(expected), []byte(strings.ToUpper(message.Get(FieldMAC))))
+}
+
+// PackMAC упаковывает сообщение и подписывает его ключом
+func (m *Message) PackMAC(key []byte) ([]byte, error) {
+	m.Set(FieldMAC, strings.Repeat("0", macLength))
+	data, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	copy(data[len(data)-macLength:], ComputeMAC(data[:len(data)-macLength], key))
+	m.Set(FieldMAC, string(data[len(data)-macLength:]))
+	return data, nil
+}
+
+// ParseKeys разбирает ключи MAC эквайреров в формате
+// "<код эквайрера>=<ключ hex>;..."
+func ParseKeys(spec string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		acquirer, keyHex, found := strings.Cut(item, "=")
+		acquirer = strings.TrimSpace(acquirer)
+		if !found || acquirer == "" || !isNumeric(acquirer) {
+			return nil, fmt.Errorf("invalid acquirer key %q", item)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(keyHex))
+		if err != nil || len(key) < 16 {
+			return nil, fmt.Errorf("acquirer %s: key must be at least 16 bytes in hex", acquirer)
+		}
+		keys[acquirer] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no acquirer keys configured")
+	}
+	return keys, nil
+}
@@ -0,0 +1,279 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Сообщение кодируется в ASCII: MTI из четырех цифр, битовая карта в виде
+// шестнадцатеричной строки (вторичная карта - при полях 65-128) и значения
+// полей в порядке номеров. Двоичные поля передаются в шестнадцатеричном виде.
+
+const (
+	MTIAuthorizationRequest  = "0100"
+	MTIAuthorizationResponse = "0110"
+	MTIFinancialRequest      = "0200"
+	MTIFinancialResponse     = "0210"
+	MTIReversalRequest       = "0400"
+	MTIReversalResponse      = "0410"
+	MTINetworkRequest        = "0800"
+	MTINetworkResponse       = "0810"
+)
+
+const (
+	mtiLength    = 4
+	bitmapLength = 16
+	maxField     = 128
+)
+
+var (
+	ErrInvalidMessage = errors.New("invalid ISO 8583 message")
+	ErrUnknownField   = errors.New("unknown ISO 8583 field")
+)
+
+type Message struct {
+	MTI    string
+	fields map[int]string
+}
+
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, fields: make(map[int]string)}
+}
+
+// Get возвращает значение поля или пустую строку, если поля нет
+func (m *Message) Get(field int) string {
+	return m.fields[field]
+}
+
+func (m *Message) Has(field int) bool {
+	_, ok := m.fields[field]
+	return ok
+}
+
+func (m *Message) Set(field int, value string) {
+	m.fields[field] = value
+}
+
+// Fields возвращает номера полей сообщения по возрастанию
+func (m *Message) Fields() []int {
+	fields := make([]int, 0, len(m.fields))
+	for field := range m.fields {
+		fields = append(fields, field)
+	}
+	sort.Ints(fields)
+	return fields
+}
+
+// Response создает ответное сообщение (0100 -> 0110) и копирует в него
+// указанные поля запроса, если они есть
+func (m *Message) Response(fields ...int) *Message {
+	mti := []byte(m.MTI)
+	if len(mti) == mtiLength {
+		mti[2]++
+	}
+
+	response := NewMessage(string(mti))
+	for _, field := range fields {
+		if value, ok := m.fields[field]; ok {
+			response.fields[field] = value
+		}
+	}
+	return response
+}
+
+// Pack кодирует сообщение. Числовые поля фиксированной длины дополняются
+// нулями слева, символьные - пробелами справа.
+func (m *Message) Pack() ([]byte, error) {
+	if len(m.MTI) != mtiLength || !isNumeric(m.MTI) {
+		return nil, fmt.Errorf("%w: MTI %q", ErrInvalidMessage, m.MTI)
+	}
+
+	fields := m.Fields()
+	var bitmap [maxField / 8]byte
+	secondary := false
+	for _, field := range fields {
+		if field < 2 || field > maxField {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownField, field)
+		}
+		if field > 64 {
+			secondary = true
+		}
+		bitmap[(field-1)/8] |= 0x80 >> ((field - 1) % 8)
+	}
+	bitmapBytes := bitmap[:8]
+	if secondary {
+		bitmap[0] |= 0x80
+		bitmapBytes = bitmap[:]
+	}
+
+	var b strings.Builder
+	b.WriteString(m.MTI)
+	b.WriteString(strings.ToUpper(hex.EncodeToString(bitmapBytes)))
+
+	for _, field := range fields {
+		encoded, err := packField(field, m.fields[field])
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(encoded)
+	}
+
+	return []byte(b.String()), nil
+}
+
+// Unpack разбирает сообщение
+func Unpack(data []byte) (*Message, error) {
+	s := string(data)
+	if len(s) < mtiLength+bitmapLength || !isNumeric(s[:mtiLength]) {
+		return nil, fmt.Errorf("%w: message is too short", ErrInvalidMessage)
+	}
+
+	m := NewMessage(s[:mtiLength])
+	pos := mtiLength
+
+	bitmap, err := hex.DecodeString(s[pos : pos+bitmapLength])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bitmap", ErrInvalidMessage)
+	}
+	pos += bitmapLength
+
+	if bitmap[0]&0x80 != 0 {
+		if len(s) < pos+bitmapLength {
+			return nil, fmt.Errorf("%w: secondary bitmap", ErrInvalidMessage)
+		}
+		secondary, err := hex.DecodeString(s[pos : pos+bitmapLength])
+		if err != nil {
+			return nil, fmt.Errorf("%w: secondary bitmap", ErrInvalidMessage)
+		}
+		bitmap = append(bitmap, secondary...)
+		pos += bitmapLength
+	}
+
+	for field := 2; field <= len(bitmap)*8; field++ {
+		if bitmap[(field-1)/8]&(0x80>>((field-1)%8)) == 0 {
+			continue
+		}
+
+		value, n, err := unpackField(field, s[pos:])
+		if err != nil {
+			return nil, err
+		}
+		m.fields[field] = value
+		pos += n
+	}
+
+	if pos != len(s) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidMessage, len(s)-pos)
+	}
+
+	return m, nil
+}
+
+func packField(field int, value string) (string, error) {
+	spec, ok := fieldSpecs[field]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownField, field)
+	}
+
+	size := spec.max
+	if spec.charset == charsetBinary {
+		size *= 2
+		value = strings.ToUpper(value)
+	}
+
+	if spec.length == fixed {
+		switch {
+		case len(value) > size:
+		case spec.charset == charsetNumeric:
+			value = strings.Repeat("0", size-len(value)) + value
+		case spec.charset != charsetBinary:
+			value += strings.Repeat(" ", size-len(value))
+		}
+	}
+	if len(value) > size || (spec.length == fixed && len(value) != size) || !validValue(spec.charset, value) {
+		return "", fmt.Errorf("%w: field %d value %q", ErrInvalidMessage, field, value)
+	}
+
+	switch spec.length {
+	case llvar:
+		return fmt.Sprintf("%02d%s", len(value), value), nil
+	case lllvar:
+		return fmt.Sprintf("%03d%s", len(value), value), nil
+	}
+	return value, nil
+}
+
+// unpackField возвращает значение поля и число прочитанных символов
+func unpackField(field int, s string) (string, int, error) {
+	spec, ok := fieldSpecs[field]
+	if !ok {
+		return "", 0, fmt.Errorf("%w: %d", ErrUnknownField, field)
+	}
+
+	prefix := 0
+	switch spec.length {
+	case llvar:
+		prefix = 2
+	case lllvar:
+		prefix = 3
+	}
+
+	size := spec.max
+	if spec.charset == charsetBinary {
+		size *= 2
+	}
+	if prefix > 0 {
+		if len(s) < prefix {
+			return "", 0, fmt.Errorf("%w: field %d length", ErrInvalidMessage, field)
+		}
+		n, err := strconv.Atoi(s[:prefix])
+		if err != nil || n > size {
+			return "", 0, fmt.Errorf("%w: field %d length", ErrInvalidMessage, field)
+		}
+		size = n
+	}
+
+	if len(s) < prefix+size {
+		return "", 0, fmt.Errorf("%w: field %d is truncated", ErrInvalidMessage, field)
+	}
+	value := s[prefix : prefix+size]
+	if !validValue(spec.charset, value) {
+		return "", 0, fmt.Errorf("%w: field %d value %q", ErrInvalidMessage, field, value)
+	}
+
+	return value, prefix + size, nil
+}
+
+func validValue(cs charset, value string) bool {
+	switch cs {
+	case charsetNumeric:
+		return isNumeric(value)
+	case charsetBinary:
+		_, err := hex.DecodeString(value)
+		return err == nil
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+		if cs == charsetAlphanumeric && !(c == ' ' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
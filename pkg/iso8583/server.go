@@ -0,0 +1,163 @@
+package iso8583
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// Сообщения передаются по TCP с заголовком длины из двух байт в сетевом
+// порядке, как в большинстве тестовых терминалов и хостов.
+
+// Handler обрабатывает запрос и возвращает ответ. Если ответ не нужен,
+// возвращается nil.
+type Handler interface {
+	Handle(request *Message) *Message
+}
+
+type HandlerFunc func(request *Message) *Message
+
+func (f HandlerFunc) Handle(request *Message) *Message {
+	return f(request)
+}
+
+type Server struct {
+	Addr    string
+	Handler Handler
+	// Ключи MAC по кодам эквайреров. Соединение закрепляется за эквайрером
+	// первого сообщения; сообщение без верного MAC или от другого эквайрера
+	// закрывает соединение.
+	Keys map[string][]byte
+	// Соединение закрывается, если терминал молчит дольше этого времени;
+	// 0 - без ограничения
+	IdleTimeout time.Duration
+}
+
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+func (s *Server) Serve(ln net.Listener) error {
+	defer ln.Close()
+
+	if len(s.Keys) == 0 {
+		return errors.New("iso8583: no acquirer keys configured")
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn обрабатывает сообщения соединения по очереди. Неразобранное
+// сообщение пропускается: ответить на него нельзя, не зная его MTI.
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	acquirer := ""
+	for {
+		if s.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+
+		data, err := ReadFrame(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("ISO 8583 connection %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		request, err := Unpack(data)
+		if err != nil {
+			log.Printf("ISO 8583 message from %s rejected: %v", conn.RemoteAddr(), err)
+			continue
+		}
+
+		key, err := s.authenticate(request, data, acquirer)
+		if err != nil {
+			log.Printf("ISO 8583 connection %s closed: %v", conn.RemoteAddr(), err)
+			return
+		}
+		acquirer = request.Get(FieldAcquirer)
+
+		response := s.Handler.Handle(request)
+		if response == nil {
+			continue
+		}
+		response.Set(FieldAcquirer, acquirer)
+
+		out, err := response.PackMAC(key)
+		if err != nil {
+			log.Printf("ISO 8583 response %s not sent: %v", response.MTI, err)
+			continue
+		}
+		if err := WriteFrame(conn, out); err != nil {
+			log.Printf("ISO 8583 connection %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// authenticate проверяет код эквайрера и MAC сообщения и возвращает ключ
+// эквайрера
+func (s *Server) authenticate(request *Message, data []byte, acquirer string) ([]byte, error) {
+	code := request.Get(FieldAcquirer)
+	if acquirer != "" && code != acquirer {
+		return nil, fmt.Errorf("%w: acquirer changed from %s to %q", ErrUnauthenticated, acquirer, code)
+	}
+
+	key, ok := s.Keys[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown acquirer %q", ErrUnauthenticated, code)
+	}
+	if !VerifyMAC(request, data, key) {
+		return nil, fmt.Errorf("%w: invalid MAC from acquirer %s", ErrUnauthenticated, code)
+	}
+	return key, nil
+}
+
+// ReadFrame читает сообщение с заголовком длины
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint16(header[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteFrame отправляет сообщение с заголовком длины
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > 0xffff {
+		return fmt.Errorf("%w: message is too long", ErrInvalidMessage)
+	}
+
+	frame := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	copy(frame[2:], data)
+
+	_, err := w.Write(frame)
+	return err
+}
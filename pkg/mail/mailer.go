@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"bytes"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -26,13 +27,33 @@ func NewMailer(host string, port int, username, password, from string) *Mailer {
 	}
 }
 
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
 func (m *Mailer) Send(to, subject, body string) error {
+	return m.SendWithAttachments(to, subject, body)
+}
+
+func (m *Mailer) SendWithAttachments(to, subject, body string, attachments ...Attachment) error {
 	msg := mail.NewMessage()
 	msg.SetHeader("From", m.from)
 	msg.SetHeader("To", to)
 	msg.SetHeader("Subject", subject)
 	msg.SetBody("text/html", body)
 
+	for _, a := range attachments {
+		var settings []mail.FileSetting
+		if a.ContentType != "" {
+			settings = append(settings, mail.SetHeader(map[string][]string{
+				"Content-Type": {a.ContentType},
+			}))
+		}
+		msg.AttachReader(a.Filename, bytes.NewReader(a.Data), settings...)
+	}
+
 	if err := m.dialer.DialAndSend(msg); err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
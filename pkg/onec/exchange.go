@@ -0,0 +1,145 @@
+package onec
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Формирование файлов обмена 1CClientBankExchange (версия формата 1.03),
+// которые импортирует бухгалтерия на базе 1С. Файл пишется в кодировке
+// Windows-1251 с переводами строк CRLF.
+
+const formatVersion = "1.03"
+
+type Exchange struct {
+	Sender         string
+	Receiver       string
+	CreatedAt      time.Time
+	From           time.Time
+	To             time.Time
+	Account        string
+	OpeningBalance float64
+	TotalCredit    float64
+	TotalDebit     float64
+	ClosingBalance float64
+	Documents      []Document
+}
+
+type Document struct {
+	Kind             string
+	Number           string
+	Date             time.Time
+	Amount           float64
+	PayerAccount     string
+	Payer            string
+	PayerBIC         string
+	RecipientAccount string
+	Recipient        string
+	RecipientBIC     string
+	Purpose          string
+	// Дата списания заполняется для расходных документов,
+	// дата поступления - для приходных
+	DebitedAt  *time.Time
+	CreditedAt *time.Time
+}
+
+func (e *Exchange) Bytes() []byte {
+	var buf bytes.Buffer
+	marker := func(name string) {
+		buf.WriteString(name + "\r\n")
+	}
+	line := func(key, value string) {
+		buf.WriteString(key + "=" + value + "\r\n")
+	}
+
+	marker("1CClientBankExchange")
+	line("ВерсияФормата", formatVersion)
+	line("Кодировка", "Windows")
+	line("Отправитель", e.Sender)
+	line("Получатель", e.Receiver)
+	line("ДатаСоздания", formatDate(e.CreatedAt))
+	line("ВремяСоздания", e.CreatedAt.Format("15:04:05"))
+	line("ДатаНачала", formatDate(e.From))
+	line("ДатаКонца", formatDate(e.To))
+	line("РасчСчет", e.Account)
+	line("Документ", "Платежное поручение")
+
+	marker("СекцияРасчСчет")
+	line("ДатаНачала", formatDate(e.From))
+	line("ДатаКонца", formatDate(e.To))
+	line("РасчСчет", e.Account)
+	line("НачальныйОстаток", formatAmount(e.OpeningBalance))
+	line("ВсегоПоступило", formatAmount(e.TotalCredit))
+	line("ВсегоСписано", formatAmount(e.TotalDebit))
+	line("КонечныйОстаток", formatAmount(e.ClosingBalance))
+	marker("КонецРасчСчет")
+
+	for _, doc := range e.Documents {
+		kind := doc.Kind
+		if kind == "" {
+			kind = "Платежное поручение"
+		}
+		line("СекцияДокумент", kind)
+		line("Номер", doc.Number)
+		line("Дата", formatDate(doc.Date))
+		line("Сумма", formatAmount(doc.Amount))
+		line("ПлательщикСчет", doc.PayerAccount)
+		line("Плательщик", doc.Payer)
+		line("ПлательщикБИК", doc.PayerBIC)
+		line("ПолучательСчет", doc.RecipientAccount)
+		line("Получатель", doc.Recipient)
+		line("ПолучательБИК", doc.RecipientBIC)
+		if doc.DebitedAt != nil {
+			line("ДатаСписано", formatDate(*doc.DebitedAt))
+		}
+		if doc.CreditedAt != nil {
+			line("ДатаПоступило", formatDate(*doc.CreditedAt))
+		}
+		line("НазначениеПлатежа", doc.Purpose)
+		marker("КонецДокумента")
+	}
+
+	marker("КонецФайла")
+
+	return EncodeWindows1251(buf.String())
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("02.01.2006")
+}
+
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// EncodeWindows1251 перекодирует строку в Windows-1251. Символы, которых нет
+// в кодовой странице, заменяются знаком вопроса.
+func EncodeWindows1251(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case r >= 'А' && r <= 'я':
+			out = append(out, byte(r-'А'+0xC0))
+		case r == 'Ё':
+			out = append(out, 0xA8)
+		case r == 'ё':
+			out = append(out, 0xB8)
+		case r == '№':
+			out = append(out, 0xB9)
+		case r == '«':
+			out = append(out, 0xAB)
+		case r == '»':
+			out = append(out, 0xBB)
+		case r == '–':
+			out = append(out, 0x96)
+		case r == '—':
+			out = append(out, 0x97)
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
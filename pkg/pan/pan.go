@@ -0,0 +1,116 @@
+package pan
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Номер карты (PAN) состоит из BIN - идентификатора эмитента, номера карты
+// у эмитента и контрольной цифры, рассчитанной по алгоритму Луна.
+
+const (
+	// Длина номеров карт, которые выпускает банк
+	Length    = 16
+	BINLength = 6
+	minLength = 13
+	maxLength = 19
+)
+
+var (
+	ErrInvalidNumber   = errors.New("invalid card number")
+	ErrInvalidBINRange = errors.New("invalid BIN range")
+)
+
+// CheckDigit рассчитывает контрольную цифру для номера без нее
+func CheckDigit(payload string) (int, error) {
+	if !isDigits(payload) {
+		return 0, ErrInvalidNumber
+	}
+
+	sum := luhnSum(payload, true)
+	return (10 - sum%10) % 10, nil
+}
+
+// Build дополняет номер контрольной цифрой
+func Build(payload string) (string, error) {
+	digit, err := CheckDigit(payload)
+	if err != nil {
+		return "", err
+	}
+	return payload + strconv.Itoa(digit), nil
+}
+
+// Valid проверяет длину номера и контрольную цифру. Пробелы между группами
+// цифр не допускаются, их нужно удалить до проверки.
+func Valid(number string) bool {
+	if len(number) < minLength || len(number) > maxLength || !isDigits(number) {
+		return false
+	}
+	return luhnSum(number, false)%10 == 0
+}
+
+// Normalize удаляет пробелы и дефисы, которыми клиенты разделяют группы цифр
+func Normalize(number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(number))
+}
+
+// BINRange - диапазон BIN, из которого выпускаются карты продукта
+type BINRange struct {
+	From int
+	To   int
+}
+
+// ParseBINRange разбирает диапазон вида "220070-220079" или одиночный BIN
+func ParseBINRange(s string) (BINRange, error) {
+	from, to, found := strings.Cut(strings.TrimSpace(s), "-")
+	if !found {
+		to = from
+	}
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if len(from) != BINLength || len(to) != BINLength || !isDigits(from) || !isDigits(to) {
+		return BINRange{}, fmt.Errorf("%w: %q", ErrInvalidBINRange, s)
+	}
+
+	r := BINRange{}
+	r.From, _ = strconv.Atoi(from)
+	r.To, _ = strconv.Atoi(to)
+	if r.From > r.To || from[0] == '0' {
+		return BINRange{}, fmt.Errorf("%w: %q", ErrInvalidBINRange, s)
+	}
+	return r, nil
+}
+
+// Size возвращает число BIN в диапазоне
+func (r BINRange) Size() int {
+	return r.To - r.From + 1
+}
+
+// luhnSum суммирует цифры, удваивая каждую вторую справа. Если контрольной
+// цифры в номере еще нет, удваивается последняя цифра.
+func luhnSum(digits string, withoutCheck bool) int {
+	sum := 0
+	double := withoutCheck
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
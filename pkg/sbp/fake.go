@@ -0,0 +1,170 @@
+package sbp
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const qrcIDAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Fake - эмуляция НСПК для локальной разработки и тестов без доступа
+// к СБП. Реестр ТСП и QR-кодов хранится в памяти процесса.
+type Fake struct {
+	mu        sync.Mutex
+	merchants map[string]string
+	qrs       map[string]*QR
+	nextID    int
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		merchants: make(map[string]string),
+		qrs:       make(map[string]*QR),
+	}
+}
+
+func (f *Fake) RegisterMerchant(req *RegisterMerchantRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	merchantID := fmt.Sprintf("MA%010d", f.nextID)
+	f.merchants[merchantID] = req.BankID
+	return merchantID, nil
+}
+
+func (f *Fake) RegisterQR(req *RegisterQRRequest) (*QR, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bankID, ok := f.merchants[req.MerchantID]
+	if !ok || bankID != req.BankID {
+		return nil, ErrMerchantNotFound
+	}
+
+	qrcID, err := generateQRCID(req.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	qr := &QR{
+		QRCID:      qrcID,
+		Type:       req.Type,
+		BankID:     req.BankID,
+		MerchantID: req.MerchantID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Purpose:    req.Purpose,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedAt:  time.Now(),
+	}
+	qr.Payload = Link{
+		QRCID:    qr.QRCID,
+		Type:     qr.Type,
+		BankID:   qr.BankID,
+		Amount:   qr.Amount,
+		Currency: qr.Currency,
+		Purpose:  qr.Purpose,
+	}.String()
+
+	f.qrs[qrcID] = qr
+	return qr, nil
+}
+
+func (f *Fake) ResolveQR(qrcID string) (*QR, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	qr, ok := f.qrs[qrcID]
+	if !ok {
+		return nil, ErrQRNotFound
+	}
+	copied := *qr
+	return &copied, nil
+}
+
+// Handler предоставляет эмуляцию по HTTP в формате, который ожидает Client
+func (f *Fake) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /merchants", func(w http.ResponseWriter, r *http.Request) {
+		var req RegisterMerchantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		merchantID, err := f.RegisterMerchant(&req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, RegisterMerchantResponse{MerchantID: merchantID})
+	})
+
+	mux.HandleFunc("POST /qrc", func(w http.ResponseWriter, r *http.Request) {
+		var req RegisterQRRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		qr, err := f.RegisterQR(&req)
+		if err == ErrMerchantNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, qr)
+	})
+
+	mux.HandleFunc("GET /qrc/{id}", func(w http.ResponseWriter, r *http.Request) {
+		qr, err := f.ResolveQR(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, qr)
+	})
+
+	return mux
+}
+
+// generateQRCID формирует идентификатор QR-кода: префикс AS для
+// статических и AD для динамических кодов и случайные символы
+func generateQRCID(qrType QRType) (string, error) {
+	prefix := "AS"
+	if qrType == QRTypeDynamic {
+		prefix = "AD"
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	limit := big.NewInt(int64(len(qrcIDAlphabet)))
+	for b.Len() < QRCIDLength {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(qrcIDAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,126 @@
+package sbp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Функциональная ссылка СБП имеет вид
+//
+//	https://qr.nspk.ru/AS1000670LSS7DN18SJQDNP4B05KLJL2?type=01&bank=100000000111&sum=10000&cur=RUB&crc=AB75
+//
+// где путь - идентификатор QR-кода в НСПК, type - тип QR-кода (01 - статический,
+// 02 - динамический), bank - идентификатор банка получателя в СБП, sum - сумма
+// в копейках, cur - валюта, crc - контрольная сумма CRC16 всей ссылки до
+// параметра crc. Назначение платежа передается в необязательном параметре
+// payment_purpose.
+
+const (
+	linkScheme = "https"
+	linkHost   = "qr.nspk.ru"
+
+	QRCIDLength = 32
+)
+
+type QRType string
+
+const (
+	QRTypeStatic  QRType = "01"
+	QRTypeDynamic QRType = "02"
+)
+
+var (
+	ErrInvalidLink = errors.New("invalid SBP payment link")
+	ErrInvalidCRC  = errors.New("SBP payment link checksum mismatch")
+)
+
+// Link - разобранная функциональная ссылка
+type Link struct {
+	QRCID    string
+	Type     QRType
+	BankID   string
+	Amount   int64 // в копейках, 0 - сумму вводит плательщик
+	Currency string
+	Purpose  string
+}
+
+// String собирает ссылку с контрольной суммой. Порядок параметров
+// фиксирован, так как от него зависит CRC.
+func (l Link) String() string {
+	var b strings.Builder
+	b.WriteString(linkScheme + "://" + linkHost + "/" + l.QRCID)
+	b.WriteString("?type=" + string(l.Type))
+	b.WriteString("&bank=" + l.BankID)
+	if l.Amount > 0 {
+		b.WriteString("&sum=" + strconv.FormatInt(l.Amount, 10))
+	}
+	if l.Currency != "" {
+		b.WriteString("&cur=" + l.Currency)
+	}
+	if l.Purpose != "" {
+		b.WriteString("&payment_purpose=" + url.QueryEscape(l.Purpose))
+	}
+
+	body := b.String()
+	return body + fmt.Sprintf("&crc=%04X", crc16(body))
+}
+
+// ParseLink разбирает функциональную ссылку и проверяет контрольную сумму
+func ParseLink(raw string) (*Link, error) {
+	raw = strings.TrimSpace(raw)
+
+	idx := strings.LastIndex(raw, "&crc=")
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: missing crc", ErrInvalidLink)
+	}
+	body, checksum := raw[:idx], raw[idx+len("&crc="):]
+	if fmt.Sprintf("%04X", crc16(body)) != strings.ToUpper(checksum) {
+		return nil, ErrInvalidCRC
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != linkScheme || u.Host != linkHost {
+		return nil, ErrInvalidLink
+	}
+
+	link := &Link{
+		QRCID:    strings.TrimPrefix(u.Path, "/"),
+		Type:     QRType(u.Query().Get("type")),
+		BankID:   u.Query().Get("bank"),
+		Currency: u.Query().Get("cur"),
+		Purpose:  u.Query().Get("payment_purpose"),
+	}
+	if len(link.QRCID) != QRCIDLength || link.BankID == "" {
+		return nil, ErrInvalidLink
+	}
+	if link.Type != QRTypeStatic && link.Type != QRTypeDynamic {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidLink, link.Type)
+	}
+	if sum := u.Query().Get("sum"); sum != "" {
+		if link.Amount, err = strconv.ParseInt(sum, 10, 64); err != nil || link.Amount <= 0 {
+			return nil, fmt.Errorf("%w: invalid sum", ErrInvalidLink)
+		}
+	}
+
+	return link, nil
+}
+
+// crc16 рассчитывает CRC-16/CCITT-FALSE (полином 0x1021, начальное
+// значение 0xFFFF)
+func crc16(s string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,134 @@
+package sbp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrQRNotFound       = errors.New("QR code not found")
+	ErrMerchantNotFound = errors.New("merchant not found")
+
+	errNotFound = errors.New("not found")
+)
+
+type RegisterMerchantRequest struct {
+	BankID string `json:"bank_id"`
+	Name   string `json:"name"`
+}
+
+type RegisterMerchantResponse struct {
+	MerchantID string `json:"merchant_id"`
+}
+
+type RegisterQRRequest struct {
+	BankID     string     `json:"bank_id"`
+	MerchantID string     `json:"merchant_id"`
+	Type       QRType     `json:"type"`
+	Amount     int64      `json:"amount"`
+	Currency   string     `json:"currency"`
+	Purpose    string     `json:"purpose"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// QR - QR-код, зарегистрированный в НСПК
+type QR struct {
+	QRCID      string     `json:"qrc_id"`
+	Type       QRType     `json:"type"`
+	BankID     string     `json:"bank_id"`
+	MerchantID string     `json:"merchant_id"`
+	Amount     int64      `json:"amount"`
+	Currency   string     `json:"currency"`
+	Purpose    string     `json:"purpose"`
+	Payload    string     `json:"payload"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NSPK - операции Национальной системы платежных карт, которые банк
+// выполняет при работе с QR-кодами СБП
+type NSPK interface {
+	RegisterMerchant(req *RegisterMerchantRequest) (string, error)
+	RegisterQR(req *RegisterQRRequest) (*QR, error)
+	ResolveQR(qrcID string) (*QR, error)
+}
+
+// Client обращается к API НСПК (или к его локальной эмуляции) по HTTP
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) RegisterMerchant(req *RegisterMerchantRequest) (string, error) {
+	var resp RegisterMerchantResponse
+	if err := c.do("POST", "/merchants", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.MerchantID, nil
+}
+
+func (c *Client) RegisterQR(req *RegisterQRRequest) (*QR, error) {
+	qr := &QR{}
+	err := c.do("POST", "/qrc", req, qr)
+	if err == errNotFound {
+		return nil, ErrMerchantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return qr, nil
+}
+
+func (c *Client) ResolveQR(qrcID string) (*QR, error) {
+	qr := &QR{}
+	err := c.do("GET", "/qrc/"+url.PathEscape(qrcID), nil, qr)
+	if err == errNotFound {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return qr, nil
+}
+
+func (c *Client) do(method, path string, body, result interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return json.NewDecoder(resp.Body).Decode(result)
+	case http.StatusNotFound:
+		return errNotFound
+	default:
+		return fmt.Errorf("NSPK returned status %d", resp.StatusCode)
+	}
+}
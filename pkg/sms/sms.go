@@ -0,0 +1,75 @@
+package sms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Sender отправляет SMS на номер в формате +7XXXXXXXXXX
+type Sender interface {
+	Send(phone, text string) error
+}
+
+type message struct {
+	Phone string `json:"phone"`
+	Text  string `json:"text"`
+}
+
+// Client отправляет SMS через HTTP-шлюз оператора
+type Client struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+func NewClient(url, token string) *Client {
+	return &Client{
+		url:        url,
+		token:      token,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) Send(phone, text string) error {
+	body, err := json.Marshal(message{Phone: phone, Text: text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Fake - эмуляция шлюза для локальной разработки: сообщения пишутся
+// в журнал вместо отправки
+type Fake struct{}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Send(phone, text string) error {
+	log.Printf("SMS to %s: %s", phone, text)
+	return nil
+}